@@ -33,7 +33,7 @@ CREATE TABLE IF NOT EXISTS palpites (
     CONSTRAINT fk_palpite_user 
         FOREIGN KEY (user_id) 
         REFERENCES users(id) 
-        ON DELETE CASCADE
+        ON DELETE RESTRICT
 );
 
 -- Índices para palpites
@@ -68,7 +68,7 @@ CREATE TABLE IF NOT EXISTS comentarios (
     CONSTRAINT fk_comentario_user 
         FOREIGN KEY (user_id) 
         REFERENCES users(id) 
-        ON DELETE CASCADE
+        ON DELETE RESTRICT
 );
 
 -- Índices para comentários
@@ -310,6 +310,31 @@ BEGIN
 END;
 $$ LANGUAGE plpgsql;
 
+-- =====================================================
+-- PASSO 8: Exclusão de conta com anonimização
+-- =====================================================
+
+-- A exclusão é agendada (deletion_scheduled_for) e, após o prazo de carência, os dados
+-- pessoais são apagados (anonymized_at). O registro do usuário é mantido para que palpites
+-- e comentários continuem atribuídos a "Usuário removido".
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_requested_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_scheduled_for TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ALTER COLUMN email DROP NOT NULL;
+ALTER TABLE users ALTER COLUMN data_nascimento DROP NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_users_deletion_scheduled_for ON users (deletion_scheduled_for)
+    WHERE deletion_scheduled_for IS NOT NULL;
+
+-- Palpites e comentários não podem mais ser apagados em cascata junto com o usuário
+-- (tabelas criadas antes desta versão do script ainda têm ON DELETE CASCADE)
+ALTER TABLE palpites DROP CONSTRAINT IF EXISTS fk_palpite_user;
+ALTER TABLE palpites ADD CONSTRAINT fk_palpite_user
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+ALTER TABLE comentarios DROP CONSTRAINT IF EXISTS fk_comentario_user;
+ALTER TABLE comentarios ADD CONSTRAINT fk_comentario_user
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+
 -- =====================================================
 -- VERIFICAÇÃO FINAL
 -- =====================================================
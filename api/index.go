@@ -2,16 +2,25 @@ package handler
 
 import (
 	"net/http"
+	"sync"
 
 	pkgroutes "smartpicks-backend/pkg/routes"
 
 	"github.com/gorilla/mux"
 )
 
+var (
+	router     *mux.Router
+	routerOnce sync.Once
+)
+
 // Vercel serverless entrypoint delegating to internal routes
 func Handler(w http.ResponseWriter, r *http.Request) {
-	// Build router with the same wiring as local main.go
-	router := mux.NewRouter()
-	pkgroutes.RegisterRoutes(router)
+	// Build router with the same wiring as local main.go, once per instance: RegisterRoutes
+	// connects to the database and configures storage, mailer, etc.
+	routerOnce.Do(func() {
+		router = mux.NewRouter()
+		pkgroutes.RegisterRoutes(router)
+	})
 	router.ServeHTTP(w, r)
 }
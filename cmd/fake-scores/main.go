@@ -0,0 +1,160 @@
+// Comando fake-scores: simula o feed externo de placares para testes locais. Envia para o
+// webhook os eventos assinados de uma partida (início, gols e fim) e imprime as respostas.
+// O segredo é o mesmo SCORES_WEBHOOK_SECRET da API (lido do .env).
+//
+// Uso: go run ./cmd/fake-scores -match-id 1 [-final 2-1] [-tick 500ms] [-replay] [-cancel]
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"smartpicks-backend/internal/config"
+	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/services"
+
+	"github.com/joho/godotenv"
+)
+
+type scoreEvent struct {
+	EventID    string    `json:"event_id"`
+	MatchID    int       `json:"match_id"`
+	Status     string    `json:"status"`
+	HomeScore  *int      `json:"home_score,omitempty"`
+	AwayScore  *int      `json:"away_score,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// goal é um gol simulado no minuto informado
+type goal struct {
+	Minute int
+	Home   bool
+}
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  Arquivo .env não encontrado, usando variáveis de ambiente do sistema")
+	}
+
+	url := flag.String("url", "http://localhost:"+config.String("PORT", "8080")+"/api/webhooks/scores", "URL do webhook")
+	secret := flag.String("secret", config.String("SCORES_WEBHOOK_SECRET", ""), "segredo do webhook")
+	matchID := flag.Int("match-id", 0, "ID da partida")
+	final := flag.String("final", "", "placar final (ex: 2-1); sem ele os gols são sorteados")
+	tick := flag.Duration("tick", time.Second, "intervalo entre os eventos")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "semente do sorteio dos gols")
+	replay := flag.Bool("replay", false, "envia cada evento duas vezes para testar a idempotência")
+	cancel := flag.Bool("cancel", false, "cancela a partida depois do início em vez de terminá-la")
+	flag.Parse()
+
+	if *matchID <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	signer, err := services.NewWebhookSigner(*secret, 0)
+	if err != nil {
+		log.Fatal("Segredo inválido:", err)
+	}
+
+	rng := rand.New(rand.NewSource(*seed))
+	goals, err := simulateGoals(rng, *final)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	run := time.Now().Unix()
+	seq := 0
+	home, away := 0, 0
+	send := func(status string, minute int) {
+		seq++
+		h, a := home, away
+		ev := scoreEvent{
+			EventID:    fmt.Sprintf("fake-%d-%d-%d", *matchID, run, seq),
+			MatchID:    *matchID,
+			Status:     status,
+			HomeScore:  &h,
+			AwayScore:  &a,
+			OccurredAt: time.Now().UTC(),
+		}
+		if status == models.MATCH_STATUS_CANCELLED {
+			ev.HomeScore, ev.AwayScore = nil, nil
+		}
+		body, _ := json.Marshal(ev)
+
+		copies := 1
+		if *replay {
+			copies = 2
+		}
+		for i := 0; i < copies; i++ {
+			post(*url, signer, body, fmt.Sprintf("%d' %s %d-%d", minute, status, home, away))
+		}
+		time.Sleep(*tick)
+	}
+
+	send(models.MATCH_STATUS_LIVE, 0)
+	if *cancel {
+		send(models.MATCH_STATUS_CANCELLED, 10)
+		return
+	}
+	for _, g := range goals {
+		if g.Home {
+			home++
+		} else {
+			away++
+		}
+		send(models.MATCH_STATUS_LIVE, g.Minute)
+	}
+	send(models.MATCH_STATUS_FINISHED, 90)
+}
+
+// simulateGoals sorteia os minutos dos gols. Com final ("2-1") a quantidade é fixa; sem ele
+// cada minuto tem a chance média de um jogo de ~1,5 gol do mandante e ~1,2 do visitante.
+func simulateGoals(rng *rand.Rand, final string) ([]goal, error) {
+	var goals []goal
+	if final != "" {
+		var home, away int
+		if _, err := fmt.Sscanf(final, "%d-%d", &home, &away); err != nil || home < 0 || away < 0 {
+			return nil, fmt.Errorf("placar final inválido: %s (use 2-1)", final)
+		}
+		for i := 0; i < home+away; i++ {
+			goals = append(goals, goal{Minute: 1 + rng.Intn(89), Home: i < home})
+		}
+	} else {
+		for minute := 1; minute < 90; minute++ {
+			if rng.Float64() < 1.5/90 {
+				goals = append(goals, goal{Minute: minute, Home: true})
+			}
+			if rng.Float64() < 1.2/90 {
+				goals = append(goals, goal{Minute: minute, Home: false})
+			}
+		}
+	}
+	sort.SliceStable(goals, func(i, j int) bool { return goals[i].Minute < goals[j].Minute })
+	return goals, nil
+}
+
+func post(url string, signer *services.WebhookSigner, body []byte, label string) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(services.WebhookSignatureHeader, signer.Sign(body, time.Now()))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("✗ %s: %v", label, err)
+		return
+	}
+	defer resp.Body.Close()
+	response, _ := io.ReadAll(resp.Body)
+	log.Printf("%s → %d %s", label, resp.StatusCode, bytes.TrimSpace(response))
+}
@@ -0,0 +1,87 @@
+// Comando import-fixtures: importa partidas de um arquivo CSV (ex: football-data.co.uk) ou JSON.
+// Os times são reconhecidos pelo nome cadastrado ou por um alias (team_aliases). Sem -apply só
+// imprime o relatório do que seria criado, atualizado ou ignorado.
+//
+// Uso: go run ./cmd/import-fixtures -file E0.csv [-format csv] [-competition-id 1] [-timezone Europe/London] [-apply]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/fixtures"
+	"smartpicks-backend/internal/repository"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	path := flag.String("file", "", "arquivo CSV ou JSON com as partidas")
+	format := flag.String("format", "", "csv ou json (padrão: pela extensão do arquivo)")
+	competitionID := flag.Int("competition-id", 0, "competição das linhas sem competição")
+	timezone := flag.String("timezone", "UTC", "fuso dos horários sem fuso")
+	apply := flag.Bool("apply", false, "grava as alterações (sem ele é só um dry-run)")
+	flag.Parse()
+
+	if *path == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *format == "" {
+		*format = fixtures.DetectFormat("", filepath.Base(*path))
+	}
+	loc, err := time.LoadLocation(*timezone)
+	if err != nil {
+		log.Fatal("Fuso inválido:", err)
+	}
+
+	file, err := os.Open(*path)
+	if err != nil {
+		log.Fatal("Erro ao abrir arquivo:", err)
+	}
+	rows, err := fixtures.Parse(*format, file, loc)
+	file.Close()
+	if err != nil {
+		log.Fatal("Erro ao ler arquivo:", err)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  Arquivo .env não encontrado, usando variáveis de ambiente do sistema")
+	}
+	database.Connect()
+
+	opts := repository.FixtureImportOptions{Apply: *apply}
+	if *competitionID > 0 {
+		opts.CompetitionID = competitionID
+	}
+	report, err := repository.ImportFixtures(rows, opts)
+	if err != nil {
+		log.Fatal("Erro ao importar partidas:", err)
+	}
+
+	for _, row := range report.Rows {
+		switch row.Action {
+		case fixtures.ACTION_ERROR:
+			log.Printf("✗ linha %d: %s x %s: %v", row.Line, row.HomeTeam, row.AwayTeam, row.Errors)
+		case fixtures.ACTION_UPDATE:
+			log.Printf("~ linha %d: %s x %s (partida %d): %v", row.Line, row.HomeTeam, row.AwayTeam, *row.MatchID, row.Changes)
+		case fixtures.ACTION_CREATE:
+			log.Printf("+ linha %d: %s x %s", row.Line, row.HomeTeam, row.AwayTeam)
+		}
+	}
+
+	summary, _ := json.Marshal(report.Summary)
+	if *apply {
+		log.Printf("Importação concluída: %s", summary)
+	} else {
+		log.Printf("Dry-run (nada foi gravado, use -apply): %s", summary)
+	}
+	if report.Summary[fixtures.ACTION_ERROR] > 0 {
+		os.Exit(1)
+	}
+}
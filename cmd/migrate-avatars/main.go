@@ -0,0 +1,104 @@
+// Comando migrate-avatars: move para o storage (STORAGE_DRIVER) os avatares ainda salvos em base64
+// na tabela users.
+// Cada avatar é redimensionado nos tamanhos de services.AvatarSizes e no banco ficam só as URLs.
+// Avatares que não são imagens válidas são apenas listados.
+//
+// Uso: go run ./cmd/migrate-avatars [-dry-run]
+package main
+
+import (
+	"encoding/base64"
+	"flag"
+	"log"
+	"strconv"
+	"strings"
+
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/imaging"
+	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/services"
+
+	"github.com/joho/godotenv"
+)
+
+type userAvatar struct {
+	ID     int
+	Avatar string
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "apenas mostra o que seria migrado")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  Arquivo .env não encontrado, usando variáveis de ambiente do sistema")
+	}
+	database.Connect()
+
+	storage, err := services.NewStorageFromEnv()
+	if err != nil {
+		log.Fatal("Erro ao configurar armazenamento de arquivos:", err)
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, avatar FROM users
+		WHERE avatar IS NOT NULL AND avatar <> '' AND avatar NOT LIKE 'http%' AND avatar_variants IS NULL
+		ORDER BY id`)
+	if err != nil {
+		log.Fatal("Erro ao buscar usuários:", err)
+	}
+	var users []userAvatar
+	for rows.Next() {
+		var u userAvatar
+		if err := rows.Scan(&u.ID, &u.Avatar); err != nil {
+			log.Fatal("Erro ao ler usuário:", err)
+		}
+		users = append(users, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Fatal("Erro ao ler usuários:", err)
+	}
+
+	var migrated, invalid int
+	for _, u := range users {
+		data, err := base64.StdEncoding.DecodeString(base64Data(u.Avatar))
+		if err == nil {
+			_, _, err = imaging.Decode(data)
+		}
+		if err != nil {
+			invalid++
+			log.Printf("✗ usuário %d: avatar inválido (%v)", u.ID, err)
+			continue
+		}
+
+		if *dryRun {
+			log.Printf("usuário %d: avatar de %d bytes seria migrado", u.ID, len(data))
+			migrated++
+			continue
+		}
+
+		variants, err := services.UploadAvatar(storage, u.ID, data)
+		if err != nil {
+			log.Printf("✗ usuário %d: erro ao enviar avatar: %v", u.ID, err)
+			continue
+		}
+		avatar := variants[strconv.Itoa(services.AvatarDefaultSize)]
+		if _, err := database.DB.Exec("UPDATE users SET avatar = $1, avatar_variants = $2 WHERE id = $3",
+			avatar, models.ImageVariants(variants), u.ID); err != nil {
+			log.Printf("✗ usuário %d: erro ao atualizar avatar: %v", u.ID, err)
+			continue
+		}
+		migrated++
+	}
+
+	log.Printf("✓ %d avatares em base64 encontrados, %d migrados, %d inválidos", len(users), migrated, invalid)
+}
+
+// base64Data remove o prefixo de data URL ("data:image/png;base64,"), se houver
+func base64Data(value string) string {
+	if i := strings.Index(value, "base64,"); i >= 0 {
+		return value[i+len("base64,"):]
+	}
+	return value
+}
@@ -0,0 +1,90 @@
+// Comando normalize-cpf: grava os CPFs já cadastrados apenas com dígitos e lista os que
+// não passam na validação (dígitos verificadores, tamanho ou dígitos repetidos).
+// Atua sobre a coluna cpf em texto puro; rode antes do reencrypt-cpf, que cifra os valores.
+//
+// Uso: go run ./cmd/normalize-cpf [-dry-run]
+package main
+
+import (
+	"flag"
+	"log"
+
+	"smartpicks-backend/internal/cpf"
+	"smartpicks-backend/internal/database"
+
+	"github.com/joho/godotenv"
+)
+
+type userCPF struct {
+	ID  int
+	CPF string
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "apenas mostra o que seria alterado")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  Arquivo .env não encontrado, usando variáveis de ambiente do sistema")
+	}
+	database.Connect()
+
+	rows, err := database.DB.Query("SELECT id, cpf FROM users WHERE cpf IS NOT NULL ORDER BY id")
+	if err != nil {
+		log.Fatal("Erro ao buscar usuários:", err)
+	}
+	var users []userCPF
+	for rows.Next() {
+		var u userCPF
+		if err := rows.Scan(&u.ID, &u.CPF); err != nil {
+			log.Fatal("Erro ao ler usuário:", err)
+		}
+		users = append(users, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Fatal("Erro ao ler usuários:", err)
+	}
+
+	// Dois cadastros que só diferem na pontuação passam a colidir depois de normalizados
+	owners := make(map[string]int, len(users))
+	for _, u := range users {
+		if normalized := cpf.Normalize(u.CPF); normalized == u.CPF {
+			owners[normalized] = u.ID
+		}
+	}
+
+	var updated, invalid, conflicts int
+	for _, u := range users {
+		normalized := cpf.Normalize(u.CPF)
+
+		if err := cpf.Validate(normalized); err != nil {
+			invalid++
+			log.Printf("✗ usuário %d: CPF %q inválido (%v)", u.ID, u.CPF, err)
+		}
+
+		if normalized == u.CPF {
+			continue
+		}
+		if owner, ok := owners[normalized]; ok && owner != u.ID {
+			conflicts++
+			log.Printf("✗ usuário %d: CPF %q já pertence ao usuário %d depois de normalizado", u.ID, u.CPF, owner)
+			continue
+		}
+		owners[normalized] = u.ID
+
+		if *dryRun {
+			log.Printf("usuário %d: %q -> %q", u.ID, u.CPF, normalized)
+			updated++
+			continue
+		}
+		if _, err := database.DB.Exec("UPDATE users SET cpf = $1 WHERE id = $2", normalized, u.ID); err != nil {
+			log.Printf("✗ usuário %d: erro ao atualizar CPF: %v", u.ID, err)
+			continue
+		}
+		updated++
+	}
+
+	log.Printf("✓ %d usuários verificados, %d CPFs normalizados, %d inválidos, %d conflitos",
+		len(users), updated, invalid, conflicts)
+}
@@ -0,0 +1,40 @@
+// Comando process-exports: processa a fila de exportações de dados (LGPD) pedidas em
+// /api/users/me/export, gravando os arquivos no storage. Deve ser agendado no cron.
+//
+// Uso: go run ./cmd/process-exports [-limit 10]
+package main
+
+import (
+	"flag"
+	"log"
+
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/handlers"
+	"smartpicks-backend/internal/repository"
+	"smartpicks-backend/internal/services"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	limit := flag.Int("limit", 10, "número máximo de exportações processadas")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  Arquivo .env não encontrado, usando variáveis de ambiente do sistema")
+	}
+	database.Connect()
+
+	storage, err := services.NewStorageFromEnv()
+	if err != nil {
+		log.Fatal("Erro ao configurar armazenamento de arquivos:", err)
+	}
+	handlers.SetStorage(storage)
+	repository.SetStorage(storage)
+
+	ready, err := handlers.ProcessDataExports(*limit)
+	if err != nil {
+		log.Fatal("Erro ao processar exportações:", err)
+	}
+	log.Printf("✓ %d exportações prontas", ready)
+}
@@ -0,0 +1,34 @@
+// Comando purge-accounts: anonimiza as contas cuja exclusão foi pedida e cujo prazo de
+// carência (ACCOUNT_DELETION_GRACE) terminou. Pode ser agendado no cron.
+//
+// Uso: go run ./cmd/purge-accounts
+package main
+
+import (
+	"log"
+
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/repository"
+	"smartpicks-backend/internal/services"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  Arquivo .env não encontrado, usando variáveis de ambiente do sistema")
+	}
+	database.Connect()
+
+	storage, err := services.NewStorageFromEnv()
+	if err != nil {
+		log.Fatal("Erro ao configurar armazenamento de arquivos:", err)
+	}
+	repository.SetStorage(storage)
+
+	purged, err := repository.PurgeScheduledDeletions()
+	if err != nil {
+		log.Fatal("Erro ao remover contas:", err)
+	}
+	log.Printf("✓ %d contas anonimizadas", purged)
+}
@@ -0,0 +1,35 @@
+// Comando purge-uploads: apaga os uploads diretos abandonados (URL pré-assinada vencida ou
+// concluídos e não usados em um palpite dentro de UPLOAD_CLAIM_TIMEOUT) e os arquivos originais
+// no storage. Deve ser agendado no cron.
+//
+// Uso: go run ./cmd/purge-uploads
+package main
+
+import (
+	"log"
+
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/repository"
+	"smartpicks-backend/internal/services"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  Arquivo .env não encontrado, usando variáveis de ambiente do sistema")
+	}
+	database.Connect()
+
+	storage, err := services.NewStorageFromEnv()
+	if err != nil {
+		log.Fatal("Erro ao configurar armazenamento de arquivos:", err)
+	}
+	repository.SetStorage(storage)
+
+	purged, err := repository.PurgeStaleUploads()
+	if err != nil {
+		log.Fatal("Erro ao remover uploads:", err)
+	}
+	log.Printf("✓ %d uploads removidos", purged)
+}
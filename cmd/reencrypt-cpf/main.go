@@ -0,0 +1,128 @@
+// Comando reencrypt-cpf: cifra os CPFs que ainda estão em texto puro e, depois de uma rotação
+// de chave (nova chave em DATA_ENCRYPTION_KEY_ID), cifra novamente as DEKs com a chave atual.
+// Com -rehash também recalcula cpf_hash, necessário quando CPF_HASH_KEY é trocada.
+//
+// Uso: go run ./cmd/reencrypt-cpf [-dry-run] [-rehash]
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"log"
+
+	"smartpicks-backend/internal/cpf"
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/services"
+
+	"github.com/joho/godotenv"
+)
+
+type userCPF struct {
+	ID        int
+	Plain     sql.NullString
+	Encrypted sql.NullString
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "apenas mostra o que seria alterado")
+	rehash := flag.Bool("rehash", false, "recalcula cpf_hash com a CPF_HASH_KEY atual")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  Arquivo .env não encontrado, usando variáveis de ambiente do sistema")
+	}
+
+	cipher, err := services.NewFieldCipherFromEnv()
+	if err != nil {
+		log.Fatal("Erro ao configurar criptografia de dados:", err)
+	}
+	database.Connect()
+
+	rows, err := database.DB.Query(`
+		SELECT id, cpf, cpf_encrypted FROM users
+		WHERE cpf IS NOT NULL OR cpf_encrypted IS NOT NULL
+		ORDER BY id`)
+	if err != nil {
+		log.Fatal("Erro ao buscar usuários:", err)
+	}
+	var users []userCPF
+	for rows.Next() {
+		var u userCPF
+		if err := rows.Scan(&u.ID, &u.Plain, &u.Encrypted); err != nil {
+			log.Fatal("Erro ao ler usuário:", err)
+		}
+		users = append(users, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Fatal("Erro ao ler usuários:", err)
+	}
+
+	var encrypted, rotated, rehashed, failed int
+	for _, u := range users {
+		switch {
+		case !u.Encrypted.Valid:
+			value := cpf.Normalize(u.Plain.String)
+			if err := cpf.Validate(value); err != nil {
+				log.Printf("⚠️  usuário %d: CPF inválido (%v), cifrando mesmo assim", u.ID, err)
+			}
+			if *dryRun {
+				log.Printf("usuário %d: CPF em texto puro será cifrado", u.ID)
+				encrypted++
+				continue
+			}
+			ciphertext, err := cipher.Encrypt(value)
+			if err == nil {
+				_, err = database.DB.Exec(`
+					UPDATE users SET cpf_encrypted = $1, cpf_hash = $2, cpf = NULL WHERE id = $3`,
+					ciphertext, cipher.Hash(value), u.ID)
+			}
+			if err != nil {
+				failed++
+				log.Printf("✗ usuário %d: erro ao cifrar CPF: %v", u.ID, err)
+				continue
+			}
+			encrypted++
+
+		default:
+			ciphertext := u.Encrypted.String
+			needsRotation := cipher.NeedsRotation(ciphertext)
+			if !needsRotation && !*rehash {
+				continue
+			}
+			if *dryRun {
+				log.Printf("usuário %d: rotação=%v rehash=%v", u.ID, needsRotation, *rehash)
+				continue
+			}
+
+			if needsRotation {
+				rewrapped, err := cipher.Rewrap(ciphertext)
+				if err == nil {
+					_, err = database.DB.Exec("UPDATE users SET cpf_encrypted = $1 WHERE id = $2", rewrapped, u.ID)
+				}
+				if err != nil {
+					failed++
+					log.Printf("✗ usuário %d: erro ao cifrar novamente: %v", u.ID, err)
+					continue
+				}
+				rotated++
+			}
+
+			if *rehash {
+				value, err := cipher.Decrypt(ciphertext)
+				if err == nil {
+					_, err = database.DB.Exec("UPDATE users SET cpf_hash = $1 WHERE id = $2", cipher.Hash(value), u.ID)
+				}
+				if err != nil {
+					failed++
+					log.Printf("✗ usuário %d: erro ao recalcular hash: %v", u.ID, err)
+					continue
+				}
+				rehashed++
+			}
+		}
+	}
+
+	log.Printf("✓ %d usuários verificados (chave atual %q): %d cifrados, %d com nova chave, %d hashes recalculados, %d erros",
+		len(users), cipher.CurrentKeyID(), encrypted, rotated, rehashed, failed)
+}
@@ -6,9 +6,10 @@ CREATE TABLE IF NOT EXISTS users (
     nome VARCHAR(255) NOT NULL,
     email VARCHAR(255) UNIQUE NOT NULL,
     password VARCHAR(255) NOT NULL,
-    cpf VARCHAR(14) UNIQUE NOT NULL,
+    -- CPF legado em texto puro; os novos cadastros usam cpf_encrypted/cpf_hash (ver CPF CIFRADO)
+    cpf VARCHAR(14),
     data_nascimento DATE NOT NULL,
-    perfil VARCHAR(20) NOT NULL DEFAULT 'user' CHECK (perfil IN ('admin', 'user')),
+    perfil VARCHAR(20) NOT NULL DEFAULT 'user' CHECK (perfil IN ('admin', 'moderator', 'tipster', 'user')),
     avatar TEXT NULL DEFAULT NULL,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
@@ -34,13 +35,14 @@ CREATE TRIGGER update_users_updated_at
     FOR EACH ROW
     EXECUTE FUNCTION update_updated_at_column();
 
--- Inserir usuários padrão (se não existirem)
-INSERT INTO users (nome, email, password, cpf, data_nascimento, perfil, avatar) VALUES
+-- Inserir usuários padrão (se não existirem). Eles são criados sem CPF: o CPF só pode ser gravado
+-- cifrado, com as chaves de cada ambiente. Bancos que já receberam a versão antiga desta carga,
+-- com CPF em texto puro, devem rodar go run ./cmd/reencrypt-cpf.
+INSERT INTO users (nome, email, password, data_nascimento, perfil, avatar) VALUES
 (
     'Admin User',
     'admin@smartpicks.com',
     '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi',
-    '00000000000',
     '1990-01-01',
     'admin',
     'https://ui-avatars.com/api/?name=Admin+User&background=0d8abc&color=fff'
@@ -49,7 +51,6 @@ INSERT INTO users (nome, email, password, cpf, data_nascimento, perfil, avatar)
     'User Comum',
     'user@smartpicks.com',
     '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi',
-    '11111111111',
     '1995-06-15',
     'user',
     'https://ui-avatars.com/api/?name=User+Comum&background=28a745&color=fff'
@@ -78,7 +79,7 @@ CREATE TABLE IF NOT EXISTS palpites (
     CONSTRAINT fk_palpite_user 
         FOREIGN KEY (user_id) 
         REFERENCES users(id) 
-        ON DELETE CASCADE
+        ON DELETE RESTRICT
 );
 
 -- Índices para palpites
@@ -113,7 +114,7 @@ CREATE TABLE IF NOT EXISTS comentarios (
     CONSTRAINT fk_comentario_user 
         FOREIGN KEY (user_id) 
         REFERENCES users(id) 
-        ON DELETE CASCADE
+        ON DELETE RESTRICT
 );
 
 -- Índices para comentários
@@ -355,6 +356,493 @@ BEGIN
 END;
 $$ LANGUAGE plpgsql;
 
+-- =====================================================
+-- SESSÕES E REFRESH TOKENS
+-- =====================================================
+
+-- Cada login abre uma sessão; os refresh tokens da sessão formam uma família
+-- que é rotacionada a cada uso. Reuso de um token antigo revoga a sessão.
+CREATE TABLE IF NOT EXISTS sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    user_agent TEXT,
+    ip_address VARCHAR(45),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    revoked_reason VARCHAR(50),
+
+    CONSTRAINT fk_session_user
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    session_id INTEGER NOT NULL,
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    used_at TIMESTAMP WITH TIME ZONE,
+
+    CONSTRAINT fk_refresh_token_session
+        FOREIGN KEY (session_id)
+        REFERENCES sessions(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens (session_id);
+
+-- =====================================================
+-- VERIFICAÇÃO DE EMAIL E REDEFINIÇÃO DE SENHA
+-- =====================================================
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE;
+
+-- Tokens de uso único enviados por email (apenas o hash SHA-256 é salvo)
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    purpose VARCHAR(30) NOT NULL CHECK (purpose IN ('password_reset', 'email_verification', 'email_change')),
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_user_token_user
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user_purpose ON user_tokens (user_id, purpose);
+
+-- =====================================================
+-- AUTENTICAÇÃO EM DOIS FATORES (TOTP)
+-- =====================================================
+
+-- totp_secret fica pendente até a confirmação (totp_enabled_at);
+-- totp_last_step guarda o último intervalo aceito para impedir reuso de códigos
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT;
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_recovery_code_user
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes (user_id);
+
+-- =====================================================
+-- PROTEÇÃO CONTRA FORÇA BRUTA NO LOGIN
+-- =====================================================
+
+-- Contadores de falhas por conta ("account:<email>"), IP ("ip:<endereço>") e 2FA ("2fa:<id>")
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key VARCHAR(320) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMP WITH TIME ZONE
+);
+
+-- =====================================================
+-- PERFIS ADICIONAIS (moderator e tipster)
+-- =====================================================
+
+-- Bancos criados antes dos novos perfis ainda têm a constraint antiga
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_perfil_check;
+ALTER TABLE users ADD CONSTRAINT users_perfil_check
+    CHECK (perfil IN ('admin', 'moderator', 'tipster', 'user'));
+
+-- =====================================================
+-- CPF CIFRADO (LGPD)
+-- =====================================================
+
+-- cpf_encrypted guarda o CPF com envelope encryption ("<id da chave>:<DEK cifrada>:<valor cifrado>")
+-- e cpf_hash o HMAC-SHA256 usado na verificação de unicidade. A coluna cpf em texto puro só
+-- existe para cadastros antigos e é esvaziada pelo comando reencrypt-cpf.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS cpf_encrypted TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS cpf_hash VARCHAR(64);
+ALTER TABLE users ALTER COLUMN cpf DROP NOT NULL;
+-- A unicidade passa a ser verificada por cpf_hash
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_cpf_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_cpf_hash ON users (cpf_hash);
+
+-- =====================================================
+-- EXPORTAÇÃO DE DADOS (LGPD)
+-- =====================================================
+
+-- Exports grandes são gerados em segundo plano; o ZIP fica salvo até expires_at
+CREATE TABLE IF NOT EXISTS data_exports (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'processing', 'ready', 'failed')),
+    -- Chave do arquivo ZIP no storage (só é baixado pelo link assinado)
+    storage_key VARCHAR(255),
+    size_bytes BIGINT,
+    error TEXT,
+    -- Tentativas do worker; started_at marca a reserva atual
+    attempts INTEGER NOT NULL DEFAULT 0,
+    started_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+
+    CONSTRAINT fk_data_export_user
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports (user_id);
+CREATE INDEX IF NOT EXISTS idx_data_exports_queue ON data_exports (created_at)
+    WHERE status IN ('pending', 'processing');
+
+-- =====================================================
+-- EXCLUSÃO DE CONTA COM ANONIMIZAÇÃO
+-- =====================================================
+
+-- A exclusão é agendada (deletion_scheduled_for) e, após o prazo de carência, os dados
+-- pessoais são apagados (anonymized_at). O registro do usuário é mantido para que palpites
+-- e comentários continuem atribuídos a "Usuário removido".
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_requested_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_scheduled_for TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ALTER COLUMN email DROP NOT NULL;
+ALTER TABLE users ALTER COLUMN data_nascimento DROP NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_users_deletion_scheduled_for ON users (deletion_scheduled_for)
+    WHERE deletion_scheduled_for IS NOT NULL;
+
+-- Palpites e comentários não podem mais ser apagados em cascata junto com o usuário
+ALTER TABLE palpites DROP CONSTRAINT IF EXISTS fk_palpite_user;
+ALTER TABLE palpites ADD CONSTRAINT fk_palpite_user
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+ALTER TABLE comentarios DROP CONSTRAINT IF EXISTS fk_comentario_user;
+ALTER TABLE comentarios ADD CONSTRAINT fk_comentario_user
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+
+-- =====================================================
+-- TROCA DE EMAIL
+-- =====================================================
+
+-- O novo email fica pendente até ser confirmado pelo link enviado (user_tokens, purpose = 'email_change')
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pending_email VARCHAR(255);
+
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_purpose_check;
+ALTER TABLE user_tokens ADD CONSTRAINT user_tokens_purpose_check
+    CHECK (purpose IN ('password_reset', 'email_verification', 'email_change'));
+
+-- =====================================================
+-- AVATARES NO S3
+-- =====================================================
+
+-- users.avatar guarda a URL do tamanho padrão (128px); avatar_variants tem as URLs de todos
+-- os tamanhos, ex: {"64": "https://...", "128": "https://...", "512": "https://..."}.
+-- Avatares antigos em base64 são migrados com: go run ./cmd/migrate-avatars
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_variants JSONB;
+
+-- =====================================================
+-- UPLOADS DIRETOS (URL PRÉ-ASSINADA)
+-- =====================================================
+
+-- Imagens enviadas pelo cliente direto ao storage. O registro nasce 'pending' em
+-- POST /api/uploads/presign e vira 'completed' depois de conferido em /complete;
+-- palpite_id é preenchido quando o palpite que usa a imagem é criado.
+CREATE TABLE IF NOT EXISTS uploads (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    storage_key VARCHAR(512) NOT NULL UNIQUE,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'completed')),
+    palpite_id INTEGER,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_upload_user
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_upload_palpite
+        FOREIGN KEY (palpite_id)
+        REFERENCES palpites(id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_uploads_user_id ON uploads (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_uploads_palpite_id ON uploads (palpite_id) WHERE palpite_id IS NOT NULL;
+-- Limpeza dos uploads abandonados (cmd/purge-uploads)
+CREATE INDEX IF NOT EXISTS idx_uploads_unclaimed ON uploads (status, expires_at, completed_at) WHERE palpite_id IS NULL;
+
+-- =====================================================
+-- PROCESSAMENTO DAS IMAGENS DOS PALPITES
+-- =====================================================
+
+-- Cada imagem é gravada sem metadados (EXIF/GPS) em três versões JPEG:
+-- {"thumb": "...", "medium": "...", "full": "..."}; img_url aponta para a "full".
+-- img_width/img_height são as dimensões da "full" e img_blurhash o placeholder exibido
+-- enquanto a imagem carrega.
+ALTER TABLE palpites ADD COLUMN IF NOT EXISTS img_variants JSONB;
+ALTER TABLE palpites ADD COLUMN IF NOT EXISTS img_width INTEGER;
+ALTER TABLE palpites ADD COLUMN IF NOT EXISTS img_height INTEGER;
+ALTER TABLE palpites ADD COLUMN IF NOT EXISTS img_blurhash VARCHAR(64);
+
+-- =====================================================
+-- IMAGENS REPETIDAS NOS PALPITES
+-- =====================================================
+
+-- img_phash é o hash perceptual (dHash, 64 bits) da imagem. Um palpite cuja imagem fica a
+-- até PALPITE_DUPLICATE_MAX_DISTANCE bits de outra é marcado com duplicate_of apontando para
+-- o original e duplicate_distance com a distância de Hamming.
+ALTER TABLE palpites ADD COLUMN IF NOT EXISTS img_phash BIGINT;
+ALTER TABLE palpites ADD COLUMN IF NOT EXISTS duplicate_of INTEGER REFERENCES palpites(id) ON DELETE SET NULL;
+ALTER TABLE palpites ADD COLUMN IF NOT EXISTS duplicate_distance SMALLINT;
+
+CREATE INDEX IF NOT EXISTS idx_palpites_duplicate_of ON palpites(duplicate_of) WHERE duplicate_of IS NOT NULL;
+
+-- Faixas de 16 bits do img_phash (da mais significativa para a menos), indexadas para a busca
+-- de imagens parecidas: duas imagens a até d bits de distância têm alguma faixa a até d/4 bits,
+-- então só as linhas que coincidem em uma faixa têm a distância completa calculada.
+-- Colunas geradas: as linhas existentes são preenchidas ao criar a coluna.
+ALTER TABLE palpites ADD COLUMN IF NOT EXISTS img_phash_b0 INTEGER
+    GENERATED ALWAYS AS (((img_phash >> 48) & 65535)::INTEGER) STORED;
+ALTER TABLE palpites ADD COLUMN IF NOT EXISTS img_phash_b1 INTEGER
+    GENERATED ALWAYS AS (((img_phash >> 32) & 65535)::INTEGER) STORED;
+ALTER TABLE palpites ADD COLUMN IF NOT EXISTS img_phash_b2 INTEGER
+    GENERATED ALWAYS AS (((img_phash >> 16) & 65535)::INTEGER) STORED;
+ALTER TABLE palpites ADD COLUMN IF NOT EXISTS img_phash_b3 INTEGER
+    GENERATED ALWAYS AS ((img_phash & 65535)::INTEGER) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_palpites_img_phash_b0 ON palpites(img_phash_b0) WHERE img_phash_b0 IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_palpites_img_phash_b1 ON palpites(img_phash_b1) WHERE img_phash_b1 IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_palpites_img_phash_b2 ON palpites(img_phash_b2) WHERE img_phash_b2 IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_palpites_img_phash_b3 ON palpites(img_phash_b3) WHERE img_phash_b3 IS NOT NULL;
+
+-- =====================================================
+-- PARTIDAS E SELEÇÕES DOS PALPITES
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS matches (
+    id SERIAL PRIMARY KEY,
+    team_a VARCHAR(100) NOT NULL,
+    team_b VARCHAR(100) NOT NULL,
+    match_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    location VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_matches_match_date ON matches(match_date);
+
+-- Cada seleção é uma aposta simples do palpite: mercado e seleção em uma partida, odd decimal
+-- e stake em unidades. line é usada no over/under (total de gols) e no handicap asiático
+-- (aplicada ao time escolhido); ambas aceitam linhas de quarto (ex: 2.25, -0.75).
+CREATE TABLE IF NOT EXISTS palpite_selections (
+    id SERIAL PRIMARY KEY,
+    palpite_id INTEGER NOT NULL REFERENCES palpites(id) ON DELETE CASCADE,
+    match_id INTEGER NOT NULL REFERENCES matches(id) ON DELETE RESTRICT,
+    market VARCHAR(20) NOT NULL
+        CHECK (market IN ('1x2', 'over_under', 'btts', 'handicap', 'correct_score')),
+    selection VARCHAR(20) NOT NULL,
+    line NUMERIC(5, 2),
+    odds NUMERIC(8, 3) NOT NULL CHECK (odds > 1),
+    stake NUMERIC(5, 2) NOT NULL DEFAULT 1 CHECK (stake > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_palpite_selections_palpite_id ON palpite_selections(palpite_id);
+CREATE INDEX IF NOT EXISTS idx_palpite_selections_match_id ON palpite_selections(match_id);
+
+-- =====================================================
+-- APURAÇÃO DOS PALPITES
+-- =====================================================
+
+-- Placar final (tempo regulamentar) usado na apuração; settled_at indica que a partida já foi apurada
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS home_score SMALLINT;
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS away_score SMALLINT;
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS settled_at TIMESTAMP WITH TIME ZONE;
+
+-- Resultado de cada seleção: pending, won, half_won, void, half_lost ou lost; profit em unidades
+ALTER TABLE palpite_selections ADD COLUMN IF NOT EXISTS status VARCHAR(12) NOT NULL DEFAULT 'pending';
+ALTER TABLE palpite_selections ADD COLUMN IF NOT EXISTS profit NUMERIC(10, 3);
+ALTER TABLE palpite_selections ADD COLUMN IF NOT EXISTS settled_at TIMESTAMP WITH TIME ZONE;
+
+-- Resumo das seleções do palpite (nulo em palpites sem seleções)
+ALTER TABLE palpites ADD COLUMN IF NOT EXISTS status VARCHAR(12);
+ALTER TABLE palpites ADD COLUMN IF NOT EXISTS profit NUMERIC(10, 3);
+ALTER TABLE palpites ADD COLUMN IF NOT EXISTS settled_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_palpites_user_status ON palpites(user_id, status) WHERE status IS NOT NULL;
+
+-- Trilha de auditoria: cada apuração (automática ou manual) com o placar, quem apurou e as
+-- seleções cujo resultado mudou
+CREATE TABLE IF NOT EXISTS settlement_audit (
+    id SERIAL PRIMARY KEY,
+    match_id INTEGER NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+    home_score SMALLINT,
+    away_score SMALLINT,
+    void BOOLEAN NOT NULL DEFAULT FALSE,
+    resettle BOOLEAN NOT NULL DEFAULT FALSE,
+    settled_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT,
+    selections INTEGER NOT NULL DEFAULT 0,
+    palpites INTEGER NOT NULL DEFAULT 0,
+    changes JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_settlement_audit_match_id ON settlement_audit(match_id, created_at DESC);
+
+-- =====================================================
+-- ESTATÍSTICAS DOS TIPSTERS
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS competitions (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    country VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS competition_id INTEGER REFERENCES competitions(id) ON DELETE SET NULL;
+
+-- Cache de GET /users/{id}/stats por período (range_key = "from:to"). Cada apuração que afeta
+-- os palpites do autor incrementa a versão dele em user_stats_versions; só as linhas calculadas
+-- na versão atual (e há menos de STATS_CACHE_TTL) são usadas.
+CREATE TABLE IF NOT EXISTS user_stats_cache (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    range_key VARCHAR(32) NOT NULL,
+    stats JSONB NOT NULL,
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    version BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, range_key)
+);
+
+CREATE TABLE IF NOT EXISTS user_stats_versions (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    version BIGINT NOT NULL DEFAULT 0
+);
+
+-- =====================================================
+-- PARTIDAS: TIMES, COMPETIÇÕES E STATUS
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS teams (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    short_name VARCHAR(20),
+    country VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- match_date é o horário de início em UTC e location o estádio. team_a (mandante) e team_b
+-- (visitante) guardam os nomes dos times; home_team_id/away_team_id apontam para teams.
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS home_team_id INTEGER REFERENCES teams(id) ON DELETE RESTRICT;
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS away_team_id INTEGER REFERENCES teams(id) ON DELETE RESTRICT;
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS status VARCHAR(12) NOT NULL DEFAULT 'scheduled';
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_matches_status') THEN
+        ALTER TABLE matches ADD CONSTRAINT chk_matches_status
+            CHECK (status IN ('scheduled', 'live', 'finished', 'postponed', 'cancelled'));
+    END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_matches_competition_id ON matches(competition_id);
+CREATE INDEX IF NOT EXISTS idx_matches_home_team_id ON matches(home_team_id);
+CREATE INDEX IF NOT EXISTS idx_matches_away_team_id ON matches(away_team_id);
+CREATE INDEX IF NOT EXISTS idx_matches_status ON matches(status);
+
+-- =====================================================
+-- IMPORTAÇÃO DE PARTIDAS (CSV/JSON)
+-- =====================================================
+
+-- Nomes alternativos dos times nos arquivos importados (ex: "Man United"), gravados normalizados
+-- (minúsculas, espaços simples)
+CREATE TABLE IF NOT EXISTS team_aliases (
+    id SERIAL PRIMARY KEY,
+    team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    alias VARCHAR(100) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_aliases_team_id ON team_aliases(team_id);
+
+-- Código da competição nos arquivos (ex: coluna Div do football-data.co.uk, "E0")
+ALTER TABLE competitions ADD COLUMN IF NOT EXISTS code VARCHAR(20) UNIQUE;
+
+-- Chave natural usada na importação: mandante, visitante e dia (UTC) do início. O índice é único
+-- para que importações concorrentes não criem a mesma partida duas vezes (INSERT ... ON CONFLICT).
+-- Partidas repetidas já gravadas precisam ser unificadas antes de criar o índice.
+DROP INDEX IF EXISTS idx_matches_natural_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_matches_natural_key_unique
+    ON matches(home_team_id, away_team_id, ((match_date AT TIME ZONE 'UTC')::date));
+
+-- =====================================================
+-- PLACARES AO VIVO (WEBHOOK DO FEED)
+-- =====================================================
+
+-- Horário do último evento do feed aplicado à partida; eventos mais antigos chegam fora de ordem
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS score_updated_at TIMESTAMP WITH TIME ZONE;
+
+-- Eventos recebidos pelos webhooks, únicos por origem e event_id (idempotência)
+CREATE TABLE IF NOT EXISTS webhook_events (
+    id SERIAL PRIMARY KEY,
+    source VARCHAR(30) NOT NULL,
+    event_id VARCHAR(100) NOT NULL,
+    match_id INTEGER REFERENCES matches(id) ON DELETE SET NULL,
+    payload JSONB NOT NULL,
+    result VARCHAR(20) NOT NULL,
+    received_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (source, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_events_match_id ON webhook_events(match_id);
+
+-- =====================================================
+-- PALPITES DEPOIS DO INÍCIO DA PARTIDA
+-- =====================================================
+
+-- late marca seleções postadas perto do início (PALPITE_LATE_WINDOW); minutes_before_kickoff é
+-- calculado pelo relógio do servidor no momento da gravação (negativo dentro da tolerância)
+ALTER TABLE palpite_selections ADD COLUMN IF NOT EXISTS late BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE palpite_selections ADD COLUMN IF NOT EXISTS minutes_before_kickoff INTEGER;
+
+-- =====================================================
+-- CONSENSO DA COMUNIDADE POR PARTIDA
+-- =====================================================
+
+-- Consenso calculado de GET /api/matches/{id}/consensus. Novos palpites na partida e a apuração
+-- dela incrementam a versão em match_consensus_versions; só o cálculo da versão atual é usado.
+CREATE TABLE IF NOT EXISTS match_consensus_cache (
+    match_id INTEGER PRIMARY KEY REFERENCES matches(id) ON DELETE CASCADE,
+    consensus JSONB NOT NULL,
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    version BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS match_consensus_versions (
+    match_id INTEGER PRIMARY KEY REFERENCES matches(id) ON DELETE CASCADE,
+    version BIGINT NOT NULL DEFAULT 0
+);
+
 -- =====================================================
 -- EXEMPLOS DE USO
 -- =====================================================
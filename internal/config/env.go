@@ -0,0 +1,44 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// String retorna o valor da variável de ambiente ou o padrão informado
+func String(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// Int retorna a variável de ambiente convertida para inteiro ou o padrão informado
+func Int(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// Bool retorna a variável de ambiente convertida para booleano ou o padrão informado
+func Bool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(strings.TrimSpace(os.Getenv(key)))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// Duration retorna a variável de ambiente no formato do time.ParseDuration (ex: 15m, 24h)
+// ou o padrão informado
+func Duration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
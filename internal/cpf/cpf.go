@@ -0,0 +1,78 @@
+// Package cpf valida, normaliza e formata números de CPF
+package cpf
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrLength         = errors.New("CPF deve conter 11 dígitos")
+	ErrRepeatedDigits = errors.New("CPF não pode ter todos os dígitos iguais")
+	ErrCheckDigits    = errors.New("dígitos verificadores do CPF inválidos")
+)
+
+// Normalize remove pontuação e espaços, mantendo apenas os dígitos
+func Normalize(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, value)
+}
+
+// Validate verifica o tamanho, rejeita sequências de um único dígito repetido
+// e confere os dois dígitos verificadores. Aceita o CPF com ou sem pontuação.
+func Validate(value string) error {
+	digits := Normalize(value)
+	if len(digits) != 11 {
+		return ErrLength
+	}
+	if strings.Count(digits, digits[:1]) == len(digits) {
+		return ErrRepeatedDigits
+	}
+	if checkDigit(digits[:9]) != digits[9] || checkDigit(digits[:10]) != digits[10] {
+		return ErrCheckDigits
+	}
+	return nil
+}
+
+// IsValid indica se o CPF é válido
+func IsValid(value string) bool {
+	return Validate(value) == nil
+}
+
+// Format exibe o CPF no formato 123.456.789-09. Valores que não têm 11 dígitos
+// são retornados sem alteração.
+func Format(value string) string {
+	digits := Normalize(value)
+	if len(digits) != 11 {
+		return value
+	}
+	return digits[:3] + "." + digits[3:6] + "." + digits[6:9] + "-" + digits[9:]
+}
+
+// Mask mantém visíveis apenas os três dígitos do meio do último bloco: ***.***.789-**
+func Mask(value string) string {
+	digits := Normalize(value)
+	if len(digits) != 11 {
+		return "***.***.***-**"
+	}
+	return "***.***." + digits[6:9] + "-**"
+}
+
+// checkDigit calcula o dígito verificador dos dígitos informados (pesos decrescentes a partir de len+1)
+func checkDigit(digits string) byte {
+	sum := 0
+	weight := len(digits) + 1
+	for i := 0; i < len(digits); i++ {
+		sum += int(digits[i]-'0') * weight
+		weight--
+	}
+	rest := (sum * 10) % 11
+	if rest == 10 {
+		rest = 0
+	}
+	return byte('0' + rest)
+}
@@ -0,0 +1,59 @@
+package cpf
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  error
+	}{
+		{"válido só dígitos", "52998224725", nil},
+		{"válido com pontuação", "529.982.247-25", nil},
+		{"válido com espaços", " 111 444 777 35 ", nil},
+		{"primeiro dígito com resto 10 vira 0", "10000000108", nil},
+		{"segundo dígito com resto 10 vira 0", "12345678810", nil},
+		{"primeiro dígito verificador errado", "52998224735", ErrCheckDigits},
+		{"segundo dígito verificador errado", "52998224724", ErrCheckDigits},
+		{"dígitos verificadores trocados", "52998224752", ErrCheckDigits},
+		{"resto 10 com dígito diferente de 0", "12345678811", ErrCheckDigits},
+		{"todos os dígitos iguais", "111.111.111-11", ErrRepeatedDigits},
+		{"zeros", "00000000000", ErrRepeatedDigits},
+		{"curto", "5299822472", ErrLength},
+		{"longo", "529982247250", ErrLength},
+		{"vazio", "", ErrLength},
+		{"só pontuação", "...-", ErrLength},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Validate(tt.value); got != tt.want {
+				t.Errorf("Validate(%q) = %v, esperado %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatAndMask(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      string
+		wantFormat string
+		wantMask   string
+	}{
+		{"só dígitos", "52998224725", "529.982.247-25", "***.***.247-**"},
+		{"já formatado", "529.982.247-25", "529.982.247-25", "***.***.247-**"},
+		{"tamanho errado", "1234", "1234", "***.***.***-**"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.value); got != tt.wantFormat {
+				t.Errorf("Format(%q) = %s, esperado %s", tt.value, got, tt.wantFormat)
+			}
+			if got := Mask(tt.value); got != tt.wantMask {
+				t.Errorf("Mask(%q) = %s, esperado %s", tt.value, got, tt.wantMask)
+			}
+		})
+	}
+}
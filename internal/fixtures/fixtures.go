@@ -0,0 +1,304 @@
+// Package fixtures lê arquivos de partidas (CSV ou JSON) para importação em lote. A conferência
+// com o banco (times, competições e partidas existentes) fica no repository.
+package fixtures
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Formatos aceitos
+const (
+	FORMAT_CSV  = "csv"
+	FORMAT_JSON = "json"
+)
+
+// Ações do relatório de importação
+const (
+	ACTION_CREATE = "create"
+	ACTION_UPDATE = "update"
+	ACTION_SKIP   = "skip"
+	ACTION_ERROR  = "error"
+)
+
+// Row é uma partida lida do arquivo. Line é a linha no CSV ou a posição (a partir de 1) no JSON.
+type Row struct {
+	Line        int
+	Competition string
+	HomeTeam    string
+	AwayTeam    string
+	Kickoff     time.Time
+	Venue       string
+	Status      string
+	Errors      []string
+}
+
+// RowResult é o resultado de uma linha no relatório
+type RowResult struct {
+	Line        int        `json:"line"`
+	Action      string     `json:"action"`
+	MatchID     *int       `json:"match_id,omitempty"`
+	Competition string     `json:"competition,omitempty"`
+	HomeTeam    string     `json:"home_team,omitempty"`
+	AwayTeam    string     `json:"away_team,omitempty"`
+	Kickoff     *time.Time `json:"kickoff,omitempty"`
+	Changes     []string   `json:"changes,omitempty"`
+	Errors      []string   `json:"errors,omitempty"`
+}
+
+// Report é o relatório da importação. Em DryRun nada foi gravado.
+type Report struct {
+	DryRun  bool           `json:"dry_run"`
+	Summary map[string]int `json:"summary"`
+	Rows    []RowResult    `json:"rows"`
+}
+
+// NewReport cria um relatório vazio com todas as ações zeradas no resumo
+func NewReport(dryRun bool) *Report {
+	return &Report{
+		DryRun: dryRun,
+		Summary: map[string]int{
+			ACTION_CREATE: 0,
+			ACTION_UPDATE: 0,
+			ACTION_SKIP:   0,
+			ACTION_ERROR:  0,
+		},
+		Rows: []RowResult{},
+	}
+}
+
+// Add inclui o resultado de uma linha no relatório
+func (r *Report) Add(result RowResult) {
+	r.Summary[result.Action]++
+	r.Rows = append(r.Rows, result)
+}
+
+// csvColumns mapeia os nomes de coluna aceitos (em minúsculas) para o campo da partida. Os nomes
+// Div, Date, Time, HomeTeam e AwayTeam são os dos arquivos do football-data.co.uk.
+var csvColumns = map[string]string{
+	"competition": "competition", "div": "competition", "league": "competition",
+	"home_team": "home", "hometeam": "home", "home": "home",
+	"away_team": "away", "awayteam": "away", "away": "away",
+	"kickoff": "kickoff", "match_date": "kickoff", "datetime": "kickoff",
+	"date": "date", "time": "time",
+	"venue": "venue", "location": "venue", "stadium": "venue",
+	"status": "status",
+}
+
+var dateLayouts = []string{"02/01/2006", "02/01/06", "2006-01-02"}
+
+var ErrMissingColumns = errors.New("o arquivo precisa das colunas do mandante, do visitante e da data")
+
+// Parse lê o arquivo no formato informado. Horários sem fuso são interpretados em loc.
+func Parse(format string, r io.Reader, loc *time.Location) ([]Row, error) {
+	switch format {
+	case FORMAT_CSV:
+		return ParseCSV(r, loc)
+	case FORMAT_JSON:
+		return ParseJSON(r, loc)
+	}
+	return nil, fmt.Errorf("formato inválido: %s (use csv ou json)", format)
+}
+
+// ParseCSV lê um CSV com cabeçalho. Colunas desconhecidas (ex: odds, FTHG) são ignoradas.
+func ParseCSV(r io.Reader, loc *time.Location) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler o cabeçalho: %v", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if field, ok := csvColumns[name]; ok {
+			if _, seen := columns[field]; !seen {
+				columns[field] = i
+			}
+		}
+	}
+	_, hasHome := columns["home"]
+	_, hasAway := columns["away"]
+	_, hasKickoff := columns["kickoff"]
+	_, hasDate := columns["date"]
+	if !hasHome || !hasAway || !(hasKickoff || hasDate) {
+		return nil, ErrMissingColumns
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		// Com erro de leitura o registro não tem campos e FieldPos não pode ser usado: a linha vem
+		// do ParseError
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, fmt.Errorf("linha %d: %v", parseErr.StartLine, parseErr.Err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if isBlank(record) {
+			continue
+		}
+
+		get := func(field string) string {
+			if i, ok := columns[field]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row := Row{
+			Line:        line,
+			Competition: get("competition"),
+			HomeTeam:    get("home"),
+			AwayTeam:    get("away"),
+			Venue:       get("venue"),
+			Status:      strings.ToLower(get("status")),
+		}
+		if hasKickoff && get("kickoff") != "" {
+			row.Kickoff, err = parseKickoff(get("kickoff"), loc)
+		} else {
+			row.Kickoff, err = parseDateAndTime(get("date"), get("time"), loc)
+		}
+		if err != nil {
+			row.Errors = append(row.Errors, err.Error())
+		}
+		rows = append(rows, row.validate())
+	}
+	return rows, nil
+}
+
+// jsonFixture é um item do arquivo JSON
+type jsonFixture struct {
+	Competition string `json:"competition"`
+	HomeTeam    string `json:"home_team"`
+	AwayTeam    string `json:"away_team"`
+	Kickoff     string `json:"kickoff"`
+	Venue       string `json:"venue"`
+	Status      string `json:"status"`
+}
+
+// ParseJSON lê um array JSON de partidas ou um objeto {"fixtures": [...]}
+func ParseJSON(r io.Reader, loc *time.Location) ([]Row, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []jsonFixture
+	if err := json.Unmarshal(data, &items); err != nil {
+		var wrapped struct {
+			Fixtures []jsonFixture `json:"fixtures"`
+		}
+		if err2 := json.Unmarshal(data, &wrapped); err2 != nil || wrapped.Fixtures == nil {
+			return nil, fmt.Errorf("JSON inválido: %v", err)
+		}
+		items = wrapped.Fixtures
+	}
+
+	rows := make([]Row, 0, len(items))
+	for i, item := range items {
+		row := Row{
+			Line:        i + 1,
+			Competition: strings.TrimSpace(item.Competition),
+			HomeTeam:    strings.TrimSpace(item.HomeTeam),
+			AwayTeam:    strings.TrimSpace(item.AwayTeam),
+			Venue:       strings.TrimSpace(item.Venue),
+			Status:      strings.ToLower(strings.TrimSpace(item.Status)),
+		}
+		if row.Kickoff, err = parseKickoff(strings.TrimSpace(item.Kickoff), loc); err != nil {
+			row.Errors = append(row.Errors, err.Error())
+		}
+		rows = append(rows, row.validate())
+	}
+	return rows, nil
+}
+
+func (row Row) validate() Row {
+	if row.HomeTeam == "" {
+		row.Errors = append(row.Errors, "mandante não informado")
+	}
+	if row.AwayTeam == "" {
+		row.Errors = append(row.Errors, "visitante não informado")
+	}
+	if row.HomeTeam != "" && NormalizeName(row.HomeTeam) == NormalizeName(row.AwayTeam) {
+		row.Errors = append(row.Errors, "mandante e visitante são o mesmo time")
+	}
+	return row
+}
+
+// parseKickoff aceita RFC3339 ou data e hora sem fuso ("2006-01-02 15:04")
+func parseKickoff(value string, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("data não informada")
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("data inválida: %s (use RFC3339 ou AAAA-MM-DD HH:MM)", value)
+}
+
+// parseDateAndTime junta as colunas Date e Time do football-data.co.uk. Sem horário a partida
+// fica à meia-noite do fuso informado.
+func parseDateAndTime(date, clock string, loc *time.Location) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, errors.New("data não informada")
+	}
+	for _, layout := range dateLayouts {
+		day, err := time.ParseInLocation(layout, date, loc)
+		if err != nil {
+			continue
+		}
+		if clock == "" {
+			return day.UTC(), nil
+		}
+		hm, err := time.Parse("15:04", clock)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("horário inválido: %s (use HH:MM)", clock)
+		}
+		return time.Date(day.Year(), day.Month(), day.Day(), hm.Hour(), hm.Minute(), 0, 0, loc).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("data inválida: %s (use DD/MM/AAAA ou AAAA-MM-DD)", date)
+}
+
+// NormalizeName padroniza nomes de times e competições para comparação (minúsculas, espaços simples)
+func NormalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// DetectFormat deduz o formato pelo Content-Type ou pela extensão do arquivo
+func DetectFormat(contentType, filename string) string {
+	switch {
+	case strings.Contains(contentType, "json"), strings.HasSuffix(strings.ToLower(filename), ".json"):
+		return FORMAT_JSON
+	case strings.Contains(contentType, "csv"), strings.HasSuffix(strings.ToLower(filename), ".csv"):
+		return FORMAT_CSV
+	}
+	return ""
+}
+
+func isBlank(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
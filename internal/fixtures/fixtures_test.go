@@ -0,0 +1,152 @@
+package fixtures
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCSVRows(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("fuso Europe/London indisponível: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		csv         string
+		loc         *time.Location
+		wantKickoff string
+		wantErrors  []string
+	}{
+		{
+			name:        "football-data com data e hora",
+			csv:         "Div,Date,Time,HomeTeam,AwayTeam,FTHG\nE0,16/08/2025,15:00,Arsenal,Chelsea,2\n",
+			loc:         london,
+			wantKickoff: "2025-08-16T14:00:00Z",
+		},
+		{
+			name:        "ano com dois dígitos e sem horário",
+			csv:         "Date,HomeTeam,AwayTeam\n16/08/25,Arsenal,Chelsea\n",
+			loc:         time.UTC,
+			wantKickoff: "2025-08-16T00:00:00Z",
+		},
+		{
+			name:        "kickoff RFC3339 ignora o fuso informado",
+			csv:         "home_team,away_team,kickoff\nFlamengo,Palmeiras,2026-05-10T19:00:00-03:00\n",
+			loc:         london,
+			wantKickoff: "2026-05-10T22:00:00Z",
+		},
+		{
+			name:        "kickoff sem fuso usa o fuso informado",
+			csv:         "home_team,away_team,kickoff\nArsenal,Chelsea,2026-01-10 12:30\n",
+			loc:         london,
+			wantKickoff: "2026-01-10T12:30:00Z",
+		},
+		{
+			name:       "data inválida",
+			csv:        "HomeTeam,AwayTeam,Date\nArsenal,Chelsea,2025/08/16\n",
+			loc:        time.UTC,
+			wantErrors: []string{"data inválida: 2025/08/16 (use DD/MM/AAAA ou AAAA-MM-DD)"},
+		},
+		{
+			name:       "horário inválido",
+			csv:        "HomeTeam,AwayTeam,Date,Time\nArsenal,Chelsea,16/08/2025,3pm\n",
+			loc:        time.UTC,
+			wantErrors: []string{"horário inválido: 3pm (use HH:MM)"},
+		},
+		{
+			name:       "linha com menos colunas",
+			csv:        "HomeTeam,AwayTeam,Date\nArsenal\n",
+			loc:        time.UTC,
+			wantErrors: []string{"data não informada", "visitante não informado"},
+		},
+		{
+			name:        "mesmo time com espaços e maiúsculas diferentes",
+			csv:         "HomeTeam,AwayTeam,Date\nMan  United,man united,16/08/2025\n",
+			loc:         time.UTC,
+			wantKickoff: "2025-08-16T00:00:00Z",
+			wantErrors:  []string{"mandante e visitante são o mesmo time"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ParseCSV(strings.NewReader(tt.csv), tt.loc)
+			if err != nil {
+				t.Fatalf("erro inesperado: %v", err)
+			}
+			if len(rows) != 1 {
+				t.Fatalf("ParseCSV() retornou %d linhas, esperado 1", len(rows))
+			}
+			row := rows[0]
+			if row.Line != 2 {
+				t.Errorf("Line = %d, esperado 2", row.Line)
+			}
+			if tt.wantKickoff != "" && row.Kickoff.Format(time.RFC3339) != tt.wantKickoff {
+				t.Errorf("Kickoff = %s, esperado %s", row.Kickoff.Format(time.RFC3339), tt.wantKickoff)
+			}
+			if strings.Join(row.Errors, "; ") != strings.Join(tt.wantErrors, "; ") {
+				t.Errorf("Errors = %q, esperado %q", row.Errors, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestParseCSVMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		wantErr string
+	}{
+		{"sem colunas obrigatórias", "HomeTeam,Date\nArsenal,16/08/2025\n", ErrMissingColumns.Error()},
+		{"arquivo vazio", "", "erro ao ler o cabeçalho"},
+		{"aspas sem fechar", "HomeTeam,AwayTeam,Date\nArsenal,Chelsea,16/08/2025\n\"Leeds,Everton,17/08/2025\n", "linha 3"},
+		{"aspas no meio do campo", "HomeTeam,AwayTeam,Date\nArse\"nal,Chelsea,16/08/2025\n", "linha 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCSV(strings.NewReader(tt.csv), time.UTC)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseCSV() erro = %v, esperado um erro com %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseCSVSkipsBlankLines(t *testing.T) {
+	rows, err := ParseCSV(strings.NewReader("\ufeffHomeTeam,AwayTeam,Date\n\n,,\nArsenal,Chelsea,16/08/2025\n"), time.UTC)
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Line != 4 {
+		t.Fatalf("ParseCSV() = %+v, esperado uma partida na linha 4", rows)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		wantRows int
+		wantErr  bool
+	}{
+		{"array", `[{"home_team":"A","away_team":"B","kickoff":"2026-05-10T19:00:00Z"}]`, 1, false},
+		{"objeto fixtures", `{"fixtures":[{"home_team":"A","away_team":"B","kickoff":"2026-05-10 19:00"}]}`, 1, false},
+		{"objeto sem fixtures", `{"partidas":[]}`, 0, true},
+		{"JSON quebrado", `[{"home_team":"A"`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ParseJSON(strings.NewReader(tt.json), time.UTC)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseJSON() erro = %v, esperado erro: %v", err, tt.wantErr)
+			}
+			if len(rows) != tt.wantRows {
+				t.Errorf("ParseJSON() retornou %d linhas, esperado %d", len(rows), tt.wantRows)
+			}
+		})
+	}
+}
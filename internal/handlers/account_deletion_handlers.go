@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/repository"
+	"smartpicks-backend/internal/services"
+)
+
+// accountDeletionGrace é o prazo entre o pedido de exclusão e a anonimização da conta
+// (ACCOUNT_DELETION_GRACE). Lido direto do ambiente porque config.Duration descarta o 0,
+// que aqui significa anonimizar imediatamente.
+func accountDeletionGrace() time.Duration {
+	grace, err := time.ParseDuration(strings.TrimSpace(os.Getenv("ACCOUNT_DELETION_GRACE")))
+	if err != nil || grace < 0 {
+		return 30 * 24 * time.Hour
+	}
+	return grace
+}
+
+// DeleteMyAccount agenda a exclusão da conta do usuário autenticado. Até o fim do prazo de
+// carência o usuário pode entrar novamente e cancelar; depois disso os dados pessoais são removidos.
+func DeleteMyAccount(w http.ResponseWriter, r *http.Request) {
+	authUser := GetAuthUser(r)
+	if authUser == nil {
+		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		sendErrorResponse(w, "Informe a senha para confirmar a exclusão da conta", http.StatusBadRequest)
+		return
+	}
+
+	var nome, email, password string
+	err := database.DB.QueryRow(`
+		SELECT nome, COALESCE(email, ''), password FROM users
+		WHERE id = $1 AND anonymized_at IS NULL`, authUser.ID).Scan(&nome, &email, &password)
+	if err != nil {
+		sendErrorResponse(w, "Usuário não encontrado", http.StatusNotFound)
+		return
+	}
+	if !verifyCurrentPassword(w, email, password, req.Password, "Senha incorreta") {
+		return
+	}
+
+	scheduledFor := time.Now().Add(accountDeletionGrace())
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		sendErrorResponse(w, "Erro ao excluir conta", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE users SET deletion_requested_at = CURRENT_TIMESTAMP, deletion_scheduled_for = $2
+		WHERE id = $1`, authUser.ID, scheduledFor); err != nil {
+		sendErrorResponse(w, "Erro ao excluir conta", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec(`
+		UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP, revoked_reason = 'account_deletion'
+		WHERE user_id = $1 AND revoked_at IS NULL`, authUser.ID); err != nil {
+		sendErrorResponse(w, "Erro ao excluir conta", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "Erro ao excluir conta", http.StatusInternalServerError)
+		return
+	}
+
+	// Sem prazo de carência a conta é anonimizada imediatamente
+	if accountDeletionGrace() <= 0 {
+		if err := repository.AnonymizeUser(authUser.ID); err != nil {
+			log.Printf("Erro ao anonimizar usuário %d: %v", authUser.ID, err)
+			sendErrorResponse(w, "Erro ao excluir conta", http.StatusInternalServerError)
+			return
+		}
+		sendSuccessResponse(w, map[string]string{"message": "Conta excluída com sucesso"})
+		return
+	}
+
+	if email != "" {
+		err := mailer.Send(services.Email{
+			To:      email,
+			Subject: "SmartPicks - Exclusão de conta agendada",
+			Body: fmt.Sprintf("Olá, %s!\n\nRecebemos o pedido de exclusão da sua conta. Ela será removida em %s.\n\n"+
+				"Para cancelar, basta entrar novamente no SmartPicks antes dessa data e cancelar a exclusão.",
+				nome, scheduledFor.Format("02/01/2006 15:04")),
+		})
+		if err != nil {
+			log.Printf("Erro ao enviar email de exclusão de conta: %v", err)
+		}
+	}
+
+	sendSuccessResponse(w, map[string]interface{}{
+		"message":                "Exclusão da conta agendada. Todas as sessões foram encerradas",
+		"deletion_scheduled_for": scheduledFor.UTC(),
+	})
+}
+
+// CancelMyAccountDeletion cancela um pedido de exclusão ainda dentro do prazo de carência
+func CancelMyAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	authUser := GetAuthUser(r)
+	if authUser == nil {
+		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		UPDATE users SET deletion_requested_at = NULL, deletion_scheduled_for = NULL
+		WHERE id = $1 AND deletion_scheduled_for > CURRENT_TIMESTAMP AND anonymized_at IS NULL`, authUser.ID)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao cancelar exclusão", http.StatusInternalServerError)
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		sendErrorResponse(w, "Não há exclusão agendada para esta conta", http.StatusNotFound)
+		return
+	}
+
+	sendSuccessResponse(w, map[string]string{"message": "Exclusão da conta cancelada"})
+}
+
+// PurgeDeletedAccounts anonimiza as contas cujo prazo de carência terminou. A rota exige a
+// permissão users:delete e pode ser chamada por um cron (ou use o comando purge-accounts).
+func PurgeDeletedAccounts(w http.ResponseWriter, r *http.Request) {
+	purged, err := repository.PurgeScheduledDeletions()
+	if err != nil {
+		log.Printf("Erro ao remover contas: %v", err)
+		sendErrorResponse(w, "Erro ao remover contas", http.StatusInternalServerError)
+		return
+	}
+
+	sendSuccessResponse(w, map[string]interface{}{
+		"purged":  purged,
+		"message": fmt.Sprintf("%d contas anonimizadas", purged),
+	})
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"smartpicks-backend/internal/config"
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/services"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Finalidades dos tokens de uso único enviados por email (tabela user_tokens)
+const (
+	tokenPurposePasswordReset     = "password_reset"
+	tokenPurposeEmailVerification = "email_verification"
+	tokenPurposeEmailChange       = "email_change"
+)
+
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+)
+
+var mailer services.Mailer = &services.LogMailer{}
+
+// SetMailer define a implementação usada para enviar emails (configurada no startup)
+func SetMailer(m services.Mailer) {
+	mailer = m
+}
+
+// ForgotPassword envia um link de redefinição de senha. A resposta é sempre a mesma,
+// exista ou não o email, para não permitir enumeração de contas.
+func ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		sendErrorResponse(w, "Email é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	var userID int
+	var nome string
+	err := database.DB.QueryRow("SELECT id, nome FROM users WHERE email = $1", req.Email).Scan(&userID, &nome)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Erro ao buscar usuário para redefinição de senha: %v", err)
+	}
+
+	if err == nil {
+		token, err := createUserToken(userID, tokenPurposePasswordReset, passwordResetTTL)
+		if err != nil {
+			log.Printf("Erro ao gerar token de redefinição de senha: %v", err)
+		} else {
+			link := fmt.Sprintf("%s/redefinir-senha?token=%s", appURL(), token)
+			err = mailer.Send(services.Email{
+				To:      req.Email,
+				Subject: "SmartPicks - Redefinição de senha",
+				Body: fmt.Sprintf("Olá, %s!\n\nRecebemos um pedido para redefinir a sua senha. "+
+					"Acesse o link abaixo (válido por 1 hora):\n\n%s\n\n"+
+					"Se você não fez esse pedido, ignore este email.", nome, link),
+			})
+			if err != nil {
+				log.Printf("Erro ao enviar email de redefinição de senha: %v", err)
+			}
+		}
+	}
+
+	sendSuccessResponse(w, map[string]string{
+		"message": "Se o email estiver cadastrado, você receberá um link para redefinir a senha",
+	})
+}
+
+// ResetPassword define uma nova senha a partir de um token de redefinição válido
+// e encerra todas as sessões do usuário
+func ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	if req.Token == "" || req.Password == "" {
+		sendErrorResponse(w, "Token e password são obrigatórios", http.StatusBadRequest)
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao processar password", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		sendErrorResponse(w, "Erro ao redefinir senha", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	userID, err := consumeUserToken(tx, req.Token, tokenPurposePasswordReset)
+	if err == errInvalidUserToken {
+		sendErrorResponse(w, "Token inválido ou expirado", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		sendErrorResponse(w, "Erro ao redefinir senha", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec("UPDATE users SET password = $1 WHERE id = $2", string(hashedPassword), userID); err != nil {
+		sendErrorResponse(w, "Erro ao redefinir senha", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP, revoked_reason = 'password_reset'
+		WHERE user_id = $1 AND revoked_at IS NULL`, userID); err != nil {
+		sendErrorResponse(w, "Erro ao redefinir senha", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "Erro ao redefinir senha", http.StatusInternalServerError)
+		return
+	}
+
+	sendSuccessResponse(w, map[string]string{"message": "Senha redefinida com sucesso"})
+}
+
+// VerifyEmail confirma o email do usuário a partir do link enviado no cadastro
+func VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		sendErrorResponse(w, "Token é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		sendErrorResponse(w, "Erro ao verificar email", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	userID, err := consumeUserToken(tx, token, tokenPurposeEmailVerification)
+	if err == errInvalidUserToken {
+		sendErrorResponse(w, "Token inválido ou expirado", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		sendErrorResponse(w, "Erro ao verificar email", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE users SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP)
+		WHERE id = $1`, userID); err != nil {
+		sendErrorResponse(w, "Erro ao verificar email", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "Erro ao verificar email", http.StatusInternalServerError)
+		return
+	}
+
+	sendSuccessResponse(w, map[string]string{"message": "Email verificado com sucesso"})
+}
+
+// sendVerificationEmail gera um token de verificação e envia o link para o email do usuário
+func sendVerificationEmail(userID int, nome, email string) error {
+	token, err := createUserToken(userID, tokenPurposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/api/auth/verify-email?token=%s", apiURL(), token)
+	return mailer.Send(services.Email{
+		To:      email,
+		Subject: "SmartPicks - Confirme seu email",
+		Body: fmt.Sprintf("Olá, %s!\n\nConfirme o seu email acessando o link abaixo (válido por 48 horas):\n\n%s",
+			nome, link),
+	})
+}
+
+var errInvalidUserToken = errors.New("token inválido ou expirado")
+
+// createUserToken gera um token de uso único para a finalidade informada. Tokens anteriores
+// ainda não usados com a mesma finalidade são invalidados.
+func createUserToken(userID int, purpose string, ttl time.Duration) (string, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	token, err := createUserTokenTx(tx, userID, purpose, ttl)
+	if err != nil {
+		return "", err
+	}
+	return token, tx.Commit()
+}
+
+// createUserTokenTx é o createUserToken dentro da transação informada
+func createUserTokenTx(tx *sql.Tx, userID int, purpose string, ttl time.Duration) (string, error) {
+	token, err := services.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE user_tokens SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`, userID, purpose); err != nil {
+		return "", err
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)`,
+		userID, purpose, services.HashOpaqueToken(token), time.Now().Add(ttl)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeUserToken marca o token como usado e retorna o usuário dono dele.
+// Retorna errInvalidUserToken se o token não existir, já tiver sido usado ou estiver expirado.
+func consumeUserToken(tx *sql.Tx, token, purpose string) (int, error) {
+	var userID int
+	err := tx.QueryRow(`
+		UPDATE user_tokens SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND purpose = $2
+		  AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING user_id`, services.HashOpaqueToken(token), purpose).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, errInvalidUserToken
+	}
+	return userID, err
+}
+
+// appURL é o endereço do frontend usado nos links enviados por email
+func appURL() string {
+	return strings.TrimSuffix(config.String("APP_URL", "http://localhost:9000"), "/")
+}
+
+// apiURL é o endereço público desta API
+func apiURL() string {
+	return strings.TrimSuffix(config.String("API_URL", "http://localhost:8080"), "/")
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/repository"
+	"smartpicks-backend/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// UnlockUserLogin remove o bloqueio de login de uma conta (e, opcionalmente, de um IP).
+// A rota exige a permissão users:unlock.
+func UnlockUserLogin(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendErrorResponse(w, "ID do usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		IP string `json:"ip"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendErrorResponse(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var email string
+	err = database.DB.QueryRow("SELECT COALESCE(email, '') FROM users WHERE id = $1", userID).Scan(&email)
+	if err == sql.ErrNoRows {
+		sendErrorResponse(w, "Usuário não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sendErrorResponse(w, "Erro ao buscar usuário", http.StatusInternalServerError)
+		return
+	}
+
+	keys := []string{services.AccountLoginKey(email), services.TwoFactorLoginKey(userID)}
+	if req.IP != "" {
+		keys = append(keys, services.IPLoginKey(req.IP))
+	}
+	for _, key := range keys {
+		if err := loginGuard.Reset(key); err != nil {
+			sendErrorResponse(w, "Erro ao desbloquear conta", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	sendSuccessResponse(w, map[string]string{"message": "Conta desbloqueada com sucesso"})
+}
+
+// UpdateUserPerfil altera o perfil de um usuário. A rota exige a permissão users:manage_roles.
+// O novo perfil vale a partir da próxima requisição, mesmo com access tokens já emitidos.
+func UpdateUserPerfil(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendErrorResponse(w, "ID do usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	if userID == GetUserIDFromRequest(r) {
+		sendErrorResponse(w, "Você não pode alterar o seu próprio perfil", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		Perfil string `json:"perfil"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	if !models.IsValidPerfil(req.Perfil) {
+		sendErrorResponse(w, invalidPerfilMessage(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := database.DB.Exec("UPDATE users SET perfil = $1 WHERE id = $2", req.Perfil, userID)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao atualizar perfil", http.StatusInternalServerError)
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		sendErrorResponse(w, "Usuário não encontrado", http.StatusNotFound)
+		return
+	}
+
+	user, err := loadUserByID(userID)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao buscar usuário", http.StatusInternalServerError)
+		return
+	}
+
+	sendSuccessResponse(w, models.UserPermissionsResponse{
+		UserResponse: userResponse(&user),
+		Permissions:  models.PermissionsFor(user.Perfil),
+	})
+}
+
+// GetDuplicatePalpites lista os grupos de palpites com imagens repetidas (hash perceptual), com o
+// autor e a data do original e de cada cópia. A rota exige a permissão palpites:moderate.
+func GetDuplicatePalpites(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	clusters, err := repository.ListDuplicateClusters(limit, offset)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao buscar imagens repetidas", http.StatusInternalServerError)
+		return
+	}
+
+	sendSuccessResponse(w, map[string]interface{}{
+		"clusters":     clusters,
+		"max_distance": repository.DuplicateMaxDistance(),
+		"limit":        limit,
+		"offset":       offset,
+	})
+}
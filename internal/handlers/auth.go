@@ -1,14 +1,20 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
+	"smartpicks-backend/internal/cpf"
 	"smartpicks-backend/internal/database"
 	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/repository"
+	"smartpicks-backend/internal/services"
 
+	"github.com/gorilla/mux"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -25,26 +31,69 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	accountKey := services.AccountLoginKey(loginData.Email)
+	ipKey := services.IPLoginKey(clientIP(r))
+	if retryAfter := checkLoginLockout(accountKey, ipKey); retryAfter > 0 {
+		sendLockoutResponse(w, retryAfter)
+		return
+	}
+
 	var user models.User
-	err := database.DB.QueryRow(`
-		SELECT id, nome, email, password, cpf,
-			   TO_CHAR(data_nascimento, 'YYYY-MM-DD') as data_nascimento,
-			   perfil, COALESCE(avatar, '') as avatar, created_at, updated_at 
-		FROM users WHERE email = $1`, loginData.Email).
-		Scan(&user.ID, &user.Nome, &user.Email, &user.Password,
-			&user.CPF, &user.DataNascimento, &user.Perfil, &user.Avatar, &user.CreatedAt, &user.UpdatedAt)
-	if err != nil {
+	var totpEnabledAt sql.NullTime
+	err := scanUser(database.DB.QueryRow(`
+		SELECT `+userColumns+`, password, totp_enabled_at
+		FROM users WHERE email = $1`, loginData.Email), &user, &user.Password, &totpEnabledAt)
+	if err != nil && err != sql.ErrNoRows {
 		log.Printf("Erro ao buscar usuário: %v", err)
+	}
+
+	// Sem usuário a senha é comparada com um hash fixo, gastando o mesmo tempo do bcrypt
+	hashedPassword := user.Password
+	if err != nil {
+		hashedPassword = dummyPasswordHash
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(loginData.Password)) != nil || err != nil {
+		if lockout := registerLoginFailure(accountKey, ipKey); lockout > 0 {
+			sendLockoutResponse(w, lockout)
+			return
+		}
 		sendErrorResponse(w, "Email ou senha incorretos", http.StatusUnauthorized)
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginData.Password)); err != nil {
-		sendErrorResponse(w, "Email ou senha incorretos", http.StatusUnauthorized)
+	if err := loginGuard.Reset(accountKey); err != nil {
+		log.Printf("Erro ao zerar tentativas de login: %v", err)
+	}
+
+	// Usuários com 2FA ativo precisam concluir o login em /auth/2fa/verify
+	if totpEnabledAt.Valid {
+		sendTwoFactorChallenge(w, user.ID)
+		return
+	}
+
+	// Com REQUIRE_ADMIN_2FA, administradores sem 2FA precisam cadastrá-lo antes de entrar,
+	// pelo link enviado ao email da conta
+	if user.IsAdmin() && adminTwoFactorRequired() {
+		sendTwoFactorSetupLink(w, &user)
 		return
 	}
 
-	sendSuccessResponse(w, user.ToResponse())
+	sendLoginResponse(w, r, &user)
+}
+
+// sendLoginResponse abre uma sessão para o usuário e responde com seus dados e tokens
+func sendLoginResponse(w http.ResponseWriter, r *http.Request, user *models.User) {
+	tokens, err := createSession(r, user.ID, user.Perfil)
+	if err != nil {
+		log.Printf("Erro ao criar sessão: %v", err)
+		sendErrorResponse(w, "Erro ao gerar token de acesso", http.StatusInternalServerError)
+		return
+	}
+
+	sendSuccessResponse(w, models.AuthResponse{
+		UserResponse:  userResponse(user),
+		TokenResponse: tokens,
+	})
 }
 
 func Register(w http.ResponseWriter, r *http.Request) {
@@ -61,35 +110,74 @@ func Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if user.Perfil == "" {
-		user.Perfil = "user"
+		user.Perfil = models.PERFIL_USER
 	}
 
 	if !models.IsValidPerfil(user.Perfil) {
-		sendErrorResponse(w, "Perfil inválido. Use 'admin' ou 'user'", http.StatusBadRequest)
+		sendErrorResponse(w, invalidPerfilMessage(), http.StatusBadRequest)
 		return
 	}
 
-	// Validar formato da data
-	parsedDate, err := time.Parse("2006-01-02", user.DataNascimento)
-	if err != nil {
-		parsedDate, err = time.Parse("02/01/2006", user.DataNascimento)
-		if err != nil {
-			sendErrorResponse(w, "Formato de data inválido. Use YYYY-MM-DD ou DD/MM/YYYY", http.StatusBadRequest)
+	// Só quem pode gerenciar perfis cadastra usuários com perfil diferente de "user"
+	if user.Perfil != models.PERFIL_USER {
+		authUser := GetAuthUser(r)
+		if authUser == nil || !authUser.HasPermission(models.PERMISSION_USERS_MANAGE_ROLES) {
+			sendErrorResponse(w, "Você não tem permissão para cadastrar usuários com este perfil", http.StatusForbidden)
 			return
 		}
 	}
-	user.DataNascimento = parsedDate.Format("2006-01-02")
+
+	user.CPF = cpf.Normalize(user.CPF)
+	if err := cpf.Validate(user.CPF); err != nil {
+		sendErrorResponse(w, "CPF inválido: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dataNascimento, err := parseDataNascimento(user.DataNascimento)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	user.DataNascimento = dataNascimento
+
+	// O avatar enviado no cadastro vai para o storage depois que o usuário tiver ID; no banco só fica a URL
+	var avatarValue string
+	if user.Avatar != nil {
+		avatarValue = *user.Avatar
+	}
+	avatarData, avatarURL, err := decodeAvatarValue(avatarValue)
+	if err != nil {
+		sendErrorResponse(w, "Avatar inválido: envie uma imagem em base64, uma data URL ou uma URL http(s)", http.StatusBadRequest)
+		return
+	}
+	user.Avatar = nil
+	if avatarURL != "" {
+		user.Avatar = &avatarURL
+	}
 
 	if userExists("email", user.Email) {
 		sendErrorResponse(w, "Email já cadastrado", http.StatusConflict)
 		return
 	}
 
-	if userExists("cpf", user.CPF) {
+	cpfExists, err := repository.CPFExists(user.CPF)
+	if err != nil {
+		log.Printf("Erro ao verificar CPF: %v", err)
+		sendErrorResponse(w, "Erro ao verificar CPF", http.StatusInternalServerError)
+		return
+	}
+	if cpfExists {
 		sendErrorResponse(w, "CPF já cadastrado", http.StatusConflict)
 		return
 	}
 
+	cpfEncrypted, cpfHash, err := repository.EncryptCPF(user.CPF)
+	if err != nil {
+		log.Printf("Erro ao cifrar CPF: %v", err)
+		sendErrorResponse(w, "Erro ao processar CPF", http.StatusInternalServerError)
+		return
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
 		sendErrorResponse(w, "Erro ao processar password", http.StatusInternalServerError)
@@ -98,48 +186,293 @@ func Register(w http.ResponseWriter, r *http.Request) {
 
 	var userID int
 	err = database.DB.QueryRow(`
-		INSERT INTO users (nome, email, password, cpf, data_nascimento, perfil, avatar)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO users (nome, email, password, cpf_encrypted, cpf_hash, data_nascimento, perfil, avatar)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id`,
-		user.Nome, user.Email, string(hashedPassword), user.CPF, user.DataNascimento, user.Perfil, user.Avatar).Scan(&userID)
+		user.Nome, user.Email, string(hashedPassword), cpfEncrypted, cpfHash, user.DataNascimento, user.Perfil, user.Avatar).Scan(&userID)
 
 	if err != nil {
 		sendErrorResponse(w, "Erro ao cadastrar usuário", http.StatusInternalServerError)
 		return
 	}
 
-	err = database.DB.QueryRow(`
-		SELECT id, nome, email, cpf,
-			   TO_CHAR(data_nascimento, 'YYYY-MM-DD') as data_nascimento,
-			   perfil, COALESCE(avatar, '') as avatar, created_at, updated_at 
-		FROM users WHERE id = $1`, userID).
-		Scan(&user.ID, &user.Nome, &user.Email, &user.CPF,
-			&user.DataNascimento, &user.Perfil, &user.Avatar, &user.CreatedAt, &user.UpdatedAt)
+	// Um avatar com problema não impede o cadastro; o usuário pode enviá-lo de novo depois
+	if len(avatarData) > 0 {
+		if err := saveUserAvatar(&models.User{ID: userID}, avatarData); err != nil {
+			log.Printf("Erro ao salvar avatar do usuário %d: %v", userID, err)
+		}
+	}
 
+	err = scanUser(database.DB.QueryRow(`
+		SELECT `+userColumns+`
+		FROM users WHERE id = $1`, userID), &user)
 	if err != nil {
-		log.Printf("Erro ao cadastrar usuário: %v", err)
-		sendErrorResponse(w, "Erro ao cadastrar usuário: "+err.Error(), http.StatusInternalServerError)
+		log.Printf("Erro ao buscar usuário cadastrado: %v", err)
+		sendErrorResponse(w, "Erro ao buscar usuário cadastrado", http.StatusInternalServerError)
 		return
 	}
 
-	// Buscar usuário recém-criado
-	err = database.DB.QueryRow(`
-		SELECT id, nome, email, password, cpf,
-			   TO_CHAR(data_nascimento, 'YYYY-MM-DD') as data_nascimento,
-			   perfil, avatar, created_at, updated_at
-		FROM users WHERE id=$1
-	`, user.ID).Scan(
-		&user.ID, &user.Nome, &user.Email, &user.Password,
-		&user.CPF, &user.DataNascimento, &user.Perfil, &user.Avatar,
-		&user.CreatedAt, &user.UpdatedAt,
-	)
+	if err := sendVerificationEmail(user.ID, user.Nome, user.Email); err != nil {
+		log.Printf("Erro ao enviar email de verificação para o usuário %d: %v", user.ID, err)
+	}
 
+	sendJSONResponse(w, userResponse(&user), http.StatusCreated)
+}
+
+// RefreshToken troca um refresh token válido por um novo par de tokens (rotação).
+// Se um refresh token já utilizado for apresentado novamente, toda a sessão é revogada.
+func RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		sendErrorResponse(w, "refresh_token é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
 	if err != nil {
-		log.Printf("Erro ao buscar usuário cadastrado: %v", err)
-		sendErrorResponse(w, "Erro ao buscar usuário cadastrado: "+err.Error(), http.StatusInternalServerError)
+		sendErrorResponse(w, "Erro ao renovar sessão", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var tokenID, sessionID, userID int
+	var usedAt, revokedAt sql.NullTime
+	var expiresAt time.Time
+	var perfil string
+	err = tx.QueryRow(`
+		SELECT rt.id, rt.used_at, s.id, s.user_id, s.expires_at, s.revoked_at, u.perfil
+		FROM refresh_tokens rt
+		JOIN sessions s ON s.id = rt.session_id
+		JOIN users u ON u.id = s.user_id
+		WHERE rt.token_hash = $1
+		FOR UPDATE OF rt, s`, services.HashOpaqueToken(req.RefreshToken)).
+		Scan(&tokenID, &usedAt, &sessionID, &userID, &expiresAt, &revokedAt, &perfil)
+	if err == sql.ErrNoRows {
+		sendErrorResponse(w, "Refresh token inválido", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao buscar refresh token: %v", err)
+		sendErrorResponse(w, "Erro ao renovar sessão", http.StatusInternalServerError)
+		return
+	}
+
+	if usedAt.Valid {
+		// Reuso de um token já rotacionado: assume vazamento e revoga a sessão inteira
+		if _, err := tx.Exec(`
+			UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP, revoked_reason = 'reuse_detected'
+			WHERE id = $1 AND revoked_at IS NULL`, sessionID); err != nil {
+			sendErrorResponse(w, "Erro ao renovar sessão", http.StatusInternalServerError)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			sendErrorResponse(w, "Erro ao renovar sessão", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Reuso de refresh token detectado na sessão %d do usuário %d", sessionID, userID)
+		sendErrorResponse(w, "Refresh token já utilizado. A sessão foi revogada, faça login novamente", http.StatusUnauthorized)
+		return
+	}
+
+	if revokedAt.Valid || time.Now().After(expiresAt) {
+		sendErrorResponse(w, "Sessão expirada ou revogada, faça login novamente", http.StatusUnauthorized)
+		return
+	}
+
+	if _, err := tx.Exec("UPDATE refresh_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1", tokenID); err != nil {
+		sendErrorResponse(w, "Erro ao renovar sessão", http.StatusInternalServerError)
+		return
+	}
+
+	refreshToken, err := insertRefreshToken(tx, sessionID)
+	if err != nil {
+		log.Printf("Erro ao gerar refresh token: %v", err)
+		sendErrorResponse(w, "Erro ao renovar sessão", http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	sendSuccessResponse(w, user.ToResponse())
+	if _, err := tx.Exec(`
+		UPDATE sessions SET last_used_at = CURRENT_TIMESTAMP, ip_address = $2, user_agent = $3
+		WHERE id = $1`, sessionID, clientIP(r), r.UserAgent()); err != nil {
+		sendErrorResponse(w, "Erro ao renovar sessão", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "Erro ao renovar sessão", http.StatusInternalServerError)
+		return
+	}
+
+	tokens, err := buildTokenResponse(userID, perfil, sessionID, refreshToken)
+	if err != nil {
+		log.Printf("Erro ao gerar access token: %v", err)
+		sendErrorResponse(w, "Erro ao gerar token de acesso", http.StatusInternalServerError)
+		return
+	}
+
+	sendSuccessResponse(w, tokens)
+}
+
+// Logout revoga a sessão atual (do access token) ou a sessão do refresh token informado
+func Logout(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendErrorResponse(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var result sql.Result
+	var err error
+	switch authUser := GetAuthUser(r); {
+	case authUser != nil && authUser.SessionID > 0:
+		result, err = database.DB.Exec(`
+			UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP, revoked_reason = 'logout'
+			WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`, authUser.SessionID, authUser.ID)
+	case req.RefreshToken != "":
+		result, err = database.DB.Exec(`
+			UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP, revoked_reason = 'logout'
+			WHERE revoked_at IS NULL
+			  AND id = (SELECT session_id FROM refresh_tokens WHERE token_hash = $1)`,
+			services.HashOpaqueToken(req.RefreshToken))
+	default:
+		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
+		return
+	}
+
+	if err != nil {
+		sendErrorResponse(w, "Erro ao encerrar sessão", http.StatusInternalServerError)
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		sendErrorResponse(w, "Sessão não encontrada ou já encerrada", http.StatusNotFound)
+		return
+	}
+
+	sendSuccessResponse(w, map[string]string{"message": "Logout realizado com sucesso"})
+}
+
+// GetMySessions lista as sessões ativas do usuário autenticado
+func GetMySessions(w http.ResponseWriter, r *http.Request) {
+	authUser := GetAuthUser(r)
+	if authUser == nil {
+		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, user_id, user_agent, ip_address, created_at, last_used_at, expires_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY last_used_at DESC`, authUser.ID)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao buscar sessões", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IPAddress,
+			&s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt); err != nil {
+			sendErrorResponse(w, "Erro ao processar sessões", http.StatusInternalServerError)
+			return
+		}
+		s.Current = s.ID == authUser.SessionID
+		sessions = append(sessions, s)
+	}
+
+	sendSuccessResponse(w, map[string]interface{}{
+		"sessions": sessions,
+		"total":    len(sessions),
+	})
+}
+
+// RevokeMySession revoga uma sessão do usuário autenticado
+func RevokeMySession(w http.ResponseWriter, r *http.Request) {
+	authUser := GetAuthUser(r)
+	if authUser == nil {
+		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendErrorResponse(w, "ID da sessão inválido", http.StatusBadRequest)
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP, revoked_reason = 'revoked_by_user'
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`, sessionID, authUser.ID)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao revogar sessão", http.StatusInternalServerError)
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		sendErrorResponse(w, "Sessão não encontrada", http.StatusNotFound)
+		return
+	}
+
+	sendSuccessResponse(w, map[string]string{"message": "Sessão revogada com sucesso"})
+}
+
+// createSession abre uma nova sessão para o usuário e emite o primeiro par de tokens
+func createSession(r *http.Request, userID int, perfil string) (models.TokenResponse, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	defer tx.Rollback()
+
+	var sessionID int
+	err = tx.QueryRow(`
+		INSERT INTO sessions (user_id, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`,
+		userID, r.UserAgent(), clientIP(r), time.Now().Add(services.RefreshTokenTTL())).Scan(&sessionID)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	refreshToken, err := insertRefreshToken(tx, sessionID)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	return buildTokenResponse(userID, perfil, sessionID, refreshToken)
+}
+
+// insertRefreshToken gera um novo refresh token para a sessão e salva apenas o seu hash
+func insertRefreshToken(tx *sql.Tx, sessionID int) (string, error) {
+	refreshToken, err := services.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO refresh_tokens (session_id, token_hash)
+		VALUES ($1, $2)`, sessionID, services.HashOpaqueToken(refreshToken))
+	if err != nil {
+		return "", err
+	}
+	return refreshToken, nil
+}
+
+func buildTokenResponse(userID int, perfil string, sessionID int, refreshToken string) (models.TokenResponse, error) {
+	accessToken, expiresAt, err := services.GenerateAccessToken(userID, perfil, sessionID)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	return models.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(time.Until(expiresAt).Seconds()),
+	}, nil
 }
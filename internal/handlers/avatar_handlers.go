@@ -1,122 +1,232 @@
 package handlers
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+
 	"smartpicks-backend/internal/database"
 	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/repository"
+	"smartpicks-backend/internal/services"
 )
 
-func UpdateAvatar(w http.ResponseWriter, r *http.Request) {
-	var requestData struct {
-		UserID int    `json:"user_id"`
-		Avatar string `json:"avatar"`
-	}
+// maxAvatarRequestSize limita o corpo da requisição: 5MB de imagem ocupam ~7MB em base64
+const maxAvatarRequestSize = 8 << 20
 
-	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
-		sendErrorResponse(w, "Dados inválidos fornecidos", http.StatusBadRequest)
+// UpdateAvatar recebe um novo avatar (multipart no campo "avatar" ou JSON com base64/data URL),
+// grava as versões redimensionadas no storage e guarda apenas as URLs no banco. Sem user_id altera o
+// avatar do próprio usuário; alterar o de outro exige a permissão users:edit_any.
+func UpdateAvatar(w http.ResponseWriter, r *http.Request) {
+	authUser := GetAuthUser(r)
+	if authUser == nil {
+		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
 		return
 	}
 
-	if requestData.UserID <= 0 {
-		sendErrorResponse(w, "ID do usuário é obrigatório", http.StatusBadRequest)
-		return
-	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarRequestSize)
 
-	if requestData.Avatar != "" {
-		const maxBase64Len = 7 * 1024 * 1024 // ~5MB em Base64
-		if len(requestData.Avatar) > maxBase64Len {
-			sendErrorResponse(w, "Avatar muito grande. Máximo 5MB", http.StatusBadRequest)
+	var userID int
+	var data []byte
+	var avatarURL string
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxAvatarRequestSize); err != nil {
+			sendErrorResponse(w, "Arquivo muito grande. Máximo 5MB", http.StatusBadRequest)
 			return
 		}
-	}
+		userID, _ = strconv.Atoi(r.FormValue("user_id"))
 
-	var avatarPtr *string
-	if requestData.Avatar != "" {
-		avatarPtr = &requestData.Avatar
-	}
+		file, _, err := r.FormFile("avatar")
+		if err != nil {
+			sendErrorResponse(w, "Erro ao receber arquivo: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
 
-	result, err := database.DB.Exec("UPDATE users SET avatar = $1 WHERE id = $2", avatarPtr, requestData.UserID)
-	if err != nil {
-		sendErrorResponse(w, "Erro na query SQL: "+err.Error(), http.StatusInternalServerError)
-		return
+		if data, err = io.ReadAll(file); err != nil {
+			sendErrorResponse(w, "Erro ao ler arquivo", http.StatusBadRequest)
+			return
+		}
+	} else {
+		var requestData struct {
+			UserID int    `json:"user_id"`
+			Avatar string `json:"avatar"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+			sendErrorResponse(w, "Dados inválidos fornecidos", http.StatusBadRequest)
+			return
+		}
+		userID = requestData.UserID
+
+		var err error
+		data, avatarURL, err = decodeAvatarValue(requestData.Avatar)
+		if errors.Is(err, errStorageAvatarURL) {
+			sendErrorResponse(w, "Avatar inválido: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			sendErrorResponse(w, "Avatar inválido: envie uma imagem em base64, uma data URL ou uma URL http(s)", http.StatusBadRequest)
+			return
+		}
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		sendErrorResponse(w, "Erro ao verificar linhas afetadas: "+err.Error(), http.StatusInternalServerError)
+	user, ok := loadAvatarOwner(w, authUser, userID)
+	if !ok {
 		return
 	}
+	oldFiles := avatarFiles(&user)
 
-	if rowsAffected == 0 {
-		sendErrorResponse(w, "Usuário não encontrado ou não foi possível atualizar", http.StatusNotFound)
-		return
+	if len(data) > 0 {
+		err := saveUserAvatar(&user, data)
+		if errors.Is(err, services.ErrInvalidAvatar) {
+			sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao salvar avatar do usuário %d: %v", user.ID, err)
+			sendErrorResponse(w, "Erro ao salvar avatar", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		// URLs externas são guardadas como vieram; avatar vazio remove o atual
+		var avatarPtr *string
+		if avatarURL != "" {
+			avatarPtr = &avatarURL
+		}
+		if _, err := database.DB.Exec("UPDATE users SET avatar = $1, avatar_variants = NULL WHERE id = $2",
+			avatarPtr, user.ID); err != nil {
+			sendErrorResponse(w, "Erro ao atualizar avatar", http.StatusInternalServerError)
+			return
+		}
 	}
 
-	var user models.User
-	err = database.DB.QueryRow(`
-		SELECT id, nome, email, cpf,
-			   TO_CHAR(data_nascimento, 'YYYY-MM-DD') as data_nascimento,
-			   perfil, COALESCE(avatar, '') as avatar, created_at, updated_at 
-		FROM users WHERE id = $1`, requestData.UserID).
-		Scan(&user.ID, &user.Nome, &user.Email, &user.CPF,
-			&user.DataNascimento, &user.Perfil, &user.Avatar, &user.CreatedAt, &user.UpdatedAt)
+	repository.DeleteAvatarFiles(user.ID, oldFiles)
+
+	user, err := loadUserByID(user.ID)
 	if err != nil {
 		sendErrorResponse(w, "Usuário não encontrado", http.StatusNotFound)
 		return
 	}
 
 	sendSuccessResponse(w, map[string]interface{}{
-		"user":    user.ToResponse(),
+		"user":    userProjection(r, &user),
 		"message": "Avatar atualizado com sucesso",
 	})
 }
 
+// DeleteAvatar remove o avatar do usuário e apaga os arquivos do storage
 func DeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	authUser := GetAuthUser(r)
+	if authUser == nil {
+		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
+		return
+	}
+
+	// O corpo é opcional: sem user_id remove o avatar do próprio usuário
 	var requestData struct {
 		UserID int `json:"user_id"`
 	}
-
-	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil && err != io.EOF {
 		sendErrorResponse(w, "Dados inválidos fornecidos", http.StatusBadRequest)
 		return
 	}
 
-	if requestData.UserID <= 0 {
-		sendErrorResponse(w, "ID do usuário é obrigatório", http.StatusBadRequest)
+	user, ok := loadAvatarOwner(w, authUser, requestData.UserID)
+	if !ok {
 		return
 	}
+	oldFiles := avatarFiles(&user)
 
-	var userExists bool
-	err := database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", requestData.UserID).Scan(&userExists)
-	if err != nil {
-		sendErrorResponse(w, "Erro ao verificar usuário: "+err.Error(), http.StatusInternalServerError)
+	if _, err := database.DB.Exec("UPDATE users SET avatar = NULL, avatar_variants = NULL WHERE id = $1", user.ID); err != nil {
+		sendErrorResponse(w, "Erro ao remover avatar", http.StatusInternalServerError)
 		return
 	}
 
-	if !userExists {
+	repository.DeleteAvatarFiles(user.ID, oldFiles)
+
+	sendSuccessResponse(w, map[string]string{
+		"message": "Avatar removido com sucesso",
+	})
+}
+
+// loadAvatarOwner carrega o usuário cujo avatar será alterado, verificando se quem faz a
+// requisição pode alterá-lo. Em caso de erro a resposta já foi enviada.
+func loadAvatarOwner(w http.ResponseWriter, authUser *AuthUser, userID int) (models.User, bool) {
+	if userID <= 0 {
+		userID = authUser.ID
+	}
+	if userID != authUser.ID && !authUser.HasPermission(models.PERMISSION_USERS_EDIT_ANY) {
+		sendErrorResponse(w, "Você não tem permissão para alterar o avatar de outro usuário", http.StatusForbidden)
+		return models.User{}, false
+	}
+
+	user, err := loadUserByID(userID)
+	if err != nil {
 		sendErrorResponse(w, "Usuário não encontrado", http.StatusNotFound)
-		return
+		return models.User{}, false
+	}
+	return user, true
+}
+
+// errStorageAvatarURL é retornado quando o avatar enviado aponta para um arquivo do nosso storage
+var errStorageAvatarURL = errors.New("a URL do avatar não pode apontar para arquivos do SmartPicks. Envie a imagem")
+
+// decodeAvatarValue interpreta o campo avatar enviado em JSON: URLs http(s) externas são
+// retornadas em url; base64 puro ou data URL são decodificados em data. URLs do nosso storage
+// são recusadas: o avatar antigo é apagado na troca, e um arquivo de outro usuário seria apagado junto.
+func decodeAvatarValue(value string) (data []byte, url string, err error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, "", nil
+	}
+	if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+		if fileStorage != nil {
+			if _, ok := fileStorage.KeyFromURL(value); ok {
+				return nil, "", errStorageAvatarURL
+			}
+		}
+		return nil, value, nil
 	}
 
-	result, err := database.DB.Exec("UPDATE users SET avatar = NULL WHERE id = $1", requestData.UserID)
+	data, err = base64.StdEncoding.DecodeString(extractBase64Data(value))
 	if err != nil {
-		sendErrorResponse(w, "Erro na query SQL: "+err.Error(), http.StatusInternalServerError)
-		return
+		return nil, "", err
 	}
+	return data, "", nil
+}
 
-	rowsAffected, err := result.RowsAffected()
+// saveUserAvatar gera as versões do avatar no storage e grava as URLs no usuário
+func saveUserAvatar(user *models.User, data []byte) error {
+	variants, err := services.UploadAvatar(fileStorage, user.ID, data)
 	if err != nil {
-		sendErrorResponse(w, "Erro ao verificar linhas afetadas: "+err.Error(), http.StatusInternalServerError)
-		return
+		return err
 	}
 
-	if rowsAffected == 0 {
-		sendErrorResponse(w, "Não foi possível remover o avatar", http.StatusInternalServerError)
-		return
+	avatar := variants[strconv.Itoa(services.AvatarDefaultSize)]
+	if _, err := database.DB.Exec("UPDATE users SET avatar = $1, avatar_variants = $2 WHERE id = $3",
+		avatar, models.ImageVariants(variants), user.ID); err != nil {
+		repository.DeleteAvatarFiles(user.ID, models.ImageVariants(variants).URLs())
+		return err
 	}
 
-	sendSuccessResponse(w, map[string]string{
-		"message": "Avatar removido com sucesso",
-	})
+	user.Avatar = &avatar
+	user.AvatarVariants = variants
+	return nil
+}
+
+// avatarFiles lista os arquivos do avatar atual do usuário, para remoção após a troca
+func avatarFiles(user *models.User) []string {
+	if len(user.AvatarVariants) > 0 {
+		return user.AvatarVariants.URLs()
+	}
+	// Avatares antigos em base64 não têm arquivo a remover
+	if user.Avatar != nil && strings.HasPrefix(*user.Avatar, "http") {
+		return []string{*user.Avatar}
+	}
+	return nil
 }
@@ -6,7 +6,6 @@ import (
 	"net/http"
 	"smartpicks-backend/internal/database"
 	"smartpicks-backend/internal/models"
-	"strconv"
 
 	"github.com/gorilla/mux"
 )
@@ -84,11 +83,16 @@ func GetComentariosByPalpite(w http.ResponseWriter, r *http.Request) {
 
 // CreateComentario cria um novo comentário em um palpite
 func CreateComentario(w http.ResponseWriter, r *http.Request) {
-	userID := GetUserIDFromRequest(r)
-	if userID == 0 {
+	authUser := GetAuthUser(r)
+	if authUser == nil {
 		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
 		return
 	}
+	if !authUser.HasPermission(models.PERMISSION_COMENTARIOS_CREATE) {
+		sendErrorResponse(w, "Você não tem permissão para comentar", http.StatusForbidden)
+		return
+	}
+	userID := authUser.ID
 
 	var req models.ComentarioRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -208,15 +212,11 @@ func DeleteComentario(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	comentarioID := vars["id"]
 
-	// Verificar se o comentário pertence ao usuário ou se é admin
+	// Verificar se o comentário pertence ao usuário ou se ele pode moderar comentários
 	var comentarioUserID int
-	var userPerfil string
 	err := database.DB.QueryRow(`
-		SELECT c.user_id, u.perfil
-		FROM comentarios c
-		JOIN users u ON u.id = $1
-		WHERE c.id = $2
-	`, userID, comentarioID).Scan(&comentarioUserID, &userPerfil)
+		SELECT user_id FROM comentarios WHERE id = $1
+	`, comentarioID).Scan(&comentarioUserID)
 
 	if err == sql.ErrNoRows {
 		sendErrorResponse(w, "Comentário não encontrado", http.StatusNotFound)
@@ -227,7 +227,7 @@ func DeleteComentario(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if comentarioUserID != userID && userPerfil != "admin" {
+	if comentarioUserID != userID && !GetAuthUser(r).HasPermission(models.PERMISSION_COMENTARIOS_DELETE) {
 		sendErrorResponse(w, "Você não tem permissão para deletar este comentário", http.StatusForbidden)
 		return
 	}
@@ -241,18 +241,3 @@ func DeleteComentario(w http.ResponseWriter, r *http.Request) {
 
 	sendJSONResponse(w, map[string]string{"message": "Comentário deletado com sucesso"}, http.StatusOK)
 }
-
-// GetUserIDFromRequest retorna o ID do usuário da requisição (pelo token JWT)
-func GetUserIDFromRequest(r *http.Request) int {
-	// Esta função deve extrair o userID do token JWT
-	// Por enquanto, retorna 0 (implementar autenticação JWT posteriormente)
-	userIDStr := r.Header.Get("X-User-ID") // Temporário
-	if userIDStr == "" {
-		return 0
-	}
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil {
-		return 0
-	}
-	return userID
-}
@@ -2,10 +2,18 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
+	"smartpicks-backend/internal/config"
 	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/models"
 )
 
 // sendErrorResponse envia uma resposta de erro padronizada
@@ -45,3 +53,115 @@ func userExists(field, value string) bool {
 	}
 	return count > 0
 }
+
+// clientIP retorna o IP do cliente. O X-Forwarded-For só é considerado quando a conexão vem de um
+// proxy confiável (TRUSTED_PROXIES); sem isso qualquer cliente poderia trocar de IP a cada
+// tentativa de login. O resultado é sempre um IP válido (cabe em sessions.ip_address).
+func clientIP(r *http.Request) string {
+	remote := remoteIP(r)
+	proxies := trustedProxies()
+	if !proxies.contains(remote) {
+		return remote.String()
+	}
+
+	// Percorre a lista da direita para a esquerda: cada proxy acrescenta o IP de quem o chamou, então
+	// o primeiro endereço que não é de um proxy confiável é o do cliente
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+		if proxies.all || !proxies.contains(ip) {
+			return ip.String()
+		}
+		remote = ip
+	}
+	return remote.String()
+}
+
+// remoteIP é o IP da conexão (RemoteAddr)
+func remoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip
+	}
+	return net.IPv4zero
+}
+
+// proxyList são os proxies cujo X-Forwarded-For é aceito. all confia em qualquer origem e usa o
+// último IP da lista, o acrescentado pelo proxy da frente (ex: Vercel, que reescreve o header).
+type proxyList struct {
+	all  bool
+	nets []*net.IPNet
+}
+
+func (p proxyList) contains(ip net.IP) bool {
+	if p.all {
+		return true
+	}
+	for _, n := range p.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	trustedProxiesOnce sync.Once
+	trustedProxiesList proxyList
+)
+
+// trustedProxies lê TRUSTED_PROXIES: IPs ou CIDRs separados por vírgula, ou "*". Vazio ignora o
+// X-Forwarded-For. Entradas inválidas são ignoradas com um aviso no log.
+func trustedProxies() proxyList {
+	trustedProxiesOnce.Do(func() {
+		for _, entry := range strings.Split(config.String("TRUSTED_PROXIES", ""), ",") {
+			entry = strings.TrimSpace(entry)
+			switch {
+			case entry == "":
+				continue
+			case entry == "*":
+				trustedProxiesList.all = true
+				continue
+			case !strings.Contains(entry, "/"):
+				if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+					entry += "/32"
+				} else {
+					entry += "/128"
+				}
+			}
+			_, n, err := net.ParseCIDR(entry)
+			if err != nil {
+				log.Printf("⚠️  TRUSTED_PROXIES: entrada inválida %q ignorada", entry)
+				continue
+			}
+			trustedProxiesList.nets = append(trustedProxiesList.nets, n)
+		}
+	})
+	return trustedProxiesList
+}
+
+// invalidPerfilMessage lista os perfis aceitos na mensagem de erro
+func invalidPerfilMessage() string {
+	return "Perfil inválido. Use um destes: " + strings.Join(models.ValidPerfis, ", ")
+}
+
+// parseDataNascimento aceita a data nos formatos YYYY-MM-DD ou DD/MM/YYYY e a retorna como YYYY-MM-DD
+func parseDataNascimento(value string) (string, error) {
+	parsedDate, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		parsedDate, err = time.Parse("02/01/2006", value)
+		if err != nil {
+			return "", errors.New("Formato de data inválido. Use YYYY-MM-DD ou DD/MM/YYYY")
+		}
+	}
+	return parsedDate.Format("2006-01-02"), nil
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// GetCompetitions lista as competições
+func GetCompetitions(w http.ResponseWriter, r *http.Request) {
+	competitions, err := repository.ListCompetitions()
+	if err != nil {
+		sendErrorResponse(w, "Erro ao buscar competições", http.StatusInternalServerError)
+		return
+	}
+	sendSuccessResponse(w, map[string]interface{}{"competitions": competitions})
+}
+
+// SaveCompetition cria (POST) ou altera (PUT /{id}) uma competição. A rota exige matches:manage.
+func SaveCompetition(w http.ResponseWriter, r *http.Request) {
+	var competition models.Competition
+	if err := json.NewDecoder(r.Body).Decode(&competition); err != nil {
+		sendErrorResponse(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	competition.ID = 0
+	if id, ok := mux.Vars(r)["id"]; ok {
+		var err error
+		if competition.ID, err = strconv.Atoi(id); err != nil {
+			sendErrorResponse(w, "ID da competição inválido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	competition.Name = strings.TrimSpace(competition.Name)
+	if competition.Name == "" {
+		sendErrorResponse(w, "name é obrigatório", http.StatusBadRequest)
+		return
+	}
+	if competition.Code != nil {
+		if code := strings.TrimSpace(*competition.Code); code != "" {
+			competition.Code = &code
+		} else {
+			competition.Code = nil
+		}
+	}
+
+	status := http.StatusOK
+	if competition.ID == 0 {
+		status = http.StatusCreated
+	}
+	err := repository.SaveCompetition(&competition)
+	switch {
+	case errors.Is(err, repository.ErrCompetitionNotFound):
+		sendErrorResponse(w, "Competição não encontrada", http.StatusNotFound)
+	case repository.IsUniqueViolation(err):
+		sendErrorResponse(w, "Já existe uma competição com esse código", http.StatusConflict)
+	case err != nil:
+		sendErrorResponse(w, "Erro ao gravar competição", http.StatusInternalServerError)
+	default:
+		sendJSONResponse(w, map[string]interface{}{"competition": competition}, status)
+	}
+}
+
+// DeleteCompetition remove uma competição. A rota exige matches:manage.
+func DeleteCompetition(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendErrorResponse(w, "ID da competição inválido", http.StatusBadRequest)
+		return
+	}
+
+	err = repository.DeleteCompetition(id)
+	if errors.Is(err, repository.ErrCompetitionNotFound) {
+		sendErrorResponse(w, "Competição não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sendErrorResponse(w, "Erro ao remover competição", http.StatusInternalServerError)
+		return
+	}
+	sendSuccessResponse(w, map[string]string{"message": "Competição removida com sucesso"})
+}
+
+// GetTeams lista os times. Aceita ?search= para filtrar pelo nome.
+func GetTeams(w http.ResponseWriter, r *http.Request) {
+	teams, err := repository.ListTeams(strings.TrimSpace(r.URL.Query().Get("search")))
+	if err != nil {
+		sendErrorResponse(w, "Erro ao buscar times", http.StatusInternalServerError)
+		return
+	}
+	sendSuccessResponse(w, map[string]interface{}{"teams": teams})
+}
+
+// SaveTeam cria (POST) ou altera (PUT /{id}) um time. A rota exige matches:manage.
+func SaveTeam(w http.ResponseWriter, r *http.Request) {
+	var team models.Team
+	if err := json.NewDecoder(r.Body).Decode(&team); err != nil {
+		sendErrorResponse(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	team.ID = 0
+	if id, ok := mux.Vars(r)["id"]; ok {
+		var err error
+		if team.ID, err = strconv.Atoi(id); err != nil {
+			sendErrorResponse(w, "ID do time inválido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	team.Name = strings.TrimSpace(team.Name)
+	if team.Name == "" {
+		sendErrorResponse(w, "name é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	status := http.StatusOK
+	if team.ID == 0 {
+		status = http.StatusCreated
+	}
+	err := repository.SaveTeam(&team)
+	switch {
+	case errors.Is(err, repository.ErrTeamNotFound):
+		sendErrorResponse(w, "Time não encontrado", http.StatusNotFound)
+	case repository.IsUniqueViolation(err):
+		sendErrorResponse(w, "Já existe um time com esse nome", http.StatusConflict)
+	case err != nil:
+		sendErrorResponse(w, "Erro ao gravar time", http.StatusInternalServerError)
+	default:
+		sendJSONResponse(w, map[string]interface{}{"team": team}, status)
+	}
+}
+
+// DeleteTeam remove um time sem partidas. A rota exige matches:manage.
+func DeleteTeam(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendErrorResponse(w, "ID do time inválido", http.StatusBadRequest)
+		return
+	}
+
+	err = repository.DeleteTeam(id)
+	switch {
+	case errors.Is(err, repository.ErrTeamNotFound):
+		sendErrorResponse(w, "Time não encontrado", http.StatusNotFound)
+	case errors.Is(err, repository.ErrInUse):
+		sendErrorResponse(w, "O time tem partidas e não pode ser removido", http.StatusConflict)
+	case err != nil:
+		sendErrorResponse(w, "Erro ao remover time", http.StatusInternalServerError)
+	default:
+		sendSuccessResponse(w, map[string]string{"message": "Time removido com sucesso"})
+	}
+}
+
+// AddTeamAlias cadastra um nome alternativo do time, usado na importação de partidas.
+// A rota exige matches:manage.
+func AddTeamAlias(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendErrorResponse(w, "ID do time inválido", http.StatusBadRequest)
+		return
+	}
+	var body struct {
+		Alias string `json:"alias"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sendErrorResponse(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(body.Alias) == "" {
+		sendErrorResponse(w, "alias é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	err = repository.AddTeamAlias(id, body.Alias)
+	switch {
+	case errors.Is(err, repository.ErrTeamNotFound):
+		sendErrorResponse(w, "Time não encontrado", http.StatusNotFound)
+	case errors.Is(err, repository.ErrAliasInUse):
+		sendErrorResponse(w, "Esse alias já está cadastrado", http.StatusConflict)
+	case err != nil:
+		sendErrorResponse(w, "Erro ao gravar alias", http.StatusInternalServerError)
+	default:
+		sendJSONResponse(w, map[string]string{"message": "Alias cadastrado com sucesso"}, http.StatusCreated)
+	}
+}
+
+// DeleteTeamAlias remove um alias do time. A rota exige matches:manage.
+func DeleteTeamAlias(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		sendErrorResponse(w, "ID do time inválido", http.StatusBadRequest)
+		return
+	}
+
+	err = repository.DeleteTeamAlias(id, vars["alias"])
+	switch {
+	case err == sql.ErrNoRows:
+		sendErrorResponse(w, "Alias não encontrado", http.StatusNotFound)
+	case err != nil:
+		sendErrorResponse(w, "Erro ao remover alias", http.StatusInternalServerError)
+	default:
+		sendSuccessResponse(w, map[string]string{"message": "Alias removido com sucesso"})
+	}
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"smartpicks-backend/internal/config"
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/imaging"
+	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/repository"
+	"smartpicks-backend/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// maxImageUploadSize é o tamanho máximo das imagens dos palpites
+const maxImageUploadSize = 5 << 20
+
+// imageExtensions define a extensão do arquivo gravado para cada tipo de imagem aceito
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+var errUploadNotAvailable = errors.New("upload não encontrado, ainda não concluído ou já utilizado")
+
+// uploadPresignTTL é a validade da URL pré-assinada (UPLOAD_PRESIGN_TTL)
+func uploadPresignTTL() time.Duration {
+	return config.Duration("UPLOAD_PRESIGN_TTL", 15*time.Minute)
+}
+
+// PresignUpload gera uma URL pré-assinada para o cliente enviar a imagem de um palpite direto
+// ao storage, sem passar pela API. Depois do envio o cliente chama /uploads/{key}/complete.
+func PresignUpload(w http.ResponseWriter, r *http.Request) {
+	authUser := GetAuthUser(r)
+	if authUser == nil {
+		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
+		return
+	}
+	if !authUser.HasPermission(models.PERMISSION_PALPITES_CREATE) {
+		sendErrorResponse(w, "Você não tem permissão para criar palpites", http.StatusForbidden)
+		return
+	}
+
+	uploader, ok := fileStorage.(services.DirectUploader)
+	if !ok {
+		sendErrorResponse(w, "O armazenamento configurado não aceita upload direto. Use /api/upload", http.StatusNotImplemented)
+		return
+	}
+
+	var req struct {
+		ContentType string `json:"content_type"`
+		Size        int64  `json:"size"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	ext, ok := imageExtensions[req.ContentType]
+	if !ok {
+		sendErrorResponse(w, "Tipo de arquivo não suportado. Use image/jpeg, image/png, image/gif ou image/webp", http.StatusBadRequest)
+		return
+	}
+	if req.Size <= 0 || req.Size > maxImageUploadSize {
+		sendErrorResponse(w, "size deve estar entre 1 byte e 5MB", http.StatusBadRequest)
+		return
+	}
+
+	random, err := services.GenerateOpaqueToken()
+	if err != nil {
+		sendErrorResponse(w, "Erro ao preparar upload", http.StatusInternalServerError)
+		return
+	}
+	key := fmt.Sprintf("palpites/%d/%d_%s%s", authUser.ID, time.Now().UnixNano(), random[:16], ext)
+
+	ttl := uploadPresignTTL()
+	uploadURL, err := uploader.PresignPut(key, req.ContentType, req.Size, ttl)
+	if err != nil {
+		log.Printf("Erro ao gerar URL de upload: %v", err)
+		sendErrorResponse(w, "Erro ao preparar upload", http.StatusInternalServerError)
+		return
+	}
+
+	upload := models.Upload{
+		UserID:      authUser.ID,
+		Key:         key,
+		ContentType: req.ContentType,
+		SizeBytes:   req.Size,
+		Status:      models.UPLOAD_STATUS_PENDING,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+	err = database.DB.QueryRow(`
+		INSERT INTO uploads (user_id, storage_key, content_type, size_bytes, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		upload.UserID, upload.Key, upload.ContentType, upload.SizeBytes, upload.Status, upload.ExpiresAt,
+	).Scan(&upload.ID, &upload.CreatedAt)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao registrar upload", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSONResponse(w, map[string]interface{}{
+		"upload":     upload,
+		"upload_url": uploadURL,
+		"method":     http.MethodPut,
+		// O cliente deve enviar exatamente estes headers, pois eles fazem parte da assinatura
+		"headers": map[string]string{
+			"Content-Type": req.ContentType,
+		},
+	}, http.StatusCreated)
+}
+
+// CompleteUpload confere o arquivo enviado pela URL pré-assinada (tamanho, tipo e os bytes
+// iniciais da imagem) e o libera para ser usado em um palpite. Arquivos inválidos são apagados.
+func CompleteUpload(w http.ResponseWriter, r *http.Request) {
+	authUser := GetAuthUser(r)
+	if authUser == nil {
+		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
+		return
+	}
+
+	uploader, ok := fileStorage.(services.DirectUploader)
+	if !ok {
+		sendErrorResponse(w, "O armazenamento configurado não aceita upload direto. Use /api/upload", http.StatusNotImplemented)
+		return
+	}
+
+	upload, err := loadUpload(mux.Vars(r)["key"], authUser.ID)
+	if err == sql.ErrNoRows {
+		sendErrorResponse(w, "Upload não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sendErrorResponse(w, "Erro ao buscar upload", http.StatusInternalServerError)
+		return
+	}
+
+	// Repetir a chamada não faz mal: o upload já conferido é retornado como está
+	if upload.Status == models.UPLOAD_STATUS_COMPLETED {
+		sendSuccessResponse(w, map[string]interface{}{"upload": upload})
+		return
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		sendErrorResponse(w, "Prazo do upload expirado. Gere uma nova URL", http.StatusGone)
+		return
+	}
+
+	info, err := uploader.Stat(upload.Key)
+	if errors.Is(err, services.ErrFileNotFound) {
+		sendErrorResponse(w, "O arquivo ainda não foi enviado", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao consultar upload %s: %v", upload.Key, err)
+		sendErrorResponse(w, "Erro ao conferir upload", http.StatusInternalServerError)
+		return
+	}
+
+	if problem := checkUploadedFile(uploader, upload, info); problem != "" {
+		if err := fileStorage.Delete(upload.Key); err != nil {
+			log.Printf("Erro ao remover upload inválido %s: %v", upload.Key, err)
+		}
+		if _, err := database.DB.Exec("DELETE FROM uploads WHERE id = $1", upload.ID); err != nil {
+			log.Printf("Erro ao remover registro do upload %d: %v", upload.ID, err)
+		}
+		sendErrorResponse(w, problem, http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = database.DB.QueryRow(`
+		UPDATE uploads SET status = $1, completed_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+		RETURNING completed_at`, models.UPLOAD_STATUS_COMPLETED, upload.ID).Scan(&upload.CompletedAt)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao concluir upload", http.StatusInternalServerError)
+		return
+	}
+	upload.Status = models.UPLOAD_STATUS_COMPLETED
+
+	sendSuccessResponse(w, map[string]interface{}{
+		"upload":  upload,
+		"message": "Upload concluído. Use a key ao criar o palpite",
+	})
+}
+
+// PurgeUploads apaga os uploads diretos abandonados e os arquivos originais no storage. A rota
+// exige a permissão users:delete e pode ser chamada por um cron (ou use o comando purge-uploads).
+func PurgeUploads(w http.ResponseWriter, r *http.Request) {
+	purged, err := repository.PurgeStaleUploads()
+	if err != nil {
+		log.Printf("Erro ao remover uploads: %v", err)
+		sendErrorResponse(w, "Erro ao remover uploads", http.StatusInternalServerError)
+		return
+	}
+
+	sendSuccessResponse(w, map[string]interface{}{
+		"purged":  purged,
+		"message": fmt.Sprintf("%d uploads removidos", purged),
+	})
+}
+
+// checkUploadedFile confere se o arquivo enviado corresponde ao que foi assinado. Retorna a
+// mensagem do problema encontrado ou "" quando o arquivo é válido.
+func checkUploadedFile(uploader services.DirectUploader, upload models.Upload, info services.FileInfo) string {
+	if info.Size != upload.SizeBytes || info.Size > maxImageUploadSize {
+		return "Tamanho do arquivo diferente do informado"
+	}
+	if info.ContentType != upload.ContentType {
+		return "Tipo do arquivo diferente do informado"
+	}
+
+	// O Content-Type é declarado pelo cliente; o tipo real vem dos bytes iniciais do arquivo
+	head, err := uploader.ReadPrefix(upload.Key, 512)
+	if err != nil {
+		log.Printf("Erro ao ler início do upload %s: %v", upload.Key, err)
+		return "Não foi possível ler o arquivo enviado"
+	}
+	detected := http.DetectContentType(head)
+	if !imaging.AllowedContentTypes[detected] || detected != upload.ContentType {
+		return "O conteúdo do arquivo não é uma imagem do tipo informado"
+	}
+	return ""
+}
+
+// loadUpload busca um upload do usuário pela chave do arquivo
+func loadUpload(key string, userID int) (models.Upload, error) {
+	var upload models.Upload
+	err := database.DB.QueryRow(`
+		SELECT id, user_id, storage_key, content_type, size_bytes, status, palpite_id,
+			expires_at, completed_at, created_at
+		FROM uploads WHERE storage_key = $1 AND user_id = $2`, key, userID).Scan(
+		&upload.ID, &upload.UserID, &upload.Key, &upload.ContentType, &upload.SizeBytes, &upload.Status,
+		&upload.PalpiteID, &upload.ExpiresAt, &upload.CompletedAt, &upload.CreatedAt)
+	return upload, err
+}
+
+// claimUpload vincula um upload concluído e ainda não utilizado ao palpite. Retorna
+// errUploadNotAvailable se o upload não existir, não for do usuário ou já estiver em uso.
+func claimUpload(tx *sql.Tx, key string, userID, palpiteID int) error {
+	result, err := tx.Exec(`
+		UPDATE uploads SET palpite_id = $1
+		WHERE storage_key = $2 AND user_id = $3 AND status = $4 AND palpite_id IS NULL`,
+		palpiteID, key, userID, models.UPLOAD_STATUS_COMPLETED)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return errUploadNotAvailable
+	}
+	return nil
+}
@@ -0,0 +1,509 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"smartpicks-backend/internal/config"
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/repository"
+	"smartpicks-backend/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// exportSyncMaxItems é o número máximo de registros (palpites, comentários e reações)
+// para o export ser montado na própria requisição; acima disso ele é processado em segundo plano
+func exportSyncMaxItems() int {
+	return config.Int("EXPORT_SYNC_MAX_ITEMS", 50)
+}
+
+// exportDownloadTTL é o tempo em que o arquivo de um export assíncrono fica disponível
+func exportDownloadTTL() time.Duration {
+	return config.Duration("EXPORT_DOWNLOAD_TTL", 24*time.Hour)
+}
+
+// ExportMyData gera o arquivo ZIP com os dados do usuário autenticado (LGPD).
+// Exports pequenos são enviados na resposta; os grandes entram na fila processada por
+// ProcessDataExports e a resposta 202 indica onde acompanhar o status.
+func ExportMyData(w http.ResponseWriter, r *http.Request) {
+	authUser := GetAuthUser(r)
+	if authUser == nil {
+		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
+		return
+	}
+
+	var items int
+	err := database.DB.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM palpites WHERE user_id = $1)
+		     + (SELECT COUNT(*) FROM comentarios WHERE user_id = $1)
+		     + (SELECT COUNT(*) FROM palpites_reactions WHERE user_id = $1)
+		     + (SELECT COUNT(*) FROM comentarios_reactions WHERE user_id = $1)`, authUser.ID).Scan(&items)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao preparar exportação", http.StatusInternalServerError)
+		return
+	}
+
+	if items <= exportSyncMaxItems() && r.URL.Query().Get("async") != "true" {
+		archive, err := buildUserExport(authUser.ID)
+		if err != nil {
+			log.Printf("Erro ao gerar exportação do usuário %d: %v", authUser.ID, err)
+			sendErrorResponse(w, "Erro ao gerar exportação", http.StatusInternalServerError)
+			return
+		}
+		sendExportArchive(w, authUser.ID, archive)
+		return
+	}
+
+	// Limpa arquivos expirados antes de criar um novo pedido
+	if err := repository.PurgeExpiredDataExports(); err != nil {
+		log.Printf("Erro ao remover exportações expiradas: %v", err)
+	}
+
+	exportID, err := repository.RequestDataExport(authUser.ID)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao criar exportação", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSONResponse(w, map[string]interface{}{
+		"export_id":  exportID,
+		"status":     models.EXPORT_STATUS_PENDING,
+		"status_url": fmt.Sprintf("%s/api/users/me/exports/%d", apiURL(), exportID),
+		"message":    "A exportação está sendo gerada. Consulte o status para obter o link de download",
+	}, http.StatusAccepted)
+}
+
+// GetMyDataExport retorna o status de um export e, quando pronto, o link de download
+func GetMyDataExport(w http.ResponseWriter, r *http.Request) {
+	authUser := GetAuthUser(r)
+	if authUser == nil {
+		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
+		return
+	}
+
+	exportID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendErrorResponse(w, "ID da exportação inválido", http.StatusBadRequest)
+		return
+	}
+
+	var export models.DataExport
+	err = database.DB.QueryRow(`
+		SELECT id, user_id, status, error, size_bytes, created_at, completed_at, expires_at
+		FROM data_exports WHERE id = $1 AND user_id = $2`, exportID, authUser.ID).
+		Scan(&export.ID, &export.UserID, &export.Status, &export.Error, &export.SizeBytes,
+			&export.CreatedAt, &export.CompletedAt, &export.ExpiresAt)
+	if err == sql.ErrNoRows {
+		sendErrorResponse(w, "Exportação não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sendErrorResponse(w, "Erro ao buscar exportação", http.StatusInternalServerError)
+		return
+	}
+
+	if export.Status == models.EXPORT_STATUS_READY && export.ExpiresAt != nil && export.ExpiresAt.After(time.Now()) {
+		token, err := services.GenerateDownloadToken(export.ID, services.AudienceDataExport, *export.ExpiresAt)
+		if err != nil {
+			sendErrorResponse(w, "Erro ao gerar link de download", http.StatusInternalServerError)
+			return
+		}
+		export.DownloadURL = fmt.Sprintf("%s/api/exports/download?token=%s", apiURL(), token)
+	}
+
+	sendSuccessResponse(w, export)
+}
+
+// DownloadDataExport envia o arquivo de um export pronto. O link é assinado e expira junto
+// com o arquivo, então pode ser aberto diretamente no navegador sem o header Authorization.
+func DownloadDataExport(w http.ResponseWriter, r *http.Request) {
+	exportID, err := services.ParseDownloadToken(r.URL.Query().Get("token"), services.AudienceDataExport)
+	if err != nil {
+		sendErrorResponse(w, "Link de download inválido ou expirado", http.StatusGone)
+		return
+	}
+
+	var userID int
+	var storageKey string
+	err = database.DB.QueryRow(`
+		SELECT user_id, storage_key FROM data_exports
+		WHERE id = $1 AND status = $2 AND expires_at > CURRENT_TIMESTAMP`,
+		exportID, models.EXPORT_STATUS_READY).Scan(&userID, &storageKey)
+	if err == sql.ErrNoRows {
+		sendErrorResponse(w, "Exportação não encontrada ou expirada", http.StatusGone)
+		return
+	}
+	if err != nil {
+		sendErrorResponse(w, "Erro ao buscar exportação", http.StatusInternalServerError)
+		return
+	}
+
+	archive, _, err := fileStorage.Get(storageKey)
+	if err != nil {
+		log.Printf("Erro ao baixar o arquivo da exportação %d: %v", exportID, err)
+		sendErrorResponse(w, "Arquivo da exportação não encontrado", http.StatusGone)
+		return
+	}
+
+	sendExportArchive(w, userID, archive)
+}
+
+func sendExportArchive(w http.ResponseWriter, userID int, archive []byte) {
+	fileName := fmt.Sprintf("smartpicks-dados-%d-%s.zip", userID, time.Now().Format("20060102"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	w.Header().Set("Content-Length", strconv.Itoa(len(archive)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+}
+
+// ProcessDataExports processa até limit exports da fila e retorna quantos ficaram prontos.
+// É chamado pelo comando process-exports ou pela rota /admin/exports/process, agendados no cron.
+func ProcessDataExports(limit int) (int, error) {
+	if err := repository.PurgeExpiredDataExports(); err != nil {
+		log.Printf("Erro ao remover exportações expiradas: %v", err)
+	}
+
+	ready := 0
+	for i := 0; i < limit; i++ {
+		job, err := repository.ClaimDataExport()
+		if err != nil {
+			return ready, err
+		}
+		if job == nil {
+			break
+		}
+		if processDataExport(job) {
+			ready++
+		}
+	}
+	return ready, nil
+}
+
+// processDataExport monta o arquivo de um export da fila e o grava no storage. Em caso de erro
+// o export volta para a fila até esgotar as tentativas.
+func processDataExport(job *repository.DataExportJob) bool {
+	fail := func(message string) bool {
+		if err := repository.FailDataExport(job, message); err != nil {
+			log.Printf("Erro ao atualizar exportação %d: %v", job.ID, err)
+		}
+		return false
+	}
+
+	archive, err := buildUserExport(job.UserID)
+	if err != nil {
+		log.Printf("Erro ao gerar exportação %d: %v", job.ID, err)
+		return fail("Erro ao gerar exportação")
+	}
+
+	// O nome aleatório impede que o arquivo seja encontrado pela URL pública do storage;
+	// o download passa sempre pelo link assinado
+	token, err := services.GenerateOpaqueToken()
+	if err != nil {
+		return fail("Erro ao gerar exportação")
+	}
+	key := fmt.Sprintf("exports/%d/%d_%s.zip", job.UserID, job.ID, token)
+	if err := fileStorage.Put(key, archive, "application/zip"); err != nil {
+		log.Printf("Erro ao gravar o arquivo da exportação %d: %v", job.ID, err)
+		return fail("Erro ao gravar exportação")
+	}
+
+	ok, err := repository.CompleteDataExport(job, key, len(archive), time.Now().Add(exportDownloadTTL()))
+	if err != nil || !ok {
+		if err != nil {
+			log.Printf("Erro ao salvar exportação %d: %v", job.ID, err)
+		}
+		repository.DeleteStoredFiles(job.UserID, []string{key})
+		return false
+	}
+	return true
+}
+
+// RunDataExports processa a fila de exports (até ?limit=, padrão 10). A rota exige a
+// permissão users:read_private e pode ser chamada por um cron (ou use o comando process-exports).
+func RunDataExports(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			sendErrorResponse(w, "limit deve estar entre 1 e 100", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	ready, err := ProcessDataExports(limit)
+	if err != nil {
+		log.Printf("Erro ao processar exportações: %v", err)
+		sendErrorResponse(w, "Erro ao processar exportações", http.StatusInternalServerError)
+		return
+	}
+
+	sendSuccessResponse(w, map[string]interface{}{
+		"ready":   ready,
+		"message": fmt.Sprintf("%d exportações prontas", ready),
+	})
+}
+
+// exportFile é uma entrada do manifesto (README) do export
+type exportFile struct {
+	Name        string
+	Description string
+}
+
+// buildUserExport monta o ZIP com o perfil, palpites (e imagens), comentários, reações e sessões
+func buildUserExport(userID int) ([]byte, error) {
+	user, err := loadUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar usuário: %v", err)
+	}
+
+	palpites, err := exportPalpites(userID)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar palpites: %v", err)
+	}
+	comentarios, err := exportComentarios(userID)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar comentários: %v", err)
+	}
+	reacoes, err := exportReacoes(userID)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar reações: %v", err)
+	}
+	sessoes, err := exportSessoes(userID)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar sessões: %v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	var files []exportFile
+
+	jsonFiles := []struct {
+		name        string
+		description string
+		data        interface{}
+	}{
+		{"perfil.json", "Dados cadastrais (nome, email, CPF, data de nascimento, perfil)", userResponse(&user)},
+		{"palpites.json", fmt.Sprintf("%d palpites publicados", len(palpites)), palpites},
+		{"comentarios.json", fmt.Sprintf("%d comentários feitos", len(comentarios)), comentarios},
+		{"reacoes.json", "Reações (like/dislike) dadas em palpites e comentários", reacoes},
+		{"sessoes.json", fmt.Sprintf("%d sessões de login (dispositivo, IP e datas)", len(sessoes)), sessoes},
+	}
+	for _, f := range jsonFiles {
+		data, err := json.MarshalIndent(f.data, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := writeZipFile(zw, f.name, data); err != nil {
+			return nil, err
+		}
+		files = append(files, exportFile{f.name, f.description})
+	}
+
+	imageFiles, missing := writeExportImages(zw, palpites)
+	files = append(files, imageFiles...)
+
+	manifest := exportManifest(&user, files, missing)
+	if err := writeZipFile(zw, "README.txt", []byte(manifest)); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeExportImages copia as imagens dos palpites do storage para a pasta imagens/.
+// Retorna as entradas do manifesto e a lista de palpites cujas imagens não puderam ser incluídas.
+func writeExportImages(zw *zip.Writer, palpites []models.Palpite) ([]exportFile, []string) {
+	var files []exportFile
+	var missing []string
+
+	for _, p := range palpites {
+		if p.ImgURL == "" {
+			continue
+		}
+		key, ok := fileStorage.KeyFromURL(p.ImgURL)
+		if !ok {
+			missing = append(missing, fmt.Sprintf("palpite %d: imagem externa (%s)", p.ID, p.ImgURL))
+			continue
+		}
+		data, _, err := fileStorage.Get(key)
+		if err != nil {
+			log.Printf("Erro ao baixar imagem do palpite %d para exportação: %v", p.ID, err)
+			missing = append(missing, fmt.Sprintf("palpite %d: imagem não encontrada no armazenamento", p.ID))
+			continue
+		}
+		name := fmt.Sprintf("imagens/palpite_%d%s", p.ID, strings.ToLower(path.Ext(key)))
+		if err := writeZipFile(zw, name, data); err != nil {
+			missing = append(missing, fmt.Sprintf("palpite %d: erro ao gravar imagem", p.ID))
+			continue
+		}
+		files = append(files, exportFile{name, fmt.Sprintf("Imagem do palpite %d", p.ID)})
+	}
+	return files, missing
+}
+
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// exportManifest gera o README.txt que descreve o conteúdo do arquivo
+func exportManifest(user *models.User, files []exportFile, missing []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "SmartPicks - Exportação de dados pessoais (LGPD)\n")
+	fmt.Fprintf(&b, "================================================\n\n")
+	fmt.Fprintf(&b, "Usuário: %s (ID %d)\n", user.Nome, user.ID)
+	fmt.Fprintf(&b, "Gerado em: %s\n\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "Os arquivos .json usam codificação UTF-8 e datas no formato RFC 3339.\n\n")
+	fmt.Fprintf(&b, "Arquivos:\n")
+	for _, f := range files {
+		fmt.Fprintf(&b, "  - %s: %s\n", f.Name, f.Description)
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(&b, "\nImagens não incluídas:\n")
+		for _, m := range missing {
+			fmt.Fprintf(&b, "  - %s\n", m)
+		}
+	}
+	return b.String()
+}
+
+func exportPalpites(userID int) ([]models.Palpite, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, user_id, titulo, COALESCE(img_url, ''), link, created_at, updated_at
+		FROM palpites WHERE user_id = $1 ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	palpites := []models.Palpite{}
+	for rows.Next() {
+		var p models.Palpite
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Titulo, &p.ImgURL, &p.Link, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		palpites = append(palpites, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, len(palpites))
+	for i := range palpites {
+		ids[i] = palpites[i].ID
+	}
+	selections, err := repository.LoadSelections(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range palpites {
+		palpites[i].Selections = selections[palpites[i].ID]
+		palpites[i].Late = models.HasLateSelection(palpites[i].Selections)
+	}
+	return palpites, nil
+}
+
+func exportComentarios(userID int) ([]models.Comentario, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, palpite_id, user_id, texto, created_at, updated_at
+		FROM comentarios WHERE user_id = $1 ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comentarios := []models.Comentario{}
+	for rows.Next() {
+		var c models.Comentario
+		if err := rows.Scan(&c.ID, &c.PalpiteID, &c.UserID, &c.Texto, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		comentarios = append(comentarios, c)
+	}
+	return comentarios, rows.Err()
+}
+
+func exportReacoes(userID int) (map[string]interface{}, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, palpite_id, user_id, tipo, created_at
+		FROM palpites_reactions WHERE user_id = $1 ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	palpiteReactions := []models.PalpiteReaction{}
+	for rows.Next() {
+		var pr models.PalpiteReaction
+		if err := rows.Scan(&pr.ID, &pr.PalpiteID, &pr.UserID, &pr.Tipo, &pr.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		palpiteReactions = append(palpiteReactions, pr)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = database.DB.Query(`
+		SELECT id, comentario_id, user_id, tipo, created_at
+		FROM comentarios_reactions WHERE user_id = $1 ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	comentarioReactions := []models.ComentarioReaction{}
+	for rows.Next() {
+		var cr models.ComentarioReaction
+		if err := rows.Scan(&cr.ID, &cr.ComentarioID, &cr.UserID, &cr.Tipo, &cr.CreatedAt); err != nil {
+			return nil, err
+		}
+		comentarioReactions = append(comentarioReactions, cr)
+	}
+
+	return map[string]interface{}{
+		"palpites":    palpiteReactions,
+		"comentarios": comentarioReactions,
+	}, rows.Err()
+}
+
+func exportSessoes(userID int) ([]models.Session, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, user_id, user_agent, ip_address, created_at, last_used_at, expires_at, revoked_at
+		FROM sessions WHERE user_id = $1 ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IPAddress,
+			&s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt, &s.RevokedAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
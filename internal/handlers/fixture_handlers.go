@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"smartpicks-backend/internal/fixtures"
+	"smartpicks-backend/internal/repository"
+)
+
+// maxFixtureFileSize limita o arquivo de partidas importado
+const maxFixtureFileSize = 5 << 20
+
+// ImportFixtures importa partidas de um arquivo CSV ou JSON (multipart no campo "file" ou no corpo).
+// Por padrão é um dry-run que só devolve o relatório; ?dry_run=false grava. A rota exige matches:manage.
+// @Param format query string false "csv ou json (padrão: pelo Content-Type ou extensão)"
+// @Param dry_run query bool false "Apenas simula a importação (padrão true)"
+// @Param competition_id query int false "Competição das linhas sem competição"
+// @Param timezone query string false "Fuso dos horários sem fuso (padrão UTC, ex: America/Sao_Paulo)"
+func ImportFixtures(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	r.Body = http.MaxBytesReader(w, r.Body, maxFixtureFileSize+1<<20)
+
+	var body io.Reader = r.Body
+	format := strings.ToLower(query.Get("format"))
+	contentType := r.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxFixtureFileSize); err != nil {
+			sendErrorResponse(w, "Arquivo muito grande. Máximo 5MB", http.StatusBadRequest)
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			sendErrorResponse(w, "Erro ao receber arquivo: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+		if format == "" {
+			format = fixtures.DetectFormat(header.Header.Get("Content-Type"), header.Filename)
+		}
+	} else if format == "" {
+		format = fixtures.DetectFormat(contentType, "")
+	}
+	if format == "" {
+		sendErrorResponse(w, "Informe o formato do arquivo (?format=csv ou ?format=json)", http.StatusBadRequest)
+		return
+	}
+
+	dryRun := true
+	if value := query.Get("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			sendErrorResponse(w, "dry_run deve ser true ou false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	opts := repository.FixtureImportOptions{Apply: !dryRun}
+	if value := query.Get("competition_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			sendErrorResponse(w, "competition_id inválido", http.StatusBadRequest)
+			return
+		}
+		opts.CompetitionID = &id
+	}
+
+	loc := time.UTC
+	if value := query.Get("timezone"); value != "" {
+		var err error
+		if loc, err = time.LoadLocation(value); err != nil {
+			sendErrorResponse(w, "timezone inválido (ex: America/Sao_Paulo)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	rows, err := fixtures.Parse(format, body, loc)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao ler arquivo: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(rows) == 0 {
+		sendErrorResponse(w, "O arquivo não tem partidas", http.StatusBadRequest)
+		return
+	}
+
+	report, err := repository.ImportFixtures(rows, opts)
+	if errors.Is(err, repository.ErrCompetitionNotFound) {
+		sendErrorResponse(w, "Competição não encontrada", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao importar partidas: %v", err)
+		sendErrorResponse(w, "Erro ao importar partidas", http.StatusInternalServerError)
+		return
+	}
+	sendSuccessResponse(w, report)
+}
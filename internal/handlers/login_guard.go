@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"smartpicks-backend/internal/services"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyPasswordHash é comparado com a senha enviada quando o email não existe, para que o tempo
+// de resposta do login não revele quais emails estão cadastrados
+const dummyPasswordHash = "$2a$10$HWS.Ki4ou9E8XPyNIwpAPOp.BIDgfW/f2g970MoSRXkoTmbleJfBm"
+
+var loginGuard = &services.LoginGuard{
+	Store:            services.NewMemoryLoginAttemptStore(),
+	AccountThreshold: 5,
+	IPThreshold:      20,
+	BaseLockout:      time.Minute,
+	MaxLockout:       time.Hour,
+	Window:           15 * time.Minute,
+}
+
+// SetLoginGuard define o controle de tentativas de login (configurado no startup)
+func SetLoginGuard(g *services.LoginGuard) {
+	loginGuard = g
+}
+
+// checkLoginLockout retorna o tempo restante de bloqueio das chaves. Em caso de erro no
+// store o login não é bloqueado, para que uma falha do banco não derrube a autenticação.
+func checkLoginLockout(keys ...string) time.Duration {
+	retryAfter, err := loginGuard.Check(keys...)
+	if err != nil {
+		log.Printf("Erro ao verificar tentativas de login: %v", err)
+		return 0
+	}
+	return retryAfter
+}
+
+// registerLoginFailure contabiliza a falha em todas as chaves e retorna o maior bloqueio aplicado
+func registerLoginFailure(keys ...string) time.Duration {
+	var lockout time.Duration
+	for _, key := range keys {
+		d, err := loginGuard.RegisterFailure(key)
+		if err != nil {
+			log.Printf("Erro ao registrar tentativa de login: %v", err)
+			continue
+		}
+		if d > lockout {
+			lockout = d
+		}
+	}
+	return lockout
+}
+
+// verifyCurrentPassword confere a senha atual exigida por uma operação sensível (troca de senha
+// ou email, exclusão da conta, desativação do 2FA) com o mesmo bloqueio por conta do login.
+// Retorna false depois de responder 401 com a mensagem informada ou 429 se a conta está bloqueada.
+func verifyCurrentPassword(w http.ResponseWriter, email, hashedPassword, password, message string) bool {
+	accountKey := services.AccountLoginKey(email)
+	if retryAfter := checkLoginLockout(accountKey); retryAfter > 0 {
+		sendLockoutResponse(w, retryAfter)
+		return false
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)) != nil {
+		if lockout := registerLoginFailure(accountKey); lockout > 0 {
+			sendLockoutResponse(w, lockout)
+			return false
+		}
+		sendErrorResponse(w, message, http.StatusUnauthorized)
+		return false
+	}
+
+	if err := loginGuard.Reset(accountKey); err != nil {
+		log.Printf("Erro ao zerar tentativas de login: %v", err)
+	}
+	return true
+}
+
+// sendLockoutResponse responde 429 com o header Retry-After e o momento da liberação
+func sendLockoutResponse(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	sendJSONResponse(w, map[string]interface{}{
+		"message":      fmt.Sprintf("Muitas tentativas de login. Tente novamente em %s", formatWait(retryAfter)),
+		"locked":       true,
+		"retry_after":  seconds,
+		"locked_until": time.Now().Add(retryAfter).UTC(),
+	}, http.StatusTooManyRequests)
+}
+
+func formatWait(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%d segundos", int(math.Ceil(d.Seconds())))
+	}
+	return fmt.Sprintf("%d minutos", int(math.Ceil(d.Minutes())))
+}
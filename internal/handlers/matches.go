@@ -1,40 +1,262 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
-	"smartpicks-backend/internal/database"
+	"strconv"
+	"strings"
+	"time"
+
 	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/repository"
+
+	"github.com/gorilla/mux"
 )
 
-// GetAllMatches @Summary Obter todas as partidas
-// @Description Retorna uma lista de todas as partidas disponíveis
+// GetAllMatches @Summary Obter as partidas
+// @Description Retorna as partidas em ordem de início, com filtros e paginação
 // @Tags Matches
+// @Param from query string false "Início a partir de (YYYY-MM-DD ou RFC3339, UTC)"
+// @Param to query string false "Início até (YYYY-MM-DD inclusivo ou RFC3339, UTC)"
+// @Param team_id query int false "ID de um dos times"
+// @Param team query string false "Parte do nome de um dos times"
+// @Param competition_id query int false "ID da competição"
+// @Param status query string false "Status separados por vírgula (scheduled,live,finished,postponed,cancelled)"
+// @Param limit query int false "Itens por página (padrão 50, máximo 200)"
+// @Param offset query int false "Itens a pular"
 func GetAllMatches(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		sendErrorResponse(w, "Método não permitido", http.StatusMethodNotAllowed)
 		return
 	}
-	rows, err := database.DB.Query(`
-		SELECT id, team_a, team_b, match_date, location
-		FROM matches
-		ORDER BY match_date ASC
-	`)
-	if err != nil {
-		sendErrorResponse(w, "Erro ao buscar partidas: "+err.Error(), http.StatusInternalServerError)
+
+	query := r.URL.Query()
+	filter := repository.MatchFilter{Team: strings.TrimSpace(query.Get("team"))}
+
+	var err error
+	if filter.From, err = parseMatchTime(query.Get("from"), false); err != nil {
+		sendErrorResponse(w, "from deve estar no formato YYYY-MM-DD ou RFC3339", http.StatusBadRequest)
 		return
 	}
-	defer rows.Close()
-	var matches []models.Match
-	for rows.Next() {
-		var m models.Match
-		err := rows.Scan(&m.ID, &m.TeamA, &m.TeamB, &m.MatchDate, &m.Location)
-		if err != nil {
-			sendErrorResponse(w, "Erro ao ler partida: "+err.Error(), http.StatusInternalServerError)
+	if filter.To, err = parseMatchTime(query.Get("to"), true); err != nil {
+		sendErrorResponse(w, "to deve estar no formato YYYY-MM-DD ou RFC3339", http.StatusBadRequest)
+		return
+	}
+	if value := query.Get("team_id"); value != "" {
+		if filter.TeamID, err = strconv.Atoi(value); err != nil {
+			sendErrorResponse(w, "team_id inválido", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := query.Get("competition_id"); value != "" {
+		if filter.CompetitionID, err = strconv.Atoi(value); err != nil {
+			sendErrorResponse(w, "competition_id inválido", http.StatusBadRequest)
 			return
 		}
-		matches = append(matches, m)
+	}
+	if value := query.Get("status"); value != "" {
+		for _, status := range strings.Split(value, ",") {
+			status = strings.TrimSpace(status)
+			if !models.IsValidMatchStatus(status) {
+				sendErrorResponse(w, "status inválido. Use: "+strings.Join(models.ValidMatchStatuses, ", "), http.StatusBadRequest)
+				return
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	filter.Limit, err = strconv.Atoi(query.Get("limit"))
+	if err != nil || filter.Limit <= 0 || filter.Limit > 200 {
+		filter.Limit = 50
+	}
+	filter.Offset, err = strconv.Atoi(query.Get("offset"))
+	if err != nil || filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	matches, total, err := repository.ListMatches(filter)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao buscar partidas: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
 	sendSuccessResponse(w, map[string]interface{}{
 		"matches": matches,
+		"total":   total,
+		"limit":   filter.Limit,
+		"offset":  filter.Offset,
+	})
+}
+
+// GetMatchByID retorna uma partida
+func GetMatchByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendErrorResponse(w, "ID da partida inválido", http.StatusBadRequest)
+		return
+	}
+
+	match, err := repository.GetMatch(id)
+	if errors.Is(err, repository.ErrMatchNotFound) {
+		sendErrorResponse(w, "Partida não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sendErrorResponse(w, "Erro ao buscar partida", http.StatusInternalServerError)
+		return
+	}
+	sendSuccessResponse(w, map[string]interface{}{"match": match})
+}
+
+// GetMatchConsensus retorna o consenso da comunidade sobre a partida: as seleções dos palpites
+// agrupadas por mercado e seleção, com contagem, percentual, odd média, percentual ponderado pelo
+// ROI dos tipsters e os melhores tipsters de cada lado
+func GetMatchConsensus(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendErrorResponse(w, "ID da partida inválido", http.StatusBadRequest)
+		return
+	}
+
+	consensus, err := repository.GetMatchConsensus(id)
+	if errors.Is(err, repository.ErrMatchNotFound) {
+		sendErrorResponse(w, "Partida não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao calcular consenso da partida %d: %v", id, err)
+		sendErrorResponse(w, "Erro ao calcular consenso", http.StatusInternalServerError)
+		return
+	}
+	sendSuccessResponse(w, consensus)
+}
+
+// CreateMatch cadastra uma partida. A rota exige a permissão matches:manage.
+func CreateMatch(w http.ResponseWriter, r *http.Request) {
+	input, ok := decodeMatchInput(w, r)
+	if !ok {
+		return
+	}
+
+	match, err := repository.CreateMatch(input)
+	if handleMatchSaveError(w, err) {
+		return
+	}
+	sendJSONResponse(w, map[string]interface{}{
+		"match":   match,
+		"message": "Partida cadastrada com sucesso",
+	}, http.StatusCreated)
+}
+
+// UpdateMatch altera uma partida. A rota exige a permissão matches:manage.
+func UpdateMatch(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendErrorResponse(w, "ID da partida inválido", http.StatusBadRequest)
+		return
+	}
+	input, ok := decodeMatchInput(w, r)
+	if !ok {
+		return
+	}
+
+	match, err := repository.UpdateMatch(id, input)
+	if handleMatchSaveError(w, err) {
+		return
+	}
+	sendSuccessResponse(w, map[string]interface{}{
+		"match":   match,
+		"message": "Partida atualizada com sucesso",
 	})
 }
+
+// DeleteMatch remove uma partida sem palpites. A rota exige a permissão matches:manage.
+func DeleteMatch(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendErrorResponse(w, "ID da partida inválido", http.StatusBadRequest)
+		return
+	}
+
+	err = repository.DeleteMatch(id)
+	switch {
+	case errors.Is(err, repository.ErrMatchNotFound):
+		sendErrorResponse(w, "Partida não encontrada", http.StatusNotFound)
+	case errors.Is(err, repository.ErrInUse):
+		sendErrorResponse(w, "A partida tem palpites e não pode ser removida. Altere o status para cancelled", http.StatusConflict)
+	case err != nil:
+		sendErrorResponse(w, "Erro ao remover partida", http.StatusInternalServerError)
+	default:
+		sendSuccessResponse(w, map[string]string{"message": "Partida removida com sucesso"})
+	}
+}
+
+// decodeMatchInput lê e valida o corpo de criação/alteração de partida. Em caso de erro a
+// resposta já foi enviada.
+func decodeMatchInput(w http.ResponseWriter, r *http.Request) (repository.MatchInput, bool) {
+	var input repository.MatchInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		sendErrorResponse(w, "JSON inválido (match_date deve estar em RFC3339, ex: 2026-05-10T19:00:00Z)", http.StatusBadRequest)
+		return input, false
+	}
+
+	input.Location = strings.TrimSpace(input.Location)
+	if input.Status == "" {
+		input.Status = models.MATCH_STATUS_SCHEDULED
+	}
+
+	switch {
+	case input.HomeTeamID <= 0 || input.AwayTeamID <= 0:
+		sendErrorResponse(w, "home_team_id e away_team_id são obrigatórios", http.StatusBadRequest)
+	case input.HomeTeamID == input.AwayTeamID:
+		sendErrorResponse(w, "Mandante e visitante devem ser times diferentes", http.StatusBadRequest)
+	case input.MatchDate.IsZero():
+		sendErrorResponse(w, "match_date é obrigatório", http.StatusBadRequest)
+	case !models.IsValidMatchStatus(input.Status):
+		sendErrorResponse(w, "status inválido. Use: "+strings.Join(models.ValidMatchStatuses, ", "), http.StatusBadRequest)
+	default:
+		return input, true
+	}
+	return input, false
+}
+
+// handleMatchSaveError envia a resposta dos erros de gravação de partida. Retorna false sem erro.
+func handleMatchSaveError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, repository.ErrMatchNotFound):
+		sendErrorResponse(w, "Partida não encontrada", http.StatusNotFound)
+	case errors.Is(err, repository.ErrTeamNotFound):
+		sendErrorResponse(w, "Time não encontrado", http.StatusBadRequest)
+	case errors.Is(err, repository.ErrCompetitionNotFound):
+		sendErrorResponse(w, "Competição não encontrada", http.StatusBadRequest)
+	case errors.Is(err, repository.ErrMatchExists):
+		sendErrorResponse(w, "Já existe uma partida destes times neste dia", http.StatusConflict)
+	default:
+		log.Printf("Erro ao gravar partida: %v", err)
+		sendErrorResponse(w, "Erro ao gravar partida", http.StatusInternalServerError)
+	}
+	return true
+}
+
+// parseMatchTime aceita uma data (YYYY-MM-DD, em UTC) ou um horário RFC3339. Em endOfDay a data
+// vale até o fim do dia, pois o filtro "to" é exclusivo.
+func parseMatchTime(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		t = t.UTC()
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
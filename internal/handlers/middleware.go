@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/services"
+)
+
+type contextKey string
+
+const authUserContextKey contextKey = "authUser"
+
+// AuthUser representa o usuário autenticado pelo access token
+type AuthUser struct {
+	ID        int
+	Perfil    string
+	SessionID int
+}
+
+// HasPermission indica se o perfil do usuário autenticado concede a permissão
+func (u *AuthUser) HasPermission(permission string) bool {
+	return models.HasPermission(u.Perfil, permission)
+}
+
+// AuthMiddleware valida o header "Authorization: Bearer <token>" e coloca o usuário
+// autenticado no contexto da requisição. Requisições sem token seguem como anônimas;
+// cada handler decide se exige autenticação.
+func AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		tokenString, found := strings.CutPrefix(header, "Bearer ")
+		if !found || strings.TrimSpace(tokenString) == "" {
+			sendErrorResponse(w, "Header Authorization inválido. Use 'Bearer <token>'", http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := services.ParseAccessToken(strings.TrimSpace(tokenString))
+		if err != nil {
+			sendErrorResponse(w, "Token inválido ou expirado", http.StatusUnauthorized)
+			return
+		}
+
+		// O token só vale enquanto a sessão estiver ativa: logout, revogação, exclusão da conta e
+		// troca de senha encerram o acesso na hora, sem esperar o fim do JWT_ACCESS_TTL. O perfil
+		// vem do banco, para que uma mudança de perfil valha já na próxima requisição.
+		perfil, err := activeSessionPerfil(claims.SessionID, claims.UserID)
+		if err == sql.ErrNoRows {
+			sendErrorResponse(w, "Sessão encerrada. Faça login novamente", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			sendErrorResponse(w, "Erro ao validar sessão", http.StatusInternalServerError)
+			return
+		}
+
+		user := &AuthUser{ID: claims.UserID, Perfil: perfil, SessionID: claims.SessionID}
+		ctx := context.WithValue(r.Context(), authUserContextKey, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// activeSessionPerfil retorna o perfil atual do usuário se a sessão do token ainda estiver ativa
+// (busca pela chave primária de sessions). Retorna sql.ErrNoRows para sessões encerradas.
+func activeSessionPerfil(sessionID, userID int) (string, error) {
+	var perfil string
+	err := database.DB.QueryRow(`
+		SELECT u.perfil FROM sessions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.id = $1 AND s.user_id = $2 AND s.revoked_at IS NULL AND s.expires_at > CURRENT_TIMESTAMP
+			AND u.anonymized_at IS NULL`, sessionID, userID).Scan(&perfil)
+	return perfil, err
+}
+
+// RequirePermission restringe a rota a usuários autenticados cujo perfil conceda a permissão.
+// Responde 401 para requisições anônimas e 403 quando falta a permissão.
+func RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			user := GetAuthUser(r)
+			if user == nil {
+				sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
+				return
+			}
+			if !user.HasPermission(permission) {
+				sendErrorResponse(w, "Você não tem permissão para executar esta ação", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GetAuthUser retorna o usuário autenticado da requisição ou nil se for anônima
+func GetAuthUser(r *http.Request) *AuthUser {
+	user, _ := r.Context().Value(authUserContextKey).(*AuthUser)
+	return user
+}
+
+// GetUserIDFromRequest retorna o ID do usuário autenticado pelo token JWT (0 se anônimo)
+func GetUserIDFromRequest(r *http.Request) int {
+	if user := GetAuthUser(r); user != nil {
+		return user.ID
+	}
+	return 0
+}
@@ -2,11 +2,15 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
-	"os"
 	"smartpicks-backend/internal/database"
 	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/repository"
 	"smartpicks-backend/internal/services"
 	"strconv"
 	"strings"
@@ -26,11 +30,13 @@ func GetPalpites(w http.ResponseWriter, r *http.Request) {
 			p.id, 
 			p.user_id,
 			u.nome,
+			u.perfil,
 			p.titulo, 
-			p.img_url, 
+			COALESCE(p.img_url, '') AS img_url, 
 			p.link, 
 			p.created_at, 
 			p.updated_at,
+			p.img_variants, p.img_width, p.img_height, p.img_blurhash, p.status, p.profit,
 			u.avatar,
 			COALESCE(likes.count, 0) AS total_likes,
 			COALESCE(dislikes.count, 0) AS total_dislikes,
@@ -65,12 +71,13 @@ func GetPalpites(w http.ResponseWriter, r *http.Request) {
 	var palpites []models.PalpiteResponse
 	for rows.Next() {
 		var p models.Palpite
-		var userName string
+		var userName, perfil string
 		var avatar *string
 		var totalLikes, totalDislikes, totalComentarios int
 
 		if err := rows.Scan(
-			&p.ID, &p.UserID, &userName, &p.Titulo, &p.ImgURL, &p.Link, &p.CreatedAt, &p.UpdatedAt,
+			&p.ID, &p.UserID, &userName, &perfil, &p.Titulo, &p.ImgURL, &p.Link, &p.CreatedAt, &p.UpdatedAt,
+			&p.ImgVariants, &p.ImgWidth, &p.ImgHeight, &p.ImgBlurhash, &p.Status, &p.Profit,
 			&avatar,
 			&totalLikes, &totalDislikes, &totalComentarios,
 		); err != nil {
@@ -78,17 +85,13 @@ func GetPalpites(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if !strings.HasPrefix(p.ImgURL, "http") {
-			bucketName := os.Getenv("AWS_BUCKET_NAME")
-			region := os.Getenv("AWS_REGION")
-			trimmedKey := strings.TrimPrefix(p.ImgURL, "/")
-			p.ImgURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucketName, region, trimmedKey)
-		}
+		p.ImgURL = storedFileURL(p.ImgURL)
 
 		p.Avatar = avatar
 
 		response := p.ToResponse()
 		response.UserName = userName
+		response.UserVerified = models.HasPermission(perfil, models.PERMISSION_PALPITES_VERIFIED)
 		response.TotalLikes = totalLikes
 		response.TotalDislikes = totalDislikes
 		response.TotalComentarios = totalComentarios
@@ -103,6 +106,11 @@ func GetPalpites(w http.ResponseWriter, r *http.Request) {
 		palpites = append(palpites, response)
 	}
 
+	if err := attachSelections(palpites); err != nil {
+		sendErrorResponse(w, "Erro ao buscar seleções: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	sendSuccessResponse(w, map[string]interface{}{
 		"palpites": palpites,
 	})
@@ -149,11 +157,13 @@ func GetPalpitesByUserID(w http.ResponseWriter, r *http.Request) {
 			p.id, 
 			p.user_id,
 			u.nome,
+			u.perfil,
 			p.titulo, 
-			p.img_url, 
+			COALESCE(p.img_url, '') AS img_url, 
 			p.link, 
 			p.created_at, 
 			p.updated_at,
+			p.img_variants, p.img_width, p.img_height, p.img_blurhash, p.status, p.profit,
 			u.avatar,
 			COALESCE(likes.count, 0) AS total_likes,
 			COALESCE(dislikes.count, 0) AS total_dislikes,
@@ -189,12 +199,13 @@ func GetPalpitesByUserID(w http.ResponseWriter, r *http.Request) {
 	var palpites []models.PalpiteResponse
 	for rows.Next() {
 		var p models.Palpite
-		var userName string
+		var userName, perfil string
 		var avatar *string
 		var totalLikes, totalDislikes, totalComentarios int
 
 		if err := rows.Scan(
-			&p.ID, &p.UserID, &userName, &p.Titulo, &p.ImgURL, &p.Link, &p.CreatedAt, &p.UpdatedAt,
+			&p.ID, &p.UserID, &userName, &perfil, &p.Titulo, &p.ImgURL, &p.Link, &p.CreatedAt, &p.UpdatedAt,
+			&p.ImgVariants, &p.ImgWidth, &p.ImgHeight, &p.ImgBlurhash, &p.Status, &p.Profit,
 			&avatar,
 			&totalLikes, &totalDislikes, &totalComentarios,
 		); err != nil {
@@ -202,17 +213,13 @@ func GetPalpitesByUserID(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if !strings.HasPrefix(p.ImgURL, "http") {
-			bucketName := os.Getenv("AWS_BUCKET_NAME")
-			region := os.Getenv("AWS_REGION")
-			trimmedKey := strings.TrimPrefix(p.ImgURL, "/")
-			p.ImgURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucketName, region, trimmedKey)
-		}
+		p.ImgURL = storedFileURL(p.ImgURL)
 
 		p.Avatar = avatar
 
 		response := p.ToResponse()
 		response.UserName = userName
+		response.UserVerified = models.HasPermission(perfil, models.PERMISSION_PALPITES_VERIFIED)
 		response.TotalLikes = totalLikes
 		response.TotalDislikes = totalDislikes
 		response.TotalComentarios = totalComentarios
@@ -227,6 +234,11 @@ func GetPalpitesByUserID(w http.ResponseWriter, r *http.Request) {
 		palpites = append(palpites, response)
 	}
 
+	if err := attachSelections(palpites); err != nil {
+		sendErrorResponse(w, "Erro ao buscar seleções: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	sendSuccessResponse(w, map[string]interface{}{
 		"palpites": palpites,
 		"total":    len(palpites),
@@ -263,11 +275,13 @@ func GetPalpiteByID(w http.ResponseWriter, r *http.Request) {
 			p.id,
 			p.user_id,
 			u.nome,
+			u.perfil,
 			p.titulo,
-			p.img_url,
+			COALESCE(p.img_url, '') AS img_url,
 			p.link,
 			p.created_at,
 			p.updated_at,
+			p.img_variants, p.img_width, p.img_height, p.img_blurhash, p.status, p.profit,
 			u.avatar,
 			COALESCE(likes.count, 0) AS total_likes,
 			COALESCE(dislikes.count, 0) AS total_dislikes,
@@ -295,7 +309,7 @@ func GetPalpiteByID(w http.ResponseWriter, r *http.Request) {
 	`, palpiteID)
 
 	var palpite models.Palpite
-	var userName string
+	var userName, perfil string
 	var avatar *string
 	var totalLikes, totalDislikes, totalComentarios int
 
@@ -303,11 +317,18 @@ func GetPalpiteByID(w http.ResponseWriter, r *http.Request) {
 		&palpite.ID,
 		&palpite.UserID,
 		&userName,
+		&perfil,
 		&palpite.Titulo,
 		&palpite.ImgURL,
 		&palpite.Link,
 		&palpite.CreatedAt,
 		&palpite.UpdatedAt,
+		&palpite.ImgVariants,
+		&palpite.ImgWidth,
+		&palpite.ImgHeight,
+		&palpite.ImgBlurhash,
+		&palpite.Status,
+		&palpite.Profit,
 		&avatar,
 		&totalLikes,
 		&totalDislikes,
@@ -330,18 +351,21 @@ func GetPalpiteByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if strings.HasPrefix(palpite.ImgURL, "palpites/") {
-		bucketName := os.Getenv("AWS_BUCKET_NAME")
-		region := os.Getenv("AWS_REGION")
-		palpite.ImgURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucketName, region, palpite.ImgURL)
-	}
+	palpite.ImgURL = storedFileURL(palpite.ImgURL)
 
 	response := models.PalpiteResponse{
 		ID:               palpite.ID,
 		UserID:           palpite.UserID,
 		UserName:         userName,
+		UserVerified:     models.HasPermission(perfil, models.PERMISSION_PALPITES_VERIFIED),
 		Titulo:           palpite.Titulo,
 		ImgURL:           palpite.ImgURL,
+		ImgVariants:      palpite.ImgVariants,
+		ImgWidth:         palpite.ImgWidth,
+		ImgHeight:        palpite.ImgHeight,
+		ImgBlurhash:      palpite.ImgBlurhash,
+		Status:           palpite.Status,
+		Profit:           palpite.Profit,
 		Link:             palpite.Link,
 		CreatedAt:        palpite.CreatedAt,
 		UpdatedAt:        palpite.UpdatedAt,
@@ -352,6 +376,14 @@ func GetPalpiteByID(w http.ResponseWriter, r *http.Request) {
 		Comentarios:      comentarios,
 	}
 
+	selections, err := repository.LoadSelections([]int{palpite.ID})
+	if err != nil {
+		sendErrorResponse(w, "Erro ao buscar seleções: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	response.Selections = selections[palpite.ID]
+	response.Late = models.HasLateSelection(response.Selections)
+
 	sendSuccessResponse(w, map[string]interface{}{
 		"palpite": response,
 	})
@@ -420,16 +452,21 @@ func getComentariosByPalpiteID(palpiteID int) ([]models.ComentarioStats, error)
 }
 
 // PostPalpite @Summary Criar um novo palpite
-// @Description Cria um novo palpite com upload para o S3
+// @Description Cria um novo palpite do usuário autenticado. A imagem pode vir no campo image
+// @Description (multipart) ou, preferencialmente, como upload_key de um upload direto já concluído
+// @Description (POST /uploads/presign e POST /uploads/{key}/complete). Com selections (partida,
+// @Description mercado, seleção, odd e stake) a imagem é opcional.
 // @Tags Palpites
-// @Accept multipart/form-data
+// @Accept multipart/form-data,json
 // @Produce json
-// @Param user_id formData int true "ID do usuário"
 // @Param titulo formData string false "Título do palpite"
 // @Param link formData string false "Link do palpite"
-// @Param image formData file true "Imagem do palpite"
+// @Param upload_key formData string false "Chave de um upload direto concluído"
+// @Param image formData file false "Imagem do palpite"
+// @Param selections formData string false "Array JSON de seleções"
 // @Success 201 {object} map[string]interface{} "Palpite criado com sucesso"
 // @Failure 400 {object} map[string]string "Dados inválidos"
+// @Failure 401 {object} map[string]string "Usuário não autenticado"
 // @Failure 500 {object} map[string]string "Erro interno do servidor"
 // @Router /palpites [post]
 func PostPalpite(w http.ResponseWriter, r *http.Request) {
@@ -438,79 +475,222 @@ func PostPalpite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	contentType := r.Header.Get("Content-Type")
-	if !strings.HasPrefix(contentType, "multipart/form-data") {
-		sendErrorResponse(w, fmt.Sprintf("Content-Type inválido: %s, esperado multipart/form-data", contentType), http.StatusBadRequest)
+	authUser := GetAuthUser(r)
+	if authUser == nil {
+		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
 		return
 	}
-
-	if err := r.ParseMultipartForm(0); err != nil {
-		sendErrorResponse(w, "Erro ao processar multipart form: "+err.Error(), http.StatusBadRequest)
+	if !authUser.HasPermission(models.PERMISSION_PALPITES_CREATE) {
+		sendErrorResponse(w, "Você não tem permissão para criar palpites", http.StatusForbidden)
 		return
 	}
 
-	userID := r.FormValue("user_id")
-	titulo := r.FormValue("titulo")
-	link := r.FormValue("link")
+	var titulo, link, uploadKey string
+	var imageData []byte
+	var selections []models.PalpiteSelection
 
-	if userID == "" {
-		sendErrorResponse(w, "user_id é obrigatório", http.StatusBadRequest)
+	contentType := r.Header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "application/json"):
+		var req struct {
+			Titulo     string                    `json:"titulo"`
+			Link       string                    `json:"link"`
+			UploadKey  string                    `json:"upload_key"`
+			Selections []models.PalpiteSelection `json:"selections"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendErrorResponse(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+		titulo, link, uploadKey, selections = req.Titulo, req.Link, req.UploadKey, req.Selections
+	case strings.HasPrefix(contentType, "multipart/form-data"):
+		r.Body = http.MaxBytesReader(w, r.Body, maxImageUploadSize+1<<20)
+		if err := r.ParseMultipartForm(maxImageUploadSize); err != nil {
+			sendErrorResponse(w, "Erro ao processar multipart form: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		titulo, link, uploadKey = r.FormValue("titulo"), r.FormValue("link"), r.FormValue("upload_key")
+
+		var err error
+		if selections, err = decodeSelections(r.FormValue("selections")); err != nil {
+			sendErrorResponse(w, "selections deve ser um array JSON", http.StatusBadRequest)
+			return
+		}
+
+		if uploadKey == "" {
+			file, _, err := r.FormFile("image")
+			if err != nil && err != http.ErrMissingFile {
+				sendErrorResponse(w, "Erro ao receber arquivo: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if file != nil {
+				defer file.Close()
+
+				if imageData, err = io.ReadAll(file); err != nil {
+					sendErrorResponse(w, "Erro ao ler arquivo", http.StatusBadRequest)
+					return
+				}
+			}
+		}
+	default:
+		sendErrorResponse(w, fmt.Sprintf("Content-Type inválido: %s, esperado multipart/form-data ou application/json", contentType), http.StatusBadRequest)
 		return
 	}
 
-	file, _, err := r.FormFile("image")
+	problem, err := validateSelections(selections)
 	if err != nil {
-		sendErrorResponse(w, "Erro ao receber arquivo: "+err.Error(), http.StatusBadRequest)
+		sendErrorResponse(w, "Erro ao validar seleções: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer file.Close()
-
-	s3Service, err := services.NewS3Service()
-	if err != nil {
-		sendErrorResponse(w, "Erro ao configurar S3: "+err.Error(), http.StatusInternalServerError)
+	if problem != "" {
+		sendErrorResponse(w, problem, http.StatusBadRequest)
 		return
 	}
 
-	ext := ".jpg"
-	timestamp := time.Now().UnixNano()
-	fileName := fmt.Sprintf("palpite_%d%s", timestamp, ext)
-
-	imageURL, err := s3Service.UploadFile(file, fileName, "image/jpeg")
-	if err != nil {
-		sendErrorResponse(w, "Erro ao enviar para S3: "+err.Error(), http.StatusInternalServerError)
+	// O original (enviado direto ao storage ou no multipart) nunca é publicado: a imagem é
+	// processada e só as versões sem metadados são gravadas
+	if uploadKey != "" {
+		upload, err := loadUpload(uploadKey, authUser.ID)
+		if err != nil || upload.Status != models.UPLOAD_STATUS_COMPLETED || upload.PalpiteID != nil {
+			sendErrorResponse(w, "upload_key inválida: "+errUploadNotAvailable.Error(), http.StatusBadRequest)
+			return
+		}
+		if imageData, _, err = fileStorage.Get(upload.Key); err != nil {
+			log.Printf("Erro ao ler upload %s: %v", upload.Key, err)
+			sendErrorResponse(w, "Erro ao ler a imagem enviada", http.StatusInternalServerError)
+			return
+		}
+	}
+	// A imagem só é obrigatória em palpites sem seleções estruturadas
+	if len(imageData) == 0 && len(selections) == 0 {
+		sendErrorResponse(w, "Envie a imagem no campo image, a upload_key de um upload concluído ou as selections do palpite", http.StatusBadRequest)
 		return
 	}
 
+	// O horário do palpite vem sempre do relógio do servidor: é ele que decide se as partidas
+	// ainda aceitam palpites
+	now := time.Now()
 	palpite := models.Palpite{
-		UserID:    stringToInt(userID),
-		Titulo:    &titulo,
-		ImgURL:    imageURL,
-		Link:      &link,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		UserID:     authUser.ID,
+		Titulo:     &titulo,
+		Link:       &link,
+		Selections: selections,
+		CreatedAt:  now,
+		UpdatedAt:  now,
 	}
 
-	err = database.DB.QueryRow(`
-		INSERT INTO palpites (user_id, titulo, img_url, link, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id
-	`,
-		palpite.UserID, palpite.Titulo, palpite.ImgURL, palpite.Link,
-		palpite.CreatedAt, palpite.UpdatedAt,
-	).Scan(&palpite.ID)
+	if len(imageData) > 0 {
+		processed, err := services.ProcessPalpiteImage(fileStorage, authUser.ID, imageData)
+		if errors.Is(err, services.ErrInvalidImage) {
+			sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("Erro ao processar imagem do palpite: %v", err)
+			sendErrorResponse(w, "Erro ao salvar imagem", http.StatusInternalServerError)
+			return
+		}
 
-	if err != nil {
+		phash := int64(processed.PHash)
+		palpite.ImgURL = processed.URL
+		palpite.ImgVariants = processed.Variants
+		palpite.ImgWidth = &processed.Width
+		palpite.ImgHeight = &processed.Height
+		palpite.ImgBlurhash = &processed.Blurhash
+		palpite.ImgPHash = &phash
+
+		// Imagens quase idênticas a de outro palpite são marcadas como cópia, mas o palpite é aceito
+		match, err := repository.FindDuplicateImage(phash, repository.DuplicateMaxDistance())
+		if err != nil {
+			log.Printf("Erro ao procurar imagem repetida: %v", err)
+		} else if match != nil {
+			palpite.DuplicateOf = &match.OriginalID
+			palpite.DuplicateDistance = &match.Distance
+		}
+	}
+
+	if err := insertPalpite(&palpite, uploadKey); err != nil {
+		repository.DeleteStoredFiles(authUser.ID, palpite.ImgVariants.URLs())
+		if err == errUploadNotAvailable {
+			sendErrorResponse(w, "upload_key inválida: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		var started *repository.MatchStartedError
+		if errors.As(err, &started) {
+			sendErrorResponse(w, "Palpite recusado: "+started.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, repository.ErrMatchNotFound) {
+			// A partida foi removida entre a validação e a gravação
+			sendErrorResponse(w, "Palpite recusado: partida não encontrada", http.StatusBadRequest)
+			return
+		}
 		sendErrorResponse(w, "Erro ao salvar palpite: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	// Com o original apagado o registro do upload não é mais necessário. Se a remoção falhar, o
+	// registro fica vinculado ao palpite e o purge-uploads tenta de novo.
+	if uploadKey != "" {
+		if err := fileStorage.Delete(uploadKey); err != nil {
+			log.Printf("Erro ao remover original do upload %s: %v", uploadKey, err)
+		} else if _, err := database.DB.Exec("DELETE FROM uploads WHERE storage_key = $1", uploadKey); err != nil {
+			log.Printf("Erro ao remover registro do upload %s: %v", uploadKey, err)
+		}
+	}
+
+	message := "Palpite criado com sucesso"
+	if palpite.DuplicateOf != nil {
+		message = "Palpite criado com sucesso. A imagem é parecida com a de outro palpite e foi marcada para revisão"
+	}
+	if models.HasLateSelection(palpite.Selections) {
+		message += fmt.Sprintf(". O palpite foi marcado como tardio (menos de %d minutos antes do início)",
+			int(repository.LateWindow().Minutes()))
+	}
 	sendSuccessResponse(w, map[string]interface{}{
 		"palpite": palpite.ToResponse(),
-		"message": "Palpite criado com sucesso",
+		"message": message,
 	})
 }
 
-func stringToInt(s string) int {
-	i, _ := strconv.Atoi(s)
-	return i
+// insertPalpite grava o palpite com as seleções e, quando a imagem veio de um upload direto,
+// vincula o upload a ele na mesma transação (um upload só pode ser usado por um palpite)
+func insertPalpite(palpite *models.Palpite, uploadKey string) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Palpites com seleções começam pendentes de apuração; os demais não têm resultado
+	if len(palpite.Selections) > 0 {
+		status := models.PICK_STATUS_PENDING
+		palpite.Status = &status
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO palpites (user_id, titulo, img_url, img_variants, img_width, img_height, img_blurhash,
+			img_phash, duplicate_of, duplicate_distance, status, link, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id
+	`,
+		palpite.UserID, palpite.Titulo, sql.NullString{String: palpite.ImgURL, Valid: palpite.ImgURL != ""},
+		palpite.ImgVariants, palpite.ImgWidth, palpite.ImgHeight, palpite.ImgBlurhash, palpite.ImgPHash,
+		palpite.DuplicateOf, palpite.DuplicateDistance, palpite.Status, palpite.Link,
+		palpite.CreatedAt, palpite.UpdatedAt,
+	).Scan(&palpite.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := repository.InsertSelections(tx, palpite.ID, palpite.Selections, palpite.CreatedAt); err != nil {
+		return err
+	}
+
+	if uploadKey != "" {
+		if err := claimUpload(tx, uploadKey, palpite.UserID, palpite.ID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
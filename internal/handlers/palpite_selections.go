@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// decodeSelections lê o campo selections enviado como texto JSON no multipart
+func decodeSelections(raw string) ([]models.PalpiteSelection, error) {
+	var selections []models.PalpiteSelection
+	if raw == "" {
+		return selections, nil
+	}
+	err := json.Unmarshal([]byte(raw), &selections)
+	return selections, err
+}
+
+// validateSelections padroniza as seleções e confere se as partidas existem. Retorna a mensagem
+// de erro para o cliente, ou "" quando todas são válidas.
+func validateSelections(selections []models.PalpiteSelection) (string, error) {
+	if len(selections) > models.MAX_SELECTIONS_PER_PALPITE {
+		return fmt.Sprintf("Um palpite pode ter no máximo %d seleções", models.MAX_SELECTIONS_PER_PALPITE), nil
+	}
+
+	var matchIDs []int
+	for i := range selections {
+		if err := selections[i].Normalize(); err != nil {
+			return fmt.Sprintf("Seleção %d inválida: %s", i+1, err.Error()), nil
+		}
+		for j := 0; j < i; j++ {
+			if selections[i].SameBet(&selections[j]) {
+				return fmt.Sprintf("Seleção %d repete a seleção %d", i+1, j+1), nil
+			}
+		}
+		matchIDs = append(matchIDs, selections[i].MatchID)
+	}
+
+	matches, err := repository.LoadMatches(matchIDs)
+	if err != nil {
+		return "", err
+	}
+	// Conferência antecipada, antes de processar a imagem; a definitiva é feita na gravação
+	now, grace := time.Now(), repository.KickoffGrace()
+	for i := range selections {
+		match, ok := matches[selections[i].MatchID]
+		if !ok {
+			return fmt.Sprintf("Seleção %d inválida: partida %d não encontrada", i+1, selections[i].MatchID), nil
+		}
+		if match.ClosedForPicks(now, grace) {
+			started := repository.MatchStartedError{MatchID: match.ID, Kickoff: match.MatchDate}
+			return fmt.Sprintf("Seleção %d recusada: %s", i+1, started.Error()), nil
+		}
+		selections[i].Match = &match
+	}
+	return "", nil
+}
+
+// attachSelections preenche as seleções de cada palpite da lista
+func attachSelections(palpites []models.PalpiteResponse) error {
+	ids := make([]int, len(palpites))
+	for i := range palpites {
+		ids[i] = palpites[i].ID
+	}
+	selections, err := repository.LoadSelections(ids)
+	if err != nil {
+		return err
+	}
+	for i := range palpites {
+		palpites[i].Selections = selections[palpites[i].ID]
+		palpites[i].Late = models.HasLateSelection(palpites[i].Selections)
+	}
+	return nil
+}
+
+// attachStatsSelections é o attachSelections das listas com estatísticas
+func attachStatsSelections(palpites []models.PalpiteStats) error {
+	ids := make([]int, len(palpites))
+	for i := range palpites {
+		ids[i] = palpites[i].ID
+	}
+	selections, err := repository.LoadSelections(ids)
+	if err != nil {
+		return err
+	}
+	for i := range palpites {
+		palpites[i].Selections = selections[palpites[i].ID]
+		palpites[i].Late = models.HasLateSelection(palpites[i].Selections)
+	}
+	return nil
+}
+
+// UpdatePalpiteSelections troca as seleções de um palpite do próprio usuário. Só é aceito enquanto
+// nenhuma das partidas (antigas ou novas) começou e nenhuma seleção foi apurada.
+// @Tags Palpites
+// @Param id path int true "ID do palpite"
+// @Router /palpites/{id}/selections [put]
+func UpdatePalpiteSelections(w http.ResponseWriter, r *http.Request) {
+	authUser := GetAuthUser(r)
+	if authUser == nil {
+		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
+		return
+	}
+	palpiteID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendErrorResponse(w, "ID do palpite inválido", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Selections []models.PalpiteSelection `json:"selections"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	if len(req.Selections) == 0 {
+		sendErrorResponse(w, "Envie ao menos uma seleção", http.StatusBadRequest)
+		return
+	}
+	problem, err := validateSelections(req.Selections)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao validar seleções: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if problem != "" {
+		sendErrorResponse(w, problem, http.StatusBadRequest)
+		return
+	}
+
+	err = repository.ReplaceSelections(palpiteID, authUser.ID, req.Selections, time.Now())
+	var started *repository.MatchStartedError
+	switch {
+	case errors.Is(err, repository.ErrPalpiteNotFound):
+		sendErrorResponse(w, "Palpite não encontrado", http.StatusNotFound)
+		return
+	case errors.Is(err, repository.ErrSelectionsSettled):
+		sendErrorResponse(w, "O palpite já tem seleções apuradas e não pode ser alterado", http.StatusConflict)
+		return
+	case errors.As(err, &started):
+		sendErrorResponse(w, "Alteração recusada: "+started.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, repository.ErrMatchNotFound):
+		// A partida foi removida entre a validação e a gravação
+		sendErrorResponse(w, "Alteração recusada: partida não encontrada", http.StatusBadRequest)
+		return
+	case err != nil:
+		sendErrorResponse(w, "Erro ao alterar seleções: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sendSuccessResponse(w, map[string]interface{}{
+		"selections": req.Selections,
+		"late":       models.HasLateSelection(req.Selections),
+		"message":    "Seleções alteradas com sucesso",
+	})
+}
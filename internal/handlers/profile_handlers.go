@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/services"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// UpdateMyProfile atualiza nome e data de nascimento do usuário autenticado. Trocar o email
+// exige a senha atual, e o novo endereço só passa a valer depois de confirmado pelo link
+// enviado para ele. Se o link não puder ser enviado nada é alterado.
+func UpdateMyProfile(w http.ResponseWriter, r *http.Request) {
+	authUser := GetAuthUser(r)
+	if authUser == nil {
+		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Nome            *string `json:"nome"`
+		DataNascimento  *string `json:"data_nascimento"`
+		Email           *string `json:"email"`
+		CurrentPassword string  `json:"current_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+
+	user, err := loadUserByID(authUser.ID)
+	if err != nil {
+		sendErrorResponse(w, "Usuário não encontrado", http.StatusNotFound)
+		return
+	}
+
+	if req.Nome != nil {
+		nome := strings.TrimSpace(*req.Nome)
+		if nome == "" {
+			sendErrorResponse(w, "Nome não pode ser vazio", http.StatusBadRequest)
+			return
+		}
+		user.Nome = nome
+	}
+
+	if req.DataNascimento != nil {
+		dataNascimento, err := parseDataNascimento(*req.DataNascimento)
+		if err != nil {
+			sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		user.DataNascimento = dataNascimento
+	}
+
+	var newEmail string
+	if req.Email != nil {
+		if req.CurrentPassword == "" {
+			sendErrorResponse(w, "current_password é obrigatório para trocar o email", http.StatusBadRequest)
+			return
+		}
+		var currentHash string
+		if err := database.DB.QueryRow("SELECT password FROM users WHERE id = $1", user.ID).Scan(&currentHash); err != nil {
+			sendErrorResponse(w, "Usuário não encontrado", http.StatusNotFound)
+			return
+		}
+		if !verifyCurrentPassword(w, user.Email, currentHash, req.CurrentPassword, "Senha atual incorreta") {
+			return
+		}
+
+		if !strings.EqualFold(strings.TrimSpace(*req.Email), user.Email) {
+			newEmail = strings.TrimSpace(*req.Email)
+			if newEmail == "" {
+				sendErrorResponse(w, "Email não pode ser vazio", http.StatusBadRequest)
+				return
+			}
+			if userExists("email", newEmail) {
+				sendErrorResponse(w, "Email já cadastrado", http.StatusConflict)
+				return
+			}
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		sendErrorResponse(w, "Erro ao atualizar perfil", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE users SET nome = $1, data_nascimento = $2 WHERE id = $3`,
+		user.Nome, user.DataNascimento, user.ID)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao atualizar perfil", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"user":    userResponse(&user),
+		"message": "Perfil atualizado com sucesso",
+	}
+
+	if newEmail != "" {
+		// O link é enviado antes do commit: se o envio falhar, o perfil não é alterado
+		if err := requestEmailChange(tx, &user, newEmail); err != nil {
+			log.Printf("Erro ao iniciar troca de email do usuário %d: %v", user.ID, err)
+			sendErrorResponse(w, "Erro ao enviar confirmação para o novo email", http.StatusInternalServerError)
+			return
+		}
+		response["pending_email"] = newEmail
+		response["message"] = "Perfil atualizado. Confirme o novo email pelo link enviado para concluir a troca"
+	}
+
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "Erro ao atualizar perfil", http.StatusInternalServerError)
+		return
+	}
+
+	if newEmail != "" && user.Email != "" {
+		notifyEmailChangeRequest(&user, newEmail)
+	}
+
+	sendSuccessResponse(w, response)
+}
+
+// ChangeMyPassword troca a senha do usuário autenticado e encerra as demais sessões
+func ChangeMyPassword(w http.ResponseWriter, r *http.Request) {
+	authUser := GetAuthUser(r)
+	if authUser == nil {
+		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		sendErrorResponse(w, "current_password e new_password são obrigatórios", http.StatusBadRequest)
+		return
+	}
+
+	var email, currentHash string
+	if err := database.DB.QueryRow("SELECT COALESCE(email, ''), password FROM users WHERE id = $1", authUser.ID).
+		Scan(&email, &currentHash); err != nil {
+		sendErrorResponse(w, "Usuário não encontrado", http.StatusNotFound)
+		return
+	}
+	if !verifyCurrentPassword(w, email, currentHash, req.CurrentPassword, "Senha atual incorreta") {
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao processar password", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		sendErrorResponse(w, "Erro ao alterar senha", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET password = $1 WHERE id = $2", string(hashedPassword), authUser.ID); err != nil {
+		sendErrorResponse(w, "Erro ao alterar senha", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.Exec(`
+		UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP, revoked_reason = 'password_change'
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL`, authUser.ID, authUser.SessionID)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao alterar senha", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "Erro ao alterar senha", http.StatusInternalServerError)
+		return
+	}
+
+	revoked, _ := result.RowsAffected()
+	sendSuccessResponse(w, map[string]interface{}{
+		"message":          "Senha alterada com sucesso",
+		"revoked_sessions": revoked,
+	})
+}
+
+// ConfirmEmailChange troca o email do usuário pelo endereço confirmado no link
+func ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		sendErrorResponse(w, "Token é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		sendErrorResponse(w, "Erro ao confirmar email", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	userID, err := consumeUserToken(tx, token, tokenPurposeEmailChange)
+	if err == errInvalidUserToken {
+		sendErrorResponse(w, "Token inválido ou expirado", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		sendErrorResponse(w, "Erro ao confirmar email", http.StatusInternalServerError)
+		return
+	}
+
+	var oldEmail, newEmail sql.NullString
+	err = tx.QueryRow("SELECT email, pending_email FROM users WHERE id = $1", userID).Scan(&oldEmail, &newEmail)
+	if err != nil || !newEmail.Valid {
+		sendErrorResponse(w, "Não há troca de email pendente", http.StatusBadRequest)
+		return
+	}
+
+	var taken bool
+	if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email = $1 AND id <> $2)",
+		newEmail.String, userID).Scan(&taken); err != nil {
+		sendErrorResponse(w, "Erro ao confirmar email", http.StatusInternalServerError)
+		return
+	}
+	if taken {
+		sendErrorResponse(w, "Email já cadastrado", http.StatusConflict)
+		return
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE users SET email = pending_email, pending_email = NULL, email_verified_at = CURRENT_TIMESTAMP
+		WHERE id = $1`, userID); err != nil {
+		sendErrorResponse(w, "Erro ao confirmar email", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "Erro ao confirmar email", http.StatusInternalServerError)
+		return
+	}
+
+	if oldEmail.Valid {
+		if err := loginGuard.Reset(services.AccountLoginKey(oldEmail.String)); err != nil {
+			log.Printf("Erro ao zerar tentativas de login: %v", err)
+		}
+	}
+
+	sendSuccessResponse(w, map[string]string{"message": "Email alterado com sucesso"})
+}
+
+// requestEmailChange guarda o novo email como pendente e envia o link de confirmação para ele,
+// dentro da transação da atualização do perfil
+func requestEmailChange(tx *sql.Tx, user *models.User, newEmail string) error {
+	if _, err := tx.Exec("UPDATE users SET pending_email = $1 WHERE id = $2", newEmail, user.ID); err != nil {
+		return err
+	}
+
+	token, err := createUserTokenTx(tx, user.ID, tokenPurposeEmailChange, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/api/auth/confirm-email-change?token=%s", apiURL(), token)
+	return mailer.Send(services.Email{
+		To:      newEmail,
+		Subject: "SmartPicks - Confirme seu novo email",
+		Body: fmt.Sprintf("Olá, %s!\n\nConfirme o seu novo email acessando o link abaixo (válido por 48 horas):\n\n%s",
+			user.Nome, link),
+	})
+}
+
+// notifyEmailChangeRequest avisa o endereço atual sobre o pedido de troca de email
+func notifyEmailChangeRequest(user *models.User, newEmail string) {
+	err := mailer.Send(services.Email{
+		To:      user.Email,
+		Subject: "SmartPicks - Pedido de troca de email",
+		Body: fmt.Sprintf("Olá, %s!\n\nRecebemos um pedido para trocar o email da sua conta para %s. "+
+			"Se não foi você, altere sua senha imediatamente.", user.Nome, newEmail),
+	})
+	if err != nil {
+		log.Printf("Erro ao avisar o email atual do usuário %d: %v", user.ID, err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"smartpicks-backend/internal/database"
 	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/repository"
 
 	"github.com/gorilla/mux"
 )
@@ -117,14 +118,16 @@ func GetPalpiteStats(w http.ResponseWriter, r *http.Request) {
 			p.id,
 			p.user_id,
 			p.titulo,
-			p.img_url,
+			COALESCE(p.img_url, '') AS img_url,
 			p.link,
 			p.created_at,
 			p.updated_at,
+			p.img_variants, p.img_width, p.img_height, p.img_blurhash, p.status, p.profit,
 			COALESCE(likes.count, 0) AS total_likes,
 			COALESCE(dislikes.count, 0) AS total_dislikes,
 			COALESCE(comments.count, 0) AS total_comentarios,
 			u.nome AS autor_nome,
+			u.perfil AS autor_perfil,
 			u.avatar AS autor_avatar,
 			ur.tipo AS user_reaction
 		FROM palpites p
@@ -151,6 +154,7 @@ func GetPalpiteStats(w http.ResponseWriter, r *http.Request) {
 	`
 
 	var palpite models.PalpiteStats
+	var autorPerfil string
 	err := database.DB.QueryRow(query, palpiteID, userID).Scan(
 		&palpite.ID,
 		&palpite.UserID,
@@ -159,10 +163,17 @@ func GetPalpiteStats(w http.ResponseWriter, r *http.Request) {
 		&palpite.Link,
 		&palpite.CreatedAt,
 		&palpite.UpdatedAt,
+		&palpite.ImgVariants,
+		&palpite.ImgWidth,
+		&palpite.ImgHeight,
+		&palpite.ImgBlurhash,
+		&palpite.Status,
+		&palpite.Profit,
 		&palpite.TotalLikes,
 		&palpite.TotalDislikes,
 		&palpite.TotalComentarios,
 		&palpite.AutorNome,
+		&autorPerfil,
 		&palpite.AutorAvatar,
 		&palpite.UserReaction,
 	)
@@ -175,6 +186,15 @@ func GetPalpiteStats(w http.ResponseWriter, r *http.Request) {
 		sendErrorResponse(w, "Erro ao buscar palpite", http.StatusInternalServerError)
 		return
 	}
+	palpite.AutorVerificado = models.HasPermission(autorPerfil, models.PERMISSION_PALPITES_VERIFIED)
+
+	selections, err := repository.LoadSelections([]int{palpite.ID})
+	if err != nil {
+		sendErrorResponse(w, "Erro ao buscar seleções", http.StatusInternalServerError)
+		return
+	}
+	palpite.Selections = selections[palpite.ID]
+	palpite.Late = models.HasLateSelection(palpite.Selections)
 
 	sendJSONResponse(w, palpite, http.StatusOK)
 }
@@ -188,14 +208,16 @@ func GetAllPalpitesWithStats(w http.ResponseWriter, r *http.Request) {
 			p.id,
 			p.user_id,
 			p.titulo,
-			p.img_url,
+			COALESCE(p.img_url, '') AS img_url,
 			p.link,
 			p.created_at,
 			p.updated_at,
+			p.img_variants, p.img_width, p.img_height, p.img_blurhash, p.status, p.profit,
 			COALESCE(likes.count, 0) AS total_likes,
 			COALESCE(dislikes.count, 0) AS total_dislikes,
 			COALESCE(comments.count, 0) AS total_comentarios,
 			u.nome AS autor_nome,
+			u.perfil AS autor_perfil,
 			u.avatar AS autor_avatar,
 			ur.tipo AS user_reaction
 		FROM palpites p
@@ -231,6 +253,7 @@ func GetAllPalpitesWithStats(w http.ResponseWriter, r *http.Request) {
 	var palpites []models.PalpiteStats
 	for rows.Next() {
 		var palpite models.PalpiteStats
+		var autorPerfil string
 		err := rows.Scan(
 			&palpite.ID,
 			&palpite.UserID,
@@ -239,10 +262,17 @@ func GetAllPalpitesWithStats(w http.ResponseWriter, r *http.Request) {
 			&palpite.Link,
 			&palpite.CreatedAt,
 			&palpite.UpdatedAt,
+			&palpite.ImgVariants,
+			&palpite.ImgWidth,
+			&palpite.ImgHeight,
+			&palpite.ImgBlurhash,
+			&palpite.Status,
+			&palpite.Profit,
 			&palpite.TotalLikes,
 			&palpite.TotalDislikes,
 			&palpite.TotalComentarios,
 			&palpite.AutorNome,
+			&autorPerfil,
 			&palpite.AutorAvatar,
 			&palpite.UserReaction,
 		)
@@ -250,8 +280,14 @@ func GetAllPalpitesWithStats(w http.ResponseWriter, r *http.Request) {
 			sendErrorResponse(w, "Erro ao processar palpites", http.StatusInternalServerError)
 			return
 		}
+		palpite.AutorVerificado = models.HasPermission(autorPerfil, models.PERMISSION_PALPITES_VERIFIED)
 		palpites = append(palpites, palpite)
 	}
 
+	if err := attachStatsSelections(palpites); err != nil {
+		sendErrorResponse(w, "Erro ao buscar seleções", http.StatusInternalServerError)
+		return
+	}
+
 	sendJSONResponse(w, palpites, http.StatusOK)
 }
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"smartpicks-backend/internal/repository"
+	"smartpicks-backend/internal/settlement"
+
+	"github.com/gorilla/mux"
+)
+
+// SettleMatch apura manualmente os palpites de uma partida com o placar final informado. Com
+// void=true todas as seleções são devolvidas (jogo cancelado ou abandonado). Uma partida já
+// apurada, ou com placar antes da data da partida, só é apurada com resettle=true. A rota exige
+// a permissão palpites:settle.
+func SettleMatch(w http.ResponseWriter, r *http.Request) {
+	matchID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendErrorResponse(w, "ID da partida inválido", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		HomeScore *int   `json:"home_score"`
+		AwayScore *int   `json:"away_score"`
+		Void      bool   `json:"void"`
+		Resettle  bool   `json:"resettle"`
+		Reason    string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+
+	settleReq := repository.SettleRequest{
+		MatchID:  matchID,
+		Reason:   req.Reason,
+		Resettle: req.Resettle,
+	}
+	if authUser := GetAuthUser(r); authUser != nil {
+		settleReq.SettledBy = &authUser.ID
+	}
+	if !req.Void {
+		if req.HomeScore == nil || req.AwayScore == nil {
+			sendErrorResponse(w, "Informe home_score e away_score, ou void=true", http.StatusBadRequest)
+			return
+		}
+		settleReq.Score = &settlement.Score{Home: *req.HomeScore, Away: *req.AwayScore}
+	}
+
+	audit, err := repository.SettleMatch(settleReq)
+	switch {
+	case errors.Is(err, repository.ErrMatchNotFound):
+		sendErrorResponse(w, "Partida não encontrada", http.StatusNotFound)
+		return
+	case errors.Is(err, repository.ErrAlreadySettled):
+		sendErrorResponse(w, "Partida já apurada. Envie resettle=true para apurar novamente", http.StatusConflict)
+		return
+	case errors.Is(err, repository.ErrMatchNotStarted):
+		sendErrorResponse(w, "A partida ainda não começou. Envie resettle=true para apurar mesmo assim", http.StatusConflict)
+		return
+	case errors.Is(err, settlement.ErrInvalidScore):
+		sendErrorResponse(w, "Placar inválido", http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Printf("Erro ao apurar partida %d: %v", matchID, err)
+		sendErrorResponse(w, "Erro ao apurar partida", http.StatusInternalServerError)
+		return
+	}
+
+	sendSuccessResponse(w, map[string]interface{}{
+		"settlement": audit,
+		"message":    "Partida apurada com sucesso",
+	})
+}
+
+// GetMatchSettlements retorna a trilha de auditoria das apurações de uma partida
+func GetMatchSettlements(w http.ResponseWriter, r *http.Request) {
+	matchID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendErrorResponse(w, "ID da partida inválido", http.StatusBadRequest)
+		return
+	}
+
+	audits, err := repository.ListSettlementAudit(matchID)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao buscar apurações", http.StatusInternalServerError)
+		return
+	}
+
+	sendSuccessResponse(w, map[string]interface{}{
+		"match_id":    matchID,
+		"settlements": audits,
+	})
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// GetUserStats retorna o desempenho do tipster calculado a partir das seleções já apuradas:
+// acertos, lucro em unidades, ROI, yield, odd média, sequências e divisão por mercado e
+// competição. Aceita ?from= e ?to= (YYYY-MM-DD, pela data das partidas).
+func GetUserStats(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		sendErrorResponse(w, "ID do usuário inválido", http.StatusBadRequest)
+		return
+	}
+
+	var statsRange repository.StatsRange
+	for param, target := range map[string]**time.Time{"from": &statsRange.From, "to": &statsRange.To} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			sendErrorResponse(w, param+" deve estar no formato YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		*target = &date
+	}
+	if statsRange.From != nil && statsRange.To != nil && statsRange.To.Before(*statsRange.From) {
+		sendErrorResponse(w, "to deve ser igual ou posterior a from", http.StatusBadRequest)
+		return
+	}
+
+	var exists bool
+	if err := database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists); err != nil {
+		sendErrorResponse(w, "Erro ao verificar usuário", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		sendErrorResponse(w, "Usuário não encontrado", http.StatusNotFound)
+		return
+	}
+
+	stats, computedAt, err := repository.GetTipsterStats(userID, statsRange)
+	if err != nil {
+		log.Printf("Erro ao calcular estatísticas do usuário %d: %v", userID, err)
+		sendErrorResponse(w, "Erro ao calcular estatísticas", http.StatusInternalServerError)
+		return
+	}
+
+	sendSuccessResponse(w, map[string]interface{}{
+		"user_id":     userID,
+		"from":        r.URL.Query().Get("from"),
+		"to":          r.URL.Query().Get("to"),
+		"stats":       stats,
+		"computed_at": computedAt,
+	})
+}
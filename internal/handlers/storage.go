@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"strings"
+
+	"smartpicks-backend/internal/services"
+)
+
+var fileStorage services.Storage = services.NewMemoryStorage()
+
+// SetStorage define onde ficam os arquivos enviados (configurado no startup pelo STORAGE_DRIVER)
+func SetStorage(s services.Storage) {
+	fileStorage = s
+}
+
+// storedFileURL converte a chave salva no banco (ex: "palpites/x.jpg") na URL pública do
+// arquivo. URLs completas são retornadas como estão.
+func storedFileURL(value string) string {
+	if value == "" || strings.HasPrefix(value, "http") {
+		return value
+	}
+	return fileStorage.URL(strings.TrimPrefix(value, "/"))
+}
@@ -0,0 +1,562 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"smartpicks-backend/internal/config"
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/services"
+)
+
+const (
+	totpIssuer         = "SmartPicks"
+	recoveryCodesCount = 10
+	challengeTokenTTL  = 5 * time.Minute
+	twoFactorSetupTTL  = 15 * time.Minute
+)
+
+// Finalidade do link enviado por email para o cadastro obrigatório de 2FA (tabela user_tokens)
+const tokenPurposeTwoFactorSetup = "two_factor_setup"
+
+// twoFactorRequest é o corpo aceito pelos endpoints de 2FA
+type twoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	SetupToken     string `json:"setup_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+	Password       string `json:"password"`
+}
+
+// adminTwoFactorRequired indica se o 2FA é obrigatório para administradores (REQUIRE_ADMIN_2FA)
+func adminTwoFactorRequired() bool {
+	return config.Bool("REQUIRE_ADMIN_2FA", false)
+}
+
+// sendTwoFactorChallenge responde ao login com um token de desafio em vez dos tokens de acesso
+func sendTwoFactorChallenge(w http.ResponseWriter, userID int) {
+	challengeToken, err := services.GenerateChallengeToken(userID, services.AudienceTwoFactorLogin, challengeTokenTTL)
+	if err != nil {
+		log.Printf("Erro ao gerar token de desafio 2FA: %v", err)
+		sendErrorResponse(w, "Erro ao gerar token de acesso", http.StatusInternalServerError)
+		return
+	}
+
+	sendSuccessResponse(w, map[string]interface{}{
+		"challenge_token":     challengeToken,
+		"expires_in":          int(challengeTokenTTL.Seconds()),
+		"two_factor_required": true,
+		"message":             "Informe o código do aplicativo autenticador",
+	})
+}
+
+// sendTwoFactorSetupLink responde ao login de um administrador sem 2FA (com REQUIRE_ADMIN_2FA).
+// A senha sozinha não libera o cadastro: o link com o setup_token vai para o email da conta,
+// para que quem só conhece a senha não consiga cadastrar o próprio autenticador.
+func sendTwoFactorSetupLink(w http.ResponseWriter, user *models.User) {
+	token, err := createUserToken(user.ID, tokenPurposeTwoFactorSetup, twoFactorSetupTTL)
+	if err != nil {
+		log.Printf("Erro ao gerar token de cadastro 2FA: %v", err)
+		sendErrorResponse(w, "Erro ao gerar token de acesso", http.StatusInternalServerError)
+		return
+	}
+
+	link := fmt.Sprintf("%s/configurar-2fa?token=%s", appURL(), token)
+	err = mailer.Send(services.Email{
+		To:      user.Email,
+		Subject: "SmartPicks - Ative a autenticação em dois fatores",
+		Body: fmt.Sprintf("Olá, %s!\n\nAdministradores precisam ativar a autenticação em dois fatores para entrar. "+
+			"Acesse o link abaixo (válido por 15 minutos) para cadastrar o aplicativo autenticador:\n\n%s\n\n"+
+			"Se não foi você que tentou entrar, troque a sua senha.", user.Nome, link),
+	})
+	if err != nil {
+		log.Printf("Erro ao enviar email de cadastro 2FA: %v", err)
+		sendErrorResponse(w, "Erro ao enviar o link de cadastro do 2FA", http.StatusInternalServerError)
+		return
+	}
+
+	sendSuccessResponse(w, map[string]interface{}{
+		"two_factor_setup_required": true,
+		"message":                   "Administradores precisam ativar a autenticação em dois fatores. Enviamos um link para o seu email",
+	})
+}
+
+// resolveTwoFactorUser identifica o usuário pelo access token ou, durante o cadastro
+// obrigatório de 2FA, pelo token de desafio de setup (retornado por SetupTwoFactor)
+func resolveTwoFactorUser(r *http.Request, challengeToken string) (userID int, viaChallenge bool) {
+	if id := GetUserIDFromRequest(r); id != 0 {
+		return id, false
+	}
+	if challengeToken == "" {
+		return 0, false
+	}
+	id, err := services.ParseChallengeToken(challengeToken, services.AudienceTwoFactorSetup)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// SetupTwoFactor gera um novo segredo TOTP (ainda pendente de confirmação) e a URI otpauth://.
+// No cadastro obrigatório o usuário é identificado pelo setup_token do link enviado por email,
+// que é consumido aqui; a resposta traz o challenge_token usado em /auth/2fa/confirm.
+func SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req twoFactorRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendErrorResponse(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	userID := GetUserIDFromRequest(r)
+	viaSetupLink := false
+	if userID == 0 && req.SetupToken != "" {
+		id, err := consumeSetupToken(req.SetupToken)
+		if err == errInvalidUserToken {
+			sendErrorResponse(w, "Link de cadastro do 2FA inválido ou expirado. Faça login novamente", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			sendErrorResponse(w, "Erro ao gerar segredo 2FA", http.StatusInternalServerError)
+			return
+		}
+		userID, viaSetupLink = id, true
+	}
+	if userID == 0 {
+		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
+		return
+	}
+
+	var email string
+	var enabledAt sql.NullTime
+	err := database.DB.QueryRow("SELECT COALESCE(email, ''), totp_enabled_at FROM users WHERE id = $1", userID).Scan(&email, &enabledAt)
+	if err != nil {
+		sendErrorResponse(w, "Usuário não encontrado", http.StatusNotFound)
+		return
+	}
+	if enabledAt.Valid {
+		sendErrorResponse(w, "Autenticação em dois fatores já está ativa", http.StatusConflict)
+		return
+	}
+
+	secret, err := services.GenerateTOTPSecret()
+	if err != nil {
+		sendErrorResponse(w, "Erro ao gerar segredo 2FA", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := database.DB.Exec(`
+		UPDATE users SET totp_secret = $1, totp_last_step = 0
+		WHERE id = $2`, secret, userID); err != nil {
+		sendErrorResponse(w, "Erro ao salvar segredo 2FA", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"secret":      secret,
+		"otpauth_uri": services.TOTPURI(totpIssuer, email, secret),
+		"message":     "Cadastre o segredo no aplicativo autenticador e confirme com um código",
+	}
+	if viaSetupLink {
+		challengeToken, err := services.GenerateChallengeToken(userID, services.AudienceTwoFactorSetup, twoFactorSetupTTL)
+		if err != nil {
+			log.Printf("Erro ao gerar token de desafio 2FA: %v", err)
+			sendErrorResponse(w, "Erro ao gerar token de acesso", http.StatusInternalServerError)
+			return
+		}
+		response["challenge_token"] = challengeToken
+		response["expires_in"] = int(twoFactorSetupTTL.Seconds())
+	}
+	sendSuccessResponse(w, response)
+}
+
+// consumeSetupToken consome o token do link de cadastro obrigatório de 2FA e retorna o usuário
+func consumeSetupToken(token string) (int, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	userID, err := consumeUserToken(tx, token, tokenPurposeTwoFactorSetup)
+	if err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit()
+}
+
+// sendTwoFactorCodeFailure contabiliza um código 2FA inválido na chave de 2FA do usuário, a
+// mesma usada em /auth/2fa/verify, e responde 401 (ou 429 se a conta ficou bloqueada)
+func sendTwoFactorCodeFailure(w http.ResponseWriter, twoFactorKey string) {
+	if lockout := registerLoginFailure(twoFactorKey); lockout > 0 {
+		sendLockoutResponse(w, lockout)
+		return
+	}
+	sendErrorResponse(w, "Código inválido", http.StatusUnauthorized)
+}
+
+// resetTwoFactorFailures zera as falhas de 2FA do usuário após um código correto
+func resetTwoFactorFailures(twoFactorKey string) {
+	if err := loginGuard.Reset(twoFactorKey); err != nil {
+		log.Printf("Erro ao zerar tentativas de 2FA: %v", err)
+	}
+}
+
+// ConfirmTwoFactor ativa o 2FA após validar o primeiro código e retorna os códigos de recuperação.
+// Quando usado com o token de desafio de setup, também conclui o login.
+func ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req twoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		sendErrorResponse(w, "Código é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	userID, viaChallenge := resolveTwoFactorUser(r, req.ChallengeToken)
+	if userID == 0 {
+		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
+		return
+	}
+
+	twoFactorKey := services.TwoFactorLoginKey(userID)
+	if retryAfter := checkLoginLockout(twoFactorKey); retryAfter > 0 {
+		sendLockoutResponse(w, retryAfter)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		sendErrorResponse(w, "Erro ao ativar 2FA", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var secret sql.NullString
+	var enabledAt sql.NullTime
+	var lastStep int64
+	err = tx.QueryRow(`
+		SELECT totp_secret, totp_enabled_at, COALESCE(totp_last_step, 0)
+		FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&secret, &enabledAt, &lastStep)
+	if err != nil {
+		sendErrorResponse(w, "Usuário não encontrado", http.StatusNotFound)
+		return
+	}
+	if enabledAt.Valid {
+		sendErrorResponse(w, "Autenticação em dois fatores já está ativa", http.StatusConflict)
+		return
+	}
+	if !secret.Valid {
+		sendErrorResponse(w, "Inicie o cadastro do 2FA em /api/auth/2fa/setup", http.StatusBadRequest)
+		return
+	}
+
+	step, ok := services.ValidateTOTP(secret.String, req.Code, time.Now(), lastStep)
+	if !ok {
+		sendTwoFactorCodeFailure(w, twoFactorKey)
+		return
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE users SET totp_enabled_at = CURRENT_TIMESTAMP, totp_last_step = $1
+		WHERE id = $2`, step, userID); err != nil {
+		sendErrorResponse(w, "Erro ao ativar 2FA", http.StatusInternalServerError)
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao gerar códigos de recuperação", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "Erro ao ativar 2FA", http.StatusInternalServerError)
+		return
+	}
+	resetTwoFactorFailures(twoFactorKey)
+
+	message := "Autenticação em dois fatores ativada. Guarde os códigos de recuperação em local seguro"
+	if !viaChallenge {
+		sendSuccessResponse(w, map[string]interface{}{
+			"recovery_codes": codes,
+			"message":        message,
+		})
+		return
+	}
+
+	// Cadastro obrigatório durante o login: conclui o login com os tokens da nova sessão
+	user, err := loadUserByID(userID)
+	if err != nil {
+		sendErrorResponse(w, "Usuário não encontrado", http.StatusNotFound)
+		return
+	}
+	tokens, err := createSession(r, user.ID, user.Perfil)
+	if err != nil {
+		log.Printf("Erro ao criar sessão: %v", err)
+		sendErrorResponse(w, "Erro ao gerar token de acesso", http.StatusInternalServerError)
+		return
+	}
+
+	sendSuccessResponse(w, struct {
+		models.AuthResponse
+		RecoveryCodes []string `json:"recovery_codes"`
+		Message       string   `json:"message"`
+	}{
+		AuthResponse:  models.AuthResponse{UserResponse: userResponse(&user), TokenResponse: tokens},
+		RecoveryCodes: codes,
+		Message:       message,
+	})
+}
+
+// VerifyTwoFactor conclui o login de um usuário com 2FA usando um código TOTP ou de recuperação
+func VerifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req twoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendErrorResponse(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	if req.ChallengeToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		sendErrorResponse(w, "challenge_token e code (ou recovery_code) são obrigatórios", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := services.ParseChallengeToken(req.ChallengeToken, services.AudienceTwoFactorLogin)
+	if err != nil {
+		sendErrorResponse(w, "Token de desafio inválido ou expirado. Faça login novamente", http.StatusUnauthorized)
+		return
+	}
+
+	twoFactorKey := services.TwoFactorLoginKey(userID)
+	if retryAfter := checkLoginLockout(twoFactorKey); retryAfter > 0 {
+		sendLockoutResponse(w, retryAfter)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		sendErrorResponse(w, "Erro ao verificar código", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var ok bool
+	if req.RecoveryCode != "" {
+		ok, err = useRecoveryCode(tx, userID, req.RecoveryCode)
+	} else {
+		ok, err = checkTOTPCode(tx, userID, req.Code)
+	}
+	if err != nil {
+		sendErrorResponse(w, "Erro ao verificar código", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		sendTwoFactorCodeFailure(w, twoFactorKey)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "Erro ao verificar código", http.StatusInternalServerError)
+		return
+	}
+	resetTwoFactorFailures(twoFactorKey)
+
+	user, err := loadUserByID(userID)
+	if err != nil {
+		sendErrorResponse(w, "Usuário não encontrado", http.StatusNotFound)
+		return
+	}
+	sendLoginResponse(w, r, &user)
+}
+
+// DisableTwoFactor desativa o 2FA após confirmar a senha e um código válido
+func DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	authUser := GetAuthUser(r)
+	if authUser == nil {
+		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
+		return
+	}
+
+	var req twoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" || req.Code == "" {
+		sendErrorResponse(w, "Password e código são obrigatórios", http.StatusBadRequest)
+		return
+	}
+
+	if authUser.Perfil == models.PERFIL_ADMIN && adminTwoFactorRequired() {
+		sendErrorResponse(w, "Autenticação em dois fatores é obrigatória para administradores", http.StatusForbidden)
+		return
+	}
+
+	twoFactorKey := services.TwoFactorLoginKey(authUser.ID)
+	if retryAfter := checkLoginLockout(twoFactorKey); retryAfter > 0 {
+		sendLockoutResponse(w, retryAfter)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		sendErrorResponse(w, "Erro ao desativar 2FA", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var email, hashedPassword string
+	if err := tx.QueryRow("SELECT COALESCE(email, ''), password FROM users WHERE id = $1", authUser.ID).
+		Scan(&email, &hashedPassword); err != nil {
+		sendErrorResponse(w, "Usuário não encontrado", http.StatusNotFound)
+		return
+	}
+	if !verifyCurrentPassword(w, email, hashedPassword, req.Password, "Senha incorreta") {
+		return
+	}
+
+	ok, err := checkTOTPCode(tx, authUser.ID, req.Code)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao verificar código", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		sendTwoFactorCodeFailure(w, twoFactorKey)
+		return
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL
+		WHERE id = $1`, authUser.ID); err != nil {
+		sendErrorResponse(w, "Erro ao desativar 2FA", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = $1", authUser.ID); err != nil {
+		sendErrorResponse(w, "Erro ao desativar 2FA", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "Erro ao desativar 2FA", http.StatusInternalServerError)
+		return
+	}
+	resetTwoFactorFailures(twoFactorKey)
+
+	sendSuccessResponse(w, map[string]string{"message": "Autenticação em dois fatores desativada"})
+}
+
+// RegenerateRecoveryCodes invalida os códigos de recuperação atuais e gera novos
+func RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserIDFromRequest(r)
+	if userID == 0 {
+		sendErrorResponse(w, "Usuário não autenticado", http.StatusUnauthorized)
+		return
+	}
+
+	var req twoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		sendErrorResponse(w, "Código é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	twoFactorKey := services.TwoFactorLoginKey(userID)
+	if retryAfter := checkLoginLockout(twoFactorKey); retryAfter > 0 {
+		sendLockoutResponse(w, retryAfter)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		sendErrorResponse(w, "Erro ao gerar códigos de recuperação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	ok, err := checkTOTPCode(tx, userID, req.Code)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao verificar código", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		sendTwoFactorCodeFailure(w, twoFactorKey)
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao gerar códigos de recuperação", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "Erro ao gerar códigos de recuperação", http.StatusInternalServerError)
+		return
+	}
+	resetTwoFactorFailures(twoFactorKey)
+
+	sendSuccessResponse(w, map[string]interface{}{
+		"recovery_codes": codes,
+		"message":        "Novos códigos de recuperação gerados",
+	})
+}
+
+// checkTOTPCode valida o código contra o segredo ativo do usuário e registra o intervalo
+// usado, para que o mesmo código não seja aceito duas vezes
+func checkTOTPCode(tx *sql.Tx, userID int, code string) (bool, error) {
+	var secret sql.NullString
+	var enabledAt sql.NullTime
+	var lastStep int64
+	err := tx.QueryRow(`
+		SELECT totp_secret, totp_enabled_at, COALESCE(totp_last_step, 0)
+		FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&secret, &enabledAt, &lastStep)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !secret.Valid || !enabledAt.Valid {
+		return false, nil
+	}
+
+	step, ok := services.ValidateTOTP(secret.String, code, time.Now(), lastStep)
+	if !ok {
+		return false, nil
+	}
+
+	_, err = tx.Exec("UPDATE users SET totp_last_step = $1 WHERE id = $2", step, userID)
+	return err == nil, err
+}
+
+// useRecoveryCode consome um código de recuperação não utilizado do usuário
+func useRecoveryCode(tx *sql.Tx, userID int, code string) (bool, error) {
+	result, err := tx.Exec(`
+		UPDATE recovery_codes SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`,
+		userID, services.HashOpaqueToken(services.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// replaceRecoveryCodes apaga os códigos de recuperação do usuário e gera um novo conjunto
+func replaceRecoveryCodes(tx *sql.Tx, userID int) ([]string, error) {
+	codes, err := services.GenerateRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		if _, err := tx.Exec(`
+			INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`,
+			userID, services.HashOpaqueToken(services.NormalizeRecoveryCode(code))); err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
@@ -1,20 +1,91 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 
 	"smartpicks-backend/internal/database"
 	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/repository"
 )
 
+// userColumns são as colunas lidas por scanUser (a senha nunca é incluída). O CPF vem cifrado
+// e só é decifrado ao montar a resposta; cpf guarda o valor de cadastros ainda não migrados.
+const userColumns = `id, nome, COALESCE(email, '') as email,
+	COALESCE(cpf, '') as cpf, COALESCE(cpf_encrypted, '') as cpf_encrypted,
+	COALESCE(TO_CHAR(data_nascimento, 'YYYY-MM-DD'), '') as data_nascimento,
+	perfil, COALESCE(avatar, '') as avatar, avatar_variants, email_verified_at, deletion_scheduled_for, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser lê uma linha selecionada com userColumns; extra recebe colunas adicionais
+// selecionadas após userColumns
+func scanUser(row rowScanner, user *models.User, extra ...interface{}) error {
+	dest := []interface{}{&user.ID, &user.Nome, &user.Email, &user.CPF, &user.CPFEncrypted,
+		&user.DataNascimento, &user.Perfil, &user.Avatar, &user.AvatarVariants, &user.EmailVerifiedAt,
+		&user.DeletionScheduledFor, &user.CreatedAt, &user.UpdatedAt}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// loadUserByID busca um usuário pelo ID
+func loadUserByID(id int) (models.User, error) {
+	var user models.User
+	err := scanUser(database.DB.QueryRow(`
+		SELECT `+userColumns+`
+		FROM users WHERE id = $1`, id), &user)
+	return user, err
+}
+
+// decryptUserCPF preenche user.CPF a partir do valor cifrado. Em caso de erro o CPF fica vazio.
+func decryptUserCPF(user *models.User) {
+	if user.CPFEncrypted == "" {
+		return
+	}
+	cpf, err := repository.DecryptCPF(user.CPFEncrypted)
+	if err != nil {
+		log.Printf("Erro ao decifrar CPF do usuário %d: %v", user.ID, err)
+	}
+	user.CPF = cpf
+	user.CPFEncrypted = ""
+}
+
+// userResponse monta a projeção privada do usuário
+func userResponse(user *models.User) models.UserResponse {
+	decryptUserCPF(user)
+	return user.ToResponse()
+}
+
+// publicUserResponse monta a projeção pública do usuário
+func publicUserResponse(user *models.User) models.PublicUserResponse {
+	decryptUserCPF(user)
+	return user.ToPublicResponse()
+}
+
+// canViewPrivateUser indica se quem faz a requisição pode ver os dados pessoais do usuário:
+// o próprio usuário ou quem tem a permissão users:read_private
+func canViewPrivateUser(r *http.Request, userID int) bool {
+	authUser := GetAuthUser(r)
+	if authUser == nil {
+		return false
+	}
+	return authUser.ID == userID || authUser.HasPermission(models.PERMISSION_USERS_READ_PRIVATE)
+}
+
+// userProjection escolhe a projeção (privada ou pública) do usuário conforme quem faz a requisição
+func userProjection(r *http.Request, user *models.User) interface{} {
+	if canViewPrivateUser(r, user.ID) {
+		return userResponse(user)
+	}
+	return publicUserResponse(user)
+}
+
 func GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	rows, err := database.DB.Query(`
-		SELECT id, nome, email, cpf,
-			   TO_CHAR(data_nascimento, 'YYYY-MM-DD') as data_nascimento,
-			   perfil, COALESCE(avatar, '') as avatar,
-			   created_at,
-			   updated_at
+		SELECT ` + userColumns + `
 		FROM users
+		WHERE anonymized_at IS NULL
 		ORDER BY created_at DESC`)
 	if err != nil {
 		sendErrorResponse(w, "Erro ao buscar usuários", http.StatusInternalServerError)
@@ -22,18 +93,16 @@ func GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	}
 	defer rows.Close()
 
-	var users []models.UserResponse
+	var users []interface{}
 	for rows.Next() {
 		var user models.User
-		err := rows.Scan(&user.ID, &user.Nome, &user.Email, &user.CPF,
-			&user.DataNascimento, &user.Perfil, &user.Avatar,
-			&user.CreatedAt, &user.UpdatedAt)
+		err := scanUser(rows, &user)
 		if err != nil {
 			sendErrorResponse(w, "Erro ao processar dados dos usuários", http.StatusInternalServerError)
 			return
 		}
 
-		users = append(users, user.ToResponse())
+		users = append(users, userProjection(r, &user))
 	}
 
 	sendSuccessResponse(w, map[string]interface{}{
@@ -50,18 +119,14 @@ func GetUserByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var user models.User
-	err := database.DB.QueryRow(`
-		SELECT id, nome, email, cpf,
-			   TO_CHAR(data_nascimento, 'YYYY-MM-DD') as data_nascimento,
-			   perfil, COALESCE(avatar, '') as avatar, created_at, updated_at 
-		FROM users WHERE id = $1`, id).
-		Scan(&user.ID, &user.Nome, &user.Email, &user.CPF,
-			&user.DataNascimento, &user.Perfil, &user.Avatar, &user.CreatedAt, &user.UpdatedAt)
+	err := scanUser(database.DB.QueryRow(`
+		SELECT `+userColumns+`
+		FROM users WHERE id = $1`, id), &user)
 	if err != nil {
 		sendErrorResponse(w, "Usuário não encontrado", http.StatusNotFound)
 		return
 	}
-	sendSuccessResponse(w, user.ToResponse())
+	sendSuccessResponse(w, userProjection(r, &user))
 }
 
 func CheckUserPermissions(w http.ResponseWriter, r *http.Request) {
@@ -73,19 +138,26 @@ func CheckUserPermissions(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var user models.User
-	err := database.DB.QueryRow(`
-		SELECT id, nome, email, cpf,
-			   TO_CHAR(data_nascimento, 'YYYY-MM-DD') as data_nascimento,
-			   perfil, COALESCE(avatar, '') as avatar, created_at, updated_at 
-		FROM users WHERE email = $1`, email).
-		Scan(&user.ID, &user.Nome, &user.Email, &user.CPF,
-			&user.DataNascimento, &user.Perfil, &user.Avatar, &user.CreatedAt, &user.UpdatedAt)
+	err := scanUser(database.DB.QueryRow(`
+		SELECT `+userColumns+`
+		FROM users WHERE email = $1`, email), &user)
 	if err != nil {
 		sendErrorResponse(w, "Usuário não encontrado", http.StatusNotFound)
 		return
 	}
 
-	sendSuccessResponse(w, user.ToResponse())
+	if !canViewPrivateUser(r, user.ID) {
+		sendSuccessResponse(w, models.PublicUserPermissionsResponse{
+			PublicUserResponse: publicUserResponse(&user),
+			Permissions:        models.PermissionsFor(user.Perfil),
+		})
+		return
+	}
+
+	sendSuccessResponse(w, models.UserPermissionsResponse{
+		UserResponse: userResponse(&user),
+		Permissions:  models.PermissionsFor(user.Perfil),
+	})
 }
 
 func GetUsersByProfile(w http.ResponseWriter, r *http.Request) {
@@ -96,16 +168,14 @@ func GetUsersByProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !models.IsValidPerfil(profile) {
-		sendErrorResponse(w, "Perfil inválido. Use 'admin' ou 'user'", http.StatusBadRequest)
+		sendErrorResponse(w, invalidPerfilMessage(), http.StatusBadRequest)
 		return
 	}
 
 	rows, err := database.DB.Query(`
-		SELECT id, nome, email, cpf,
-			   TO_CHAR(data_nascimento, 'YYYY-MM-DD') as data_nascimento,
-			   perfil, COALESCE(avatar, '') as avatar, created_at, updated_at
+		SELECT `+userColumns+`
 		FROM users 
-		WHERE perfil = $1
+		WHERE perfil = $1 AND anonymized_at IS NULL
 		ORDER BY created_at DESC`, profile)
 	if err != nil {
 		sendErrorResponse(w, "Erro ao buscar usuários por perfil", http.StatusInternalServerError)
@@ -113,18 +183,17 @@ func GetUsersByProfile(w http.ResponseWriter, r *http.Request) {
 	}
 	defer rows.Close()
 
-	var users []models.UserResponse
+	var users []interface{}
 	for rows.Next() {
 		var user models.User
 
-		err := rows.Scan(&user.ID, &user.Nome, &user.Email, &user.CPF,
-			&user.DataNascimento, &user.Perfil, &user.Avatar, &user.CreatedAt, &user.UpdatedAt)
+		err := scanUser(rows, &user)
 		if err != nil {
 			sendErrorResponse(w, "Erro ao processar dados dos usuários", http.StatusInternalServerError)
 			return
 		}
 
-		users = append(users, user.ToResponse())
+		users = append(users, userProjection(r, &user))
 	}
 
 	sendSuccessResponse(w, map[string]interface{}{
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/repository"
+	"smartpicks-backend/internal/services"
+)
+
+// maxWebhookBodySize limita o corpo dos webhooks recebidos
+const maxWebhookBodySize = 64 << 10
+
+var scoresWebhookSigner *services.WebhookSigner
+
+// SetScoresWebhookSigner define o assinador do webhook de placares. Sem ele o webhook fica desativado.
+func SetScoresWebhookSigner(s *services.WebhookSigner) {
+	scoresWebhookSigner = s
+}
+
+// ScoresWebhook recebe atualizações de placar e status do feed externo, assinadas com HMAC no
+// header X-Scores-Signature. É idempotente pelo event_id. Quando a partida termina (ou é
+// cancelada) os palpites ligados a ela são apurados.
+func ScoresWebhook(w http.ResponseWriter, r *http.Request) {
+	if scoresWebhookSigner == nil {
+		sendErrorResponse(w, "Webhook de placares não configurado", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+	if err != nil {
+		sendErrorResponse(w, "Corpo da requisição muito grande", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err := scoresWebhookSigner.Verify(r.Header.Get(services.WebhookSignatureHeader), body, time.Now()); err != nil {
+		sendErrorResponse(w, "Assinatura do webhook inválida: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var ev repository.ScoreEvent
+	if err := json.Unmarshal(body, &ev); err != nil {
+		sendErrorResponse(w, "JSON inválido (occurred_at deve estar em RFC3339)", http.StatusBadRequest)
+		return
+	}
+	ev.EventID = strings.TrimSpace(ev.EventID)
+	if ev.OccurredAt.IsZero() {
+		ev.OccurredAt = time.Now()
+	}
+	ev.OccurredAt = ev.OccurredAt.UTC()
+
+	switch {
+	case ev.EventID == "" || len(ev.EventID) > 100:
+		sendErrorResponse(w, "event_id é obrigatório (até 100 caracteres)", http.StatusBadRequest)
+		return
+	case ev.MatchID <= 0:
+		sendErrorResponse(w, "match_id é obrigatório", http.StatusBadRequest)
+		return
+	case !models.IsValidMatchStatus(ev.Status):
+		sendErrorResponse(w, "status inválido. Use: "+strings.Join(models.ValidMatchStatuses, ", "), http.StatusBadRequest)
+		return
+	case (ev.HomeScore != nil && *ev.HomeScore < 0) || (ev.AwayScore != nil && *ev.AwayScore < 0):
+		sendErrorResponse(w, "O placar não pode ser negativo", http.StatusBadRequest)
+		return
+	case ev.Status == models.MATCH_STATUS_FINISHED && (ev.HomeScore == nil || ev.AwayScore == nil):
+		sendErrorResponse(w, "home_score e away_score são obrigatórios em partidas finalizadas", http.StatusBadRequest)
+		return
+	}
+
+	// O evento e a apuração que ele dispara são gravados juntos: em caso de erro nada fica
+	// registrado e o feed pode reenviar o evento
+	result, err := repository.ApplyScoreEvent(ev, body)
+	switch {
+	case errors.Is(err, repository.ErrMatchNotFound):
+		sendErrorResponse(w, "Partida não encontrada", http.StatusNotFound)
+		return
+	case errors.Is(err, repository.ErrMatchNotStarted):
+		sendErrorResponse(w, "A partida ainda não começou; confira a data cadastrada", http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("Erro ao aplicar evento de placar %s: %v", ev.EventID, err)
+		sendErrorResponse(w, "Erro ao aplicar evento", http.StatusInternalServerError)
+		return
+	}
+	if result.Duplicate {
+		sendSuccessResponse(w, map[string]interface{}{"event_id": ev.EventID, "duplicate": true})
+		return
+	}
+
+	match, err := repository.GetMatch(ev.MatchID)
+	if err != nil {
+		sendErrorResponse(w, "Erro ao buscar partida", http.StatusInternalServerError)
+		return
+	}
+	sendSuccessResponse(w, map[string]interface{}{
+		"event_id":   ev.EventID,
+		"duplicate":  false,
+		"stale":      result.Stale,
+		"match":      match,
+		"settlement": result.Settlement,
+	})
+}
@@ -0,0 +1,121 @@
+package imaging
+
+import (
+	"image"
+	"math"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// Componentes usados no blurhash: 4 na horizontal e 3 na vertical (hash de 28 caracteres)
+const (
+	blurhashComponentsX = 4
+	blurhashComponentsY = 3
+	blurhashSampleSize  = 32
+)
+
+// Blurhash calcula o placeholder borrado da imagem (https://blurha.sh), exibido pelos clientes
+// enquanto a imagem carrega
+func Blurhash(img image.Image) string {
+	// O hash só guarda as frequências baixas; calcular sobre uma miniatura dá o mesmo resultado
+	b := img.Bounds()
+	w, h := blurhashSampleSize, blurhashSampleSize*b.Dy()/b.Dx()
+	if b.Dy() > b.Dx() {
+		w, h = blurhashSampleSize*b.Dx()/b.Dy(), blurhashSampleSize
+	}
+	w, h = max(w, 1), max(h, 1)
+	small := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.ApproxBiLinear.Scale(small, small.Bounds(), img, b, draw.Src, nil)
+
+	factors := make([][3]float64, 0, blurhashComponentsX*blurhashComponentsY)
+	for j := 0; j < blurhashComponentsY; j++ {
+		for i := 0; i < blurhashComponentsX; i++ {
+			factors = append(factors, blurhashFactor(small, i, j))
+		}
+	}
+
+	var hash strings.Builder
+	hash.WriteString(encode83((blurhashComponentsX-1)+(blurhashComponentsY-1)*9, 1))
+
+	maxValue := 1.0
+	ac := factors[1:]
+	if len(ac) > 0 {
+		actualMax := 0.0
+		for _, f := range ac {
+			actualMax = math.Max(actualMax, math.Max(math.Abs(f[0]), math.Max(math.Abs(f[1]), math.Abs(f[2]))))
+		}
+		quantisedMax := int(math.Max(0, math.Min(82, math.Floor(actualMax*166-0.5))))
+		maxValue = float64(quantisedMax+1) / 166
+		hash.WriteString(encode83(quantisedMax, 1))
+	} else {
+		hash.WriteString(encode83(0, 1))
+	}
+
+	dc := factors[0]
+	hash.WriteString(encode83(linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4))
+	for _, f := range ac {
+		hash.WriteString(encode83(encodeAC(f, maxValue), 2))
+	}
+	return hash.String()
+}
+
+func blurhashFactor(img *image.RGBA, i, j int) [3]float64 {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	normalisation := 2.0
+	if i == 0 && j == 0 {
+		normalisation = 1
+	}
+
+	var r, g, b float64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			basis := normalisation * math.Cos(math.Pi*float64(i)*float64(x)/float64(w)) *
+				math.Cos(math.Pi*float64(j)*float64(y)/float64(h))
+			offset := img.PixOffset(x, y)
+			r += basis * sRGBToLinear(img.Pix[offset])
+			g += basis * sRGBToLinear(img.Pix[offset+1])
+			b += basis * sRGBToLinear(img.Pix[offset+2])
+		}
+	}
+	scale := 1 / float64(w*h)
+	return [3]float64{r * scale, g * scale, b * scale}
+}
+
+func encodeAC(f [3]float64, maxValue float64) int {
+	quant := func(v float64) int {
+		return int(math.Max(0, math.Min(18, math.Floor(signPow(v/maxValue, 0.5)*9+9.5))))
+	}
+	return quant(f[0])*19*19 + quant(f[1])*19 + quant(f[2])
+}
+
+func encode83(value, length int) string {
+	result := make([]byte, length)
+	for i := 1; i <= length; i++ {
+		digit := (value / int(math.Pow(83, float64(length-i)))) % 83
+		result[i-1] = base83Chars[digit]
+	}
+	return string(result)
+}
+
+func sRGBToLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
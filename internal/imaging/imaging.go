@@ -0,0 +1,119 @@
+// Package imaging decodifica, valida e redimensiona as imagens enviadas pelos usuários
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+
+	// Decoders registrados para image.Decode
+	_ "image/gif"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// AllowedContentTypes são os tipos aceitos, identificados pelo conteúdo (http.DetectContentType)
+var AllowedContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// MaxPixels limita o tamanho da imagem decodificada (evita estourar a memória com imagens gigantes)
+const MaxPixels = 40_000_000
+
+var ErrUnsupportedType = errors.New("tipo de imagem não suportado. Use JPEG, PNG, GIF ou WebP")
+
+// Decode identifica o tipo real da imagem pelo conteúdo e a decodifica, já aplicando a rotação
+// indicada no EXIF. Os metadados (EXIF, GPS) não são preservados nas versões geradas a partir dela.
+func Decode(data []byte) (image.Image, string, error) {
+	contentType := http.DetectContentType(data)
+	if !AllowedContentTypes[contentType] {
+		return nil, contentType, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, contentType, fmt.Errorf("imagem inválida: %v", err)
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, contentType, fmt.Errorf("imagem muito grande: %dx%d pixels", cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, contentType, fmt.Errorf("imagem inválida: %v", err)
+	}
+	if contentType == "image/jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+	return img, contentType, nil
+}
+
+// SquareThumbnail recorta o centro da imagem em um quadrado e o redimensiona para size x size
+func SquareThumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	x0 := bounds.Min.X + (bounds.Dx()-side)/2
+	y0 := bounds.Min.Y + (bounds.Dy()-side)/2
+	crop := image.Rect(x0, y0, x0+side, y0+side)
+
+	return scale(img, crop, size, size)
+}
+
+// Fit reduz a imagem para no máximo maxWidth pixels de largura, mantendo a proporção.
+// Imagens menores não são ampliadas.
+func Fit(img image.Image, maxWidth int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > maxWidth {
+		height = max(height*maxWidth/width, 1)
+		width = maxWidth
+	}
+	return scale(img, bounds, width, height)
+}
+
+func scale(img image.Image, src image.Rectangle, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	// Fundo branco para imagens com transparência, já que o JPEG não tem canal alfa
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Over, nil)
+	return dst
+}
+
+// EncodeJPEG codifica a imagem em JPEG com a qualidade informada (1-100)
+func EncodeJPEG(img image.Image, quality int) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DHash calcula o hash perceptual (difference hash) de 64 bits da imagem. Imagens visualmente
+// iguais (mesmo print recomprimido ou redimensionado) têm hashes com pouca distância de Hamming.
+func DHash(img image.Image) uint64 {
+	small := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.ApproxBiLinear.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if small.GrayAt(x, y).Y > small.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
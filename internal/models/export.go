@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Status de um export de dados (LGPD)
+const (
+	EXPORT_STATUS_PENDING    = "pending"
+	EXPORT_STATUS_PROCESSING = "processing"
+	EXPORT_STATUS_READY      = "ready"
+	EXPORT_STATUS_FAILED     = "failed"
+)
+
+// DataExport representa um pedido de exportação dos dados do usuário
+type DataExport struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	Status      string     `json:"status"`
+	Error       *string    `json:"error,omitempty"`
+	SizeBytes   *int64     `json:"size_bytes,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+}
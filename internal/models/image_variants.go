@@ -0,0 +1,50 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// ImageVariants guarda a URL de cada versão gerada de uma imagem, indexada pelo nome da versão
+// (avatares: "64", "128", "512"; palpites: "thumb", "medium", "full")
+type ImageVariants map[string]string
+
+// Scan lê uma coluna JSONB com as variantes
+func (v *ImageVariants) Scan(src interface{}) error {
+	if src == nil {
+		*v = nil
+		return nil
+	}
+	var data []byte
+	switch value := src.(type) {
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return fmt.Errorf("variantes de imagem: tipo %T não suportado", src)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Value grava as variantes como JSON (NULL quando não há variantes)
+func (v ImageVariants) Value() (driver.Value, error) {
+	if len(v) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// URLs retorna todas as URLs das variantes
+func (v ImageVariants) URLs() []string {
+	urls := make([]string, 0, len(v))
+	for _, url := range v {
+		urls = append(urls, url)
+	}
+	return urls
+}
@@ -2,10 +2,76 @@ package models
 
 import "time"
 
+// Status de uma partida
+const (
+	MATCH_STATUS_SCHEDULED = "scheduled"
+	MATCH_STATUS_LIVE      = "live"
+	MATCH_STATUS_FINISHED  = "finished"
+	MATCH_STATUS_POSTPONED = "postponed"
+	MATCH_STATUS_CANCELLED = "cancelled"
+)
+
+var ValidMatchStatuses = []string{
+	MATCH_STATUS_SCHEDULED,
+	MATCH_STATUS_LIVE,
+	MATCH_STATUS_FINISHED,
+	MATCH_STATUS_POSTPONED,
+	MATCH_STATUS_CANCELLED,
+}
+
+// Match é uma partida. TeamA é o mandante e TeamB o visitante; MatchDate é o horário de início
+// em UTC e Location o estádio.
 type Match struct {
+	ID            int        `json:"id"`
+	CompetitionID *int       `json:"competition_id,omitempty"`
+	Competition   *string    `json:"competition,omitempty"`
+	HomeTeamID    *int       `json:"home_team_id,omitempty"`
+	AwayTeamID    *int       `json:"away_team_id,omitempty"`
+	TeamA         string     `json:"team_a"`
+	TeamB         string     `json:"team_b"`
+	MatchDate     time.Time  `json:"match_date"`
+	Location      string     `json:"location"`
+	Status        string     `json:"status"`
+	HomeScore     *int       `json:"home_score,omitempty"`
+	AwayScore     *int       `json:"away_score,omitempty"`
+	SettledAt     *time.Time `json:"settled_at,omitempty"`
+}
+
+// Competition é um campeonato (ex: Brasileirão Série A)
+type Competition struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Code      *string   `json:"code,omitempty"`
+	Country   *string   `json:"country,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Team é um time, referenciado pelas partidas como mandante ou visitante
+type Team struct {
 	ID        int       `json:"id"`
-	TeamA     string    `json:"team_a"`
-	TeamB     string    `json:"team_b"`
-	MatchDate time.Time `json:"match_date"`
-	Location  string    `json:"location"`
+	Name      string    `json:"name"`
+	ShortName *string   `json:"short_name,omitempty"`
+	Country   *string   `json:"country,omitempty"`
+	Aliases   []string  `json:"aliases,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func IsValidMatchStatus(status string) bool {
+	for _, validStatus := range ValidMatchStatuses {
+		if status == validStatus {
+			return true
+		}
+	}
+	return false
+}
+
+// ClosedForPicks indica se a partida não aceita mais palpites no instante now (relógio do
+// servidor): depois do início mais a tolerância grace, ou quando já está ao vivo, encerrada
+// ou cancelada, mesmo que o horário cadastrado ainda não tenha chegado.
+func (m *Match) ClosedForPicks(now time.Time, grace time.Duration) bool {
+	switch m.Status {
+	case MATCH_STATUS_LIVE, MATCH_STATUS_FINISHED, MATCH_STATUS_CANCELLED:
+		return true
+	}
+	return !now.Before(m.MatchDate.UTC().Add(grace))
 }
@@ -2,11 +2,28 @@ package models
 
 import "time"
 
+// Palpite é um palpite publicado. ImgVariants tem as versões redimensionadas da imagem
+// (thumb, medium, full) e ImgURL aponta para a full. A imagem é opcional quando o palpite
+// tem Selections.
 type Palpite struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	Titulo    *string   `json:"titulo,omitempty"`
-	ImgURL    string    `json:"img_url"`
+	ID          int           `json:"id"`
+	UserID      int           `json:"user_id"`
+	Titulo      *string       `json:"titulo,omitempty"`
+	ImgURL      string        `json:"img_url"`
+	ImgVariants ImageVariants `json:"img_variants,omitempty"`
+	ImgWidth    *int          `json:"img_width,omitempty"`
+	ImgHeight   *int          `json:"img_height,omitempty"`
+	ImgBlurhash *string       `json:"img_blurhash,omitempty"`
+	ImgPHash    *int64        `json:"-"`
+	// DuplicateOf aponta para o palpite original quando a imagem é uma cópia (hash perceptual parecido)
+	DuplicateOf       *int               `json:"duplicate_of,omitempty"`
+	DuplicateDistance *int               `json:"duplicate_distance,omitempty"`
+	Selections        []PalpiteSelection `json:"selections,omitempty"`
+	// Late indica que alguma seleção foi postada perto do início da partida
+	Late bool `json:"late"`
+	// Status e Profit (em unidades) resumem a apuração das seleções; ficam nulos sem seleções
+	Status    *string   `json:"status,omitempty"`
+	Profit    *float64  `json:"profit,omitempty"`
 	Avatar    *string   `json:"avatar,omitempty"`
 	Link      *string   `json:"link,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
@@ -14,34 +31,53 @@ type Palpite struct {
 }
 
 type PalpiteResponse struct {
-	ID               int               `json:"id"`
-	UserID           int               `json:"user_id"`
-	UserName         string            `json:"user_name"`
-	Titulo           *string           `json:"titulo,omitempty"`
-	ImgURL           string            `json:"img_url"`
-	Avatar           *string           `json:"avatar,omitempty"`
-	Link             *string           `json:"link,omitempty"`
-	CreatedAt        time.Time         `json:"created_at"`
-	UpdatedAt        time.Time         `json:"updated_at"`
-	TotalLikes       int               `json:"total_likes"`
-	TotalDislikes    int               `json:"total_dislikes"`
-	TotalComentarios int               `json:"total_comentarios"`
-	Comentarios      []ComentarioStats `json:"comentarios,omitempty"`
+	ID               int                `json:"id"`
+	UserID           int                `json:"user_id"`
+	UserName         string             `json:"user_name"`
+	UserVerified     bool               `json:"user_verified"`
+	Titulo           *string            `json:"titulo,omitempty"`
+	ImgURL           string             `json:"img_url"`
+	ImgVariants      ImageVariants      `json:"img_variants,omitempty"`
+	ImgWidth         *int               `json:"img_width,omitempty"`
+	ImgHeight        *int               `json:"img_height,omitempty"`
+	ImgBlurhash      *string            `json:"img_blurhash,omitempty"`
+	DuplicateOf      *int               `json:"duplicate_of,omitempty"`
+	Avatar           *string            `json:"avatar,omitempty"`
+	Link             *string            `json:"link,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+	TotalLikes       int                `json:"total_likes"`
+	TotalDislikes    int                `json:"total_dislikes"`
+	TotalComentarios int                `json:"total_comentarios"`
+	Comentarios      []ComentarioStats  `json:"comentarios,omitempty"`
+	Selections       []PalpiteSelection `json:"selections,omitempty"`
+	Late             bool               `json:"late"`
+	Status           *string            `json:"status,omitempty"`
+	Profit           *float64           `json:"profit,omitempty"`
 }
 type PalpiteStats struct {
-	ID               int       `json:"id"`
-	UserID           int       `json:"user_id"`
-	Titulo           *string   `json:"titulo,omitempty"`
-	ImgURL           string    `json:"img_url"`
-	Link             *string   `json:"link,omitempty"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
-	TotalLikes       int       `json:"total_likes"`
-	TotalDislikes    int       `json:"total_dislikes"`
-	TotalComentarios int       `json:"total_comentarios"`
-	AutorNome        string    `json:"autor_nome"`
-	AutorAvatar      *string   `json:"autor_avatar,omitempty"`
-	UserReaction     *string   `json:"user_reaction,omitempty"`
+	ID               int                `json:"id"`
+	UserID           int                `json:"user_id"`
+	Titulo           *string            `json:"titulo,omitempty"`
+	ImgURL           string             `json:"img_url"`
+	ImgVariants      ImageVariants      `json:"img_variants,omitempty"`
+	ImgWidth         *int               `json:"img_width,omitempty"`
+	ImgHeight        *int               `json:"img_height,omitempty"`
+	ImgBlurhash      *string            `json:"img_blurhash,omitempty"`
+	Link             *string            `json:"link,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+	TotalLikes       int                `json:"total_likes"`
+	TotalDislikes    int                `json:"total_dislikes"`
+	TotalComentarios int                `json:"total_comentarios"`
+	AutorNome        string             `json:"autor_nome"`
+	AutorVerificado  bool               `json:"autor_verificado"`
+	AutorAvatar      *string            `json:"autor_avatar,omitempty"`
+	UserReaction     *string            `json:"user_reaction,omitempty"`
+	Selections       []PalpiteSelection `json:"selections,omitempty"`
+	Late             bool               `json:"late"`
+	Status           *string            `json:"status,omitempty"`
+	Profit           *float64           `json:"profit,omitempty"`
 }
 
 func (p *Palpite) ToResponse() PalpiteResponse {
@@ -50,6 +86,15 @@ func (p *Palpite) ToResponse() PalpiteResponse {
 		UserID:           p.UserID,
 		Titulo:           p.Titulo,
 		ImgURL:           p.ImgURL,
+		ImgVariants:      p.ImgVariants,
+		ImgWidth:         p.ImgWidth,
+		ImgHeight:        p.ImgHeight,
+		ImgBlurhash:      p.ImgBlurhash,
+		DuplicateOf:      p.DuplicateOf,
+		Selections:       p.Selections,
+		Late:             HasLateSelection(p.Selections),
+		Status:           p.Status,
+		Profit:           p.Profit,
 		Avatar:           p.Avatar,
 		Link:             p.Link,
 		CreatedAt:        p.CreatedAt,
@@ -0,0 +1,67 @@
+package models
+
+// Permissões (ações) concedidas aos perfis
+const (
+	PERMISSION_PALPITES_CREATE    = "palpites:create"
+	PERMISSION_PALPITES_VERIFIED  = "palpites:verified"
+	PERMISSION_PALPITES_MODERATE  = "palpites:moderate"
+	PERMISSION_PALPITES_SETTLE    = "palpites:settle"
+	PERMISSION_MATCHES_MANAGE     = "matches:manage"
+	PERMISSION_COMENTARIOS_CREATE = "comentarios:create"
+	PERMISSION_COMENTARIOS_DELETE = "comentarios:delete_any"
+	PERMISSION_USERS_UNLOCK       = "users:unlock"
+	PERMISSION_USERS_EDIT_ANY     = "users:edit_any"
+	PERMISSION_USERS_READ_PRIVATE = "users:read_private"
+	PERMISSION_USERS_MANAGE_ROLES = "users:manage_roles"
+	PERMISSION_USERS_DELETE       = "users:delete"
+)
+
+var basePermissions = []string{
+	PERMISSION_PALPITES_CREATE,
+	PERMISSION_COMENTARIOS_CREATE,
+}
+
+// RolePermissions mapeia cada perfil para as ações que ele pode executar
+var RolePermissions = map[string][]string{
+	PERFIL_USER: basePermissions,
+	PERFIL_TIPSTER: append(append([]string{}, basePermissions...),
+		PERMISSION_PALPITES_VERIFIED,
+	),
+	PERFIL_MODERATOR: append(append([]string{}, basePermissions...),
+		PERMISSION_PALPITES_MODERATE,
+		PERMISSION_COMENTARIOS_DELETE,
+		PERMISSION_USERS_UNLOCK,
+		PERMISSION_USERS_EDIT_ANY,
+	),
+	PERFIL_ADMIN: append(append([]string{}, basePermissions...),
+		PERMISSION_PALPITES_VERIFIED,
+		PERMISSION_PALPITES_MODERATE,
+		PERMISSION_PALPITES_SETTLE,
+		PERMISSION_MATCHES_MANAGE,
+		PERMISSION_COMENTARIOS_DELETE,
+		PERMISSION_USERS_UNLOCK,
+		PERMISSION_USERS_EDIT_ANY,
+		PERMISSION_USERS_READ_PRIVATE,
+		PERMISSION_USERS_MANAGE_ROLES,
+		PERMISSION_USERS_DELETE,
+	),
+}
+
+// PermissionsFor retorna as permissões efetivas do perfil (vazio para perfis desconhecidos)
+func PermissionsFor(perfil string) []string {
+	permissions := RolePermissions[perfil]
+	if permissions == nil {
+		return []string{}
+	}
+	return append([]string{}, permissions...)
+}
+
+// HasPermission indica se o perfil pode executar a ação
+func HasPermission(perfil, permission string) bool {
+	for _, p := range RolePermissions[perfil] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
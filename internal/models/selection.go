@@ -0,0 +1,193 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mercados aceitos nas seleções dos palpites
+const (
+	MARKET_1X2           = "1x2"
+	MARKET_OVER_UNDER    = "over_under"
+	MARKET_BTTS          = "btts"
+	MARKET_HANDICAP      = "handicap"
+	MARKET_CORRECT_SCORE = "correct_score"
+)
+
+// Seleções de cada mercado. No handicap a linha vale para o time escolhido (ex: away -0.75).
+const (
+	SELECTION_HOME  = "home"
+	SELECTION_DRAW  = "draw"
+	SELECTION_AWAY  = "away"
+	SELECTION_OVER  = "over"
+	SELECTION_UNDER = "under"
+	SELECTION_YES   = "yes"
+	SELECTION_NO    = "no"
+)
+
+// Resultado de uma seleção (e do palpite) depois da apuração. Nas linhas de quarto do handicap
+// asiático e do over/under metade da stake pode ganhar ou perder e a outra metade ser devolvida.
+const (
+	PICK_STATUS_PENDING   = "pending"
+	PICK_STATUS_WON       = "won"
+	PICK_STATUS_HALF_WON  = "half_won"
+	PICK_STATUS_VOID      = "void"
+	PICK_STATUS_HALF_LOST = "half_lost"
+	PICK_STATUS_LOST      = "lost"
+)
+
+// Limites das seleções de um palpite
+const (
+	MAX_SELECTIONS_PER_PALPITE = 10
+	MIN_SELECTION_ODDS         = 1.01
+	MAX_SELECTION_ODDS         = 1000
+	DEFAULT_SELECTION_STAKE    = 1
+	MAX_SELECTION_STAKE        = 10
+)
+
+var ValidMarkets = []string{MARKET_1X2, MARKET_OVER_UNDER, MARKET_BTTS, MARKET_HANDICAP, MARKET_CORRECT_SCORE}
+
+var marketSelections = map[string][]string{
+	MARKET_1X2:        {SELECTION_HOME, SELECTION_DRAW, SELECTION_AWAY},
+	MARKET_OVER_UNDER: {SELECTION_OVER, SELECTION_UNDER},
+	MARKET_BTTS:       {SELECTION_YES, SELECTION_NO},
+	MARKET_HANDICAP:   {SELECTION_HOME, SELECTION_AWAY},
+}
+
+// selectionAliases aceita as formas usuais das casas de aposta (1/X/2, sim/não)
+var selectionAliases = map[string]string{
+	"1":   SELECTION_HOME,
+	"x":   SELECTION_DRAW,
+	"2":   SELECTION_AWAY,
+	"sim": SELECTION_YES,
+	"nao": SELECTION_NO,
+	"não": SELECTION_NO,
+}
+
+var correctScorePattern = regexp.MustCompile(`^(\d{1,2})-(\d{1,2})$`)
+
+// PalpiteSelection é uma aposta simples de um palpite: mercado e seleção em uma partida, com a
+// odd decimal e a stake em unidades. Cada seleção é avaliada separadamente.
+type PalpiteSelection struct {
+	ID        int        `json:"id"`
+	PalpiteID int        `json:"palpite_id"`
+	MatchID   int        `json:"match_id"`
+	Market    string     `json:"market"`
+	Selection string     `json:"selection"`
+	Line      *float64   `json:"line,omitempty"`
+	Odds      float64    `json:"odds"`
+	Stake     float64    `json:"stake"`
+	Status    string     `json:"status"`
+	Profit    *float64   `json:"profit,omitempty"`
+	SettledAt *time.Time `json:"settled_at,omitempty"`
+	// Late marca seleções postadas perto do início da partida; MinutesBeforeKickoff é negativo
+	// quando a seleção entrou na tolerância depois do início
+	Late                 bool      `json:"late"`
+	MinutesBeforeKickoff *int      `json:"minutes_before_kickoff,omitempty"`
+	Match                *Match    `json:"match,omitempty"`
+	CreatedAt            time.Time `json:"created_at"`
+}
+
+// HasLateSelection indica se alguma das seleções foi postada perto do início da partida
+func HasLateSelection(selections []PalpiteSelection) bool {
+	for _, s := range selections {
+		if s.Late {
+			return true
+		}
+	}
+	return false
+}
+
+func IsValidMarket(market string) bool {
+	for _, validMarket := range ValidMarkets {
+		if market == validMarket {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize padroniza a seleção (minúsculas, apelidos, stake padrão) e valida mercado, seleção,
+// linha, odd e stake
+func (s *PalpiteSelection) Normalize() error {
+	if s.MatchID <= 0 {
+		return errors.New("match_id é obrigatório")
+	}
+
+	s.Market = strings.ToLower(strings.TrimSpace(s.Market))
+	if !IsValidMarket(s.Market) {
+		return fmt.Errorf("market inválido. Use: %s", strings.Join(ValidMarkets, ", "))
+	}
+
+	s.Selection = strings.ToLower(strings.TrimSpace(s.Selection))
+	if alias, ok := selectionAliases[s.Selection]; ok {
+		s.Selection = alias
+	}
+
+	if s.Market == MARKET_CORRECT_SCORE {
+		m := correctScorePattern.FindStringSubmatch(strings.ReplaceAll(s.Selection, " ", ""))
+		if m == nil {
+			return errors.New("selection do placar exato deve estar no formato casa-fora (ex: 2-1)")
+		}
+		home, _ := strconv.Atoi(m[1])
+		away, _ := strconv.Atoi(m[2])
+		s.Selection = fmt.Sprintf("%d-%d", home, away)
+	} else if !contains(marketSelections[s.Market], s.Selection) {
+		return fmt.Errorf("selection inválida para o mercado %s. Use: %s", s.Market, strings.Join(marketSelections[s.Market], ", "))
+	}
+
+	switch s.Market {
+	case MARKET_OVER_UNDER, MARKET_HANDICAP:
+		if s.Line == nil {
+			return fmt.Errorf("line é obrigatória no mercado %s", s.Market)
+		}
+		if !isQuarterLine(*s.Line) || math.Abs(*s.Line) > 20 {
+			return errors.New("line deve ser múltipla de 0.25 (ex: 2.5, -0.75)")
+		}
+		if s.Market == MARKET_OVER_UNDER && *s.Line <= 0 {
+			return errors.New("line do over/under deve ser positiva")
+		}
+	default:
+		s.Line = nil
+	}
+
+	if s.Odds < MIN_SELECTION_ODDS || s.Odds > MAX_SELECTION_ODDS {
+		return fmt.Errorf("odds deve estar entre %.2f e %d", MIN_SELECTION_ODDS, MAX_SELECTION_ODDS)
+	}
+	if s.Stake == 0 {
+		s.Stake = DEFAULT_SELECTION_STAKE
+	}
+	if s.Stake < 0 || s.Stake > MAX_SELECTION_STAKE {
+		return fmt.Errorf("stake deve estar entre 0 e %d unidades", MAX_SELECTION_STAKE)
+	}
+	return nil
+}
+
+// SameBet indica se duas seleções são a mesma aposta (partida, mercado, seleção e linha)
+func (s *PalpiteSelection) SameBet(other *PalpiteSelection) bool {
+	if s.MatchID != other.MatchID || s.Market != other.Market || s.Selection != other.Selection {
+		return false
+	}
+	if s.Line == nil || other.Line == nil {
+		return s.Line == nil && other.Line == nil
+	}
+	return *s.Line == *other.Line
+}
+
+func isQuarterLine(line float64) bool {
+	return math.Mod(math.Abs(line)*4, 1) == 0
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Session representa uma sessão de login (família de refresh tokens)
+type Session struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	UserAgent  *string    `json:"user_agent,omitempty"`
+	IPAddress  *string    `json:"ip_address,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Current    bool       `json:"current"`
+}
+
+// RefreshRequest representa a requisição de renovação de tokens
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Status de um upload direto para o storage
+const (
+	UPLOAD_STATUS_PENDING   = "pending"
+	UPLOAD_STATUS_COMPLETED = "completed"
+)
+
+// Upload registra um arquivo enviado direto para o storage por uma URL pré-assinada.
+// Só depois de completed ele pode ser usado como imagem de um palpite. O arquivo é o original
+// enviado pelo cliente, com os metadados, e por isso sua URL nunca é retornada.
+type Upload struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	Key         string     `json:"key"`
+	ContentType string     `json:"content_type"`
+	SizeBytes   int64      `json:"size_bytes"`
+	Status      string     `json:"status"`
+	PalpiteID   *int       `json:"palpite_id,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -1,25 +1,37 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"smartpicks-backend/internal/cpf"
+)
 
 const (
-	PERFIL_ADMIN = "admin"
-	PERFIL_USER  = "user"
+	PERFIL_ADMIN     = "admin"
+	PERFIL_MODERATOR = "moderator"
+	PERFIL_TIPSTER   = "tipster"
+	PERFIL_USER      = "user"
 )
 
-var ValidPerfis = []string{PERFIL_ADMIN, PERFIL_USER}
+var ValidPerfis = []string{PERFIL_ADMIN, PERFIL_MODERATOR, PERFIL_TIPSTER, PERFIL_USER}
 
 type User struct {
-	ID             int       `json:"id"`
-	Nome           string    `json:"nome"`
-	Email          string    `json:"email"`
-	Password       string    `json:"password,omitempty"`
-	CPF            string    `json:"cpf"`
-	DataNascimento string    `json:"data_nascimento"`
-	Perfil         string    `json:"perfil"`
-	Avatar         *string   `json:"avatar,omitempty"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID             int     `json:"id"`
+	Nome           string  `json:"nome"`
+	Email          string  `json:"email"`
+	Password       string  `json:"password,omitempty"`
+	CPF            string  `json:"cpf"`
+	CPFEncrypted   string  `json:"-"`
+	DataNascimento string  `json:"data_nascimento"`
+	Perfil         string  `json:"perfil"`
+	Avatar         *string `json:"avatar,omitempty"`
+	// AvatarVariants tem as URLs de todos os tamanhos; Avatar aponta para o tamanho padrão
+	AvatarVariants  ImageVariants `json:"avatar_variants,omitempty"`
+	EmailVerifiedAt *time.Time    `json:"email_verified_at,omitempty"`
+	// DeletionScheduledFor é preenchido quando o usuário pediu a exclusão da conta
+	DeletionScheduledFor *time.Time `json:"deletion_scheduled_for,omitempty"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
 }
 
 type UserLogin struct {
@@ -27,17 +39,63 @@ type UserLogin struct {
 	Password string `json:"password"`
 }
 
+// UserResponse é a projeção privada do usuário, com todos os dados pessoais.
+// Só deve ser enviada ao próprio usuário ou a quem tem a permissão users:read_private.
 type UserResponse struct {
-	ID             int       `json:"id"`
-	Nome           string    `json:"nome"`
-	Email          string    `json:"email"`
-	CPF            string    `json:"cpf"`
-	DataNascimento string    `json:"data_nascimento"`
-	Perfil         string    `json:"perfil"`
-	Avatar         *string   `json:"avatar,omitempty"`
-	IsAdmin        bool      `json:"is_admin"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID                   int           `json:"id"`
+	Nome                 string        `json:"nome"`
+	Email                string        `json:"email"`
+	CPF                  string        `json:"cpf"`
+	DataNascimento       string        `json:"data_nascimento"`
+	Perfil               string        `json:"perfil"`
+	Avatar               *string       `json:"avatar,omitempty"`
+	AvatarVariants       ImageVariants `json:"avatar_variants,omitempty"`
+	IsAdmin              bool          `json:"is_admin"`
+	Verified             bool          `json:"verified"`
+	EmailVerified        bool          `json:"email_verified"`
+	EmailVerifiedAt      *time.Time    `json:"email_verified_at,omitempty"`
+	DeletionScheduledFor *time.Time    `json:"deletion_scheduled_for,omitempty"`
+	CreatedAt            time.Time     `json:"created_at"`
+	UpdatedAt            time.Time     `json:"updated_at"`
+}
+
+// PublicUserResponse é a projeção pública do usuário: sem email e data de nascimento e com o CPF mascarado
+type PublicUserResponse struct {
+	ID             int           `json:"id"`
+	Nome           string        `json:"nome"`
+	CPF            string        `json:"cpf"`
+	Perfil         string        `json:"perfil"`
+	Avatar         *string       `json:"avatar,omitempty"`
+	AvatarVariants ImageVariants `json:"avatar_variants,omitempty"`
+	IsAdmin        bool          `json:"is_admin"`
+	Verified       bool          `json:"verified"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+// UserPermissionsResponse é a resposta de /users/permissions: o usuário e suas permissões efetivas
+type UserPermissionsResponse struct {
+	UserResponse
+	Permissions []string `json:"permissions"`
+}
+
+// PublicUserPermissionsResponse é a versão pública de UserPermissionsResponse
+type PublicUserPermissionsResponse struct {
+	PublicUserResponse
+	Permissions []string `json:"permissions"`
+}
+
+// TokenResponse contém o par de tokens emitido no login e no refresh
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// AuthResponse é a resposta do login: os dados do usuário acrescidos dos tokens
+type AuthResponse struct {
+	UserResponse
+	TokenResponse
 }
 
 func IsValidPerfil(perfil string) bool {
@@ -53,17 +111,45 @@ func (u *User) IsAdmin() bool {
 	return u.Perfil == PERFIL_ADMIN
 }
 
+func (u *User) HasPermission(permission string) bool {
+	return HasPermission(u.Perfil, permission)
+}
+
+// IsVerified indica se o usuário é um tipster verificado (permissão palpites:verified)
+func (u *User) IsVerified() bool {
+	return u.HasPermission(PERMISSION_PALPITES_VERIFIED)
+}
+
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
+		ID:                   u.ID,
+		Nome:                 u.Nome,
+		Email:                u.Email,
+		CPF:                  cpf.Format(u.CPF),
+		DataNascimento:       u.DataNascimento,
+		Perfil:               u.Perfil,
+		Avatar:               u.Avatar,
+		AvatarVariants:       u.AvatarVariants,
+		IsAdmin:              u.IsAdmin(),
+		Verified:             u.IsVerified(),
+		EmailVerified:        u.EmailVerifiedAt != nil,
+		EmailVerifiedAt:      u.EmailVerifiedAt,
+		DeletionScheduledFor: u.DeletionScheduledFor,
+		CreatedAt:            u.CreatedAt,
+		UpdatedAt:            u.UpdatedAt,
+	}
+}
+
+func (u *User) ToPublicResponse() PublicUserResponse {
+	return PublicUserResponse{
 		ID:             u.ID,
 		Nome:           u.Nome,
-		Email:          u.Email,
-		CPF:            u.CPF,
-		DataNascimento: u.DataNascimento,
+		CPF:            cpf.Mask(u.CPF),
 		Perfil:         u.Perfil,
 		Avatar:         u.Avatar,
+		AvatarVariants: u.AvatarVariants,
 		IsAdmin:        u.IsAdmin(),
+		Verified:       u.IsVerified(),
 		CreatedAt:      u.CreatedAt,
-		UpdatedAt:      u.UpdatedAt,
 	}
 }
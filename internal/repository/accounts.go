@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"database/sql"
+	"log"
+
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/services"
+)
+
+// AnonymizedUserName é o nome exibido nos palpites e comentários de contas removidas
+const AnonymizedUserName = "Usuário removido"
+
+// AnonymizeUser remove os dados pessoais da conta, mantendo o registro para que palpites
+// e comentários continuem atribuídos a ela. As imagens dos palpites e o avatar são apagados do S3.
+func AnonymizeUser(userID int) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var email sql.NullString
+	var avatar sql.NullString
+	var avatarVariants models.ImageVariants
+	err = tx.QueryRow("SELECT email, avatar, avatar_variants FROM users WHERE id = $1 AND anonymized_at IS NULL FOR UPDATE", userID).
+		Scan(&email, &avatar, &avatarVariants)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	files := avatarVariants.URLs()
+	if avatar.Valid && len(avatarVariants) == 0 {
+		files = append(files, avatar.String)
+	}
+	rows, err := tx.Query("SELECT img_url, img_variants FROM palpites WHERE user_id = $1 AND img_url IS NOT NULL", userID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var imgURL string
+		var imgVariants models.ImageVariants
+		if err := rows.Scan(&imgURL, &imgVariants); err != nil {
+			rows.Close()
+			return err
+		}
+		if len(imgVariants) > 0 {
+			files = append(files, imgVariants.URLs()...)
+		} else {
+			files = append(files, imgURL)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Originais dos uploads diretos ainda no storage e arquivos de exportação
+	rows, err = tx.Query(`
+		SELECT storage_key FROM uploads WHERE user_id = $1
+		UNION ALL
+		SELECT storage_key FROM data_exports WHERE user_id = $1 AND storage_key IS NOT NULL`, userID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return err
+		}
+		files = append(files, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// password vazio nunca confere com bcrypt, então a conta não pode mais ser acessada
+	if _, err := tx.Exec(`
+		UPDATE users SET
+			nome = $2, email = NULL, pending_email = NULL, password = '', cpf = NULL, cpf_encrypted = NULL, cpf_hash = NULL,
+			data_nascimento = NULL, avatar = NULL, avatar_variants = NULL, email_verified_at = NULL,
+			totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL,
+			deletion_requested_at = NULL, deletion_scheduled_for = NULL, anonymized_at = CURRENT_TIMESTAMP
+		WHERE id = $1`, userID, AnonymizedUserName); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		UPDATE palpites SET img_url = NULL, img_variants = NULL, img_width = NULL, img_height = NULL, img_blurhash = NULL,
+			img_phash = NULL
+		WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	for _, query := range []string{
+		"DELETE FROM sessions WHERE user_id = $1",
+		"DELETE FROM user_tokens WHERE user_id = $1",
+		"DELETE FROM recovery_codes WHERE user_id = $1",
+		"DELETE FROM data_exports WHERE user_id = $1",
+		"DELETE FROM uploads WHERE user_id = $1",
+	} {
+		if _, err := tx.Exec(query, userID); err != nil {
+			return err
+		}
+	}
+	if email.Valid {
+		if _, err := tx.Exec("DELETE FROM login_attempts WHERE key = $1", services.AccountLoginKey(email.String)); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	DeleteStoredFiles(userID, files)
+	return nil
+}
+
+// PurgeScheduledDeletions anonimiza as contas cujo prazo de carência para exclusão terminou.
+// Retorna quantas contas foram anonimizadas.
+func PurgeScheduledDeletions() (int, error) {
+	rows, err := database.DB.Query(`
+		SELECT id FROM users
+		WHERE deletion_scheduled_for <= CURRENT_TIMESTAMP AND anonymized_at IS NULL`)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		if err := AnonymizeUser(id); err != nil {
+			log.Printf("Erro ao anonimizar usuário %d: %v", id, err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"math"
+	"time"
+
+	"smartpicks-backend/internal/config"
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/stats"
+
+	"github.com/lib/pq"
+)
+
+// Quantidade de tipsters listados em cada opção do consenso
+const consensusTopTipsters = 5
+
+// consensusCacheTTL é a validade máxima do consenso em cache (CONSENSUS_CACHE_TTL). Novos
+// palpites e a apuração da partida já invalidam o cache; o TTL cobre a mudança do ROI dos
+// tipsters com a apuração de outras partidas.
+func consensusCacheTTL() time.Duration {
+	return config.Duration("CONSENSUS_CACHE_TTL", 5*time.Minute)
+}
+
+// ConsensusMinPicks é o mínimo de seleções apuradas para o ROI do tipster pesar no consenso
+// (CONSENSUS_MIN_PICKS)
+func ConsensusMinPicks() int {
+	return config.Int("CONSENSUS_MIN_PICKS", 10)
+}
+
+// GetMatchConsensus retorna o consenso da comunidade sobre a partida, usando o cache quando válido.
+// Retorna ErrMatchNotFound se a partida não existir. Como nas estatísticas dos tipsters, o cache
+// guarda a versão da partida lida antes do cálculo, e novos palpites e a apuração incrementam a
+// versão na mesma transação: um cálculo que concorreu com eles nunca é servido.
+func GetMatchConsensus(matchID int) (*stats.Consensus, error) {
+	var version int64
+	err := database.DB.QueryRow(`
+		SELECT COALESCE((SELECT version FROM match_consensus_versions WHERE match_id = $1), 0)`, matchID).Scan(&version)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	err = database.DB.QueryRow(`
+		SELECT consensus FROM match_consensus_cache
+		WHERE match_id = $1 AND version = $2 AND computed_at > $3`,
+		matchID, version, time.Now().Add(-consensusCacheTTL())).Scan(&data)
+	if err == nil {
+		var cached stats.Consensus
+		if err := json.Unmarshal(data, &cached); err == nil {
+			return &cached, nil
+		}
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	var exists bool
+	if err := database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM matches WHERE id = $1)", matchID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrMatchNotFound
+	}
+
+	backings, err := loadMatchBackings(matchID)
+	if err != nil {
+		return nil, err
+	}
+	result := stats.ComputeConsensus(matchID, backings, ConsensusMinPicks(), consensusTopTipsters)
+	result.ComputedAt = time.Now()
+
+	if data, err = json.Marshal(result); err == nil {
+		_, err = database.DB.Exec(`
+			INSERT INTO match_consensus_cache (match_id, consensus, computed_at, version)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (match_id) DO UPDATE
+			SET consensus = EXCLUDED.consensus, computed_at = EXCLUDED.computed_at, version = EXCLUDED.version`,
+			matchID, data, result.ComputedAt, version)
+	}
+	if err != nil {
+		log.Printf("Erro ao gravar consenso da partida %d em cache: %v", matchID, err)
+	}
+	return &result, nil
+}
+
+// loadMatchBackings busca as seleções da partida, da mais antiga para a mais nova, com o ROI de
+// todas as seleções apuradas de cada tipster. Contas anonimizadas ficam fora.
+func loadMatchBackings(matchID int) ([]stats.Backing, error) {
+	rows, err := database.DB.Query(`
+		SELECT p.user_id, u.nome, s.market, s.selection, s.line, s.odds
+		FROM palpite_selections s
+		JOIN palpites p ON p.id = s.palpite_id
+		JOIN users u ON u.id = p.user_id
+		WHERE s.match_id = $1 AND u.anonymized_at IS NULL
+		ORDER BY s.created_at, s.id`, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var backings []stats.Backing
+	var userIDs []int
+	for rows.Next() {
+		var b stats.Backing
+		if err := rows.Scan(&b.UserID, &b.UserName, &b.Market, &b.Selection, &b.Line, &b.Odds); err != nil {
+			return nil, err
+		}
+		backings = append(backings, b)
+		userIDs = append(userIDs, b.UserID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(backings) == 0 {
+		return backings, nil
+	}
+
+	type track struct {
+		picks  int
+		profit float64
+	}
+	tracks := map[int]track{}
+	rows, err = database.DB.Query(`
+		SELECT p.user_id, COUNT(*), COALESCE(SUM(s.profit), 0)
+		FROM palpite_selections s
+		JOIN palpites p ON p.id = s.palpite_id
+		WHERE p.user_id = ANY($1) AND s.status <> $2
+		GROUP BY p.user_id`, pq.Array(userIDs), models.PICK_STATUS_PENDING)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID int
+		var t track
+		if err := rows.Scan(&userID, &t.picks, &t.profit); err != nil {
+			return nil, err
+		}
+		tracks[userID] = t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Mesmo ROI de /users/{id}/stats: lucro sobre a banca em unidades
+	bankroll := StatsBankroll()
+	for i := range backings {
+		t := tracks[backings[i].UserID]
+		backings[i].TipsterPicks = t.picks
+		if bankroll > 0 {
+			backings[i].TipsterROI = math.Round(t.profit/bankroll*100*100) / 100
+		}
+	}
+	return backings, nil
+}
+
+// invalidateConsensusCache descarta o consenso em cache das partidas, incrementando a versão
+// delas. As partidas são travadas em ordem para que transações concorrentes não se bloqueiem.
+func invalidateConsensusCache(tx *sql.Tx, matchIDs []int) error {
+	if len(matchIDs) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(`
+		INSERT INTO match_consensus_versions (match_id, version)
+		SELECT DISTINCT id, 1 FROM unnest($1::int[]) AS id
+		ORDER BY id
+		ON CONFLICT (match_id) DO UPDATE SET version = match_consensus_versions.version + 1`, pq.Array(matchIDs))
+	return err
+}
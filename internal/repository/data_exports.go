@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"smartpicks-backend/internal/config"
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/models"
+)
+
+// Mensagem gravada nos exports que não terminaram a tempo
+const dataExportTimeoutError = "A exportação não foi concluída a tempo. Peça uma nova exportação"
+
+// DataExportJob é um export assíncrono reservado por um worker. Attempts identifica a
+// reserva: um worker que perdeu o export para outro não consegue mais concluí-lo.
+type DataExportJob struct {
+	ID       int
+	UserID   int
+	Attempts int
+}
+
+// dataExportJobTimeout é o tempo máximo de processamento de um export (EXPORT_JOB_TIMEOUT).
+// Depois disso o worker é considerado perdido e o export volta para a fila.
+func dataExportJobTimeout() time.Duration {
+	return config.Duration("EXPORT_JOB_TIMEOUT", 15*time.Minute)
+}
+
+// dataExportQueueTimeout é o tempo máximo que um export espera na fila (EXPORT_QUEUE_TIMEOUT)
+func dataExportQueueTimeout() time.Duration {
+	return config.Duration("EXPORT_QUEUE_TIMEOUT", 24*time.Hour)
+}
+
+// dataExportMaxAttempts é o número de tentativas antes de o export ser marcado como falho
+// (EXPORT_JOB_MAX_ATTEMPTS)
+func dataExportMaxAttempts() int {
+	return config.Int("EXPORT_JOB_MAX_ATTEMPTS", 3)
+}
+
+// RequestDataExport coloca um export assíncrono na fila e retorna seu ID. Um pedido do
+// usuário que ainda está na fila ou em processamento é reaproveitado.
+func RequestDataExport(userID int) (int, error) {
+	if err := releaseStaleDataExports(); err != nil {
+		return 0, err
+	}
+
+	var exportID int
+	err := database.DB.QueryRow(`
+		SELECT id FROM data_exports
+		WHERE user_id = $1 AND status IN ($2, $3)
+		ORDER BY created_at DESC LIMIT 1`,
+		userID, models.EXPORT_STATUS_PENDING, models.EXPORT_STATUS_PROCESSING).Scan(&exportID)
+	if err == sql.ErrNoRows {
+		err = database.DB.QueryRow(`
+			INSERT INTO data_exports (user_id, status) VALUES ($1, $2)
+			RETURNING id`, userID, models.EXPORT_STATUS_PENDING).Scan(&exportID)
+	}
+	return exportID, err
+}
+
+// ClaimDataExport reserva o export mais antigo da fila para o worker. Retorna nil quando a
+// fila está vazia. Workers concorrentes não disputam o mesmo export (SKIP LOCKED).
+func ClaimDataExport() (*DataExportJob, error) {
+	if err := releaseStaleDataExports(); err != nil {
+		return nil, err
+	}
+
+	var job DataExportJob
+	err := database.DB.QueryRow(`
+		UPDATE data_exports SET status = $1, started_at = CURRENT_TIMESTAMP, attempts = attempts + 1
+		WHERE id = (
+			SELECT id FROM data_exports
+			WHERE status = $2
+			ORDER BY created_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING id, user_id, attempts`,
+		models.EXPORT_STATUS_PROCESSING, models.EXPORT_STATUS_PENDING).Scan(&job.ID, &job.UserID, &job.Attempts)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// CompleteDataExport marca o export como pronto, com o arquivo já gravado no storage.
+// Retorna false se a reserva do worker não vale mais (o export voltou para a fila ou foi
+// removido); nesse caso o arquivo deve ser descartado.
+func CompleteDataExport(job *DataExportJob, storageKey string, size int, expiresAt time.Time) (bool, error) {
+	result, err := database.DB.Exec(`
+		UPDATE data_exports
+		SET status = $1, storage_key = $2, size_bytes = $3, error = NULL,
+			completed_at = CURRENT_TIMESTAMP, expires_at = $4
+		WHERE id = $5 AND status = $6 AND attempts = $7`,
+		models.EXPORT_STATUS_READY, storageKey, size, expiresAt,
+		job.ID, models.EXPORT_STATUS_PROCESSING, job.Attempts)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// FailDataExport devolve o export para a fila ou, esgotadas as tentativas, o marca como falho
+func FailDataExport(job *DataExportJob, message string) error {
+	_, err := database.DB.Exec(`
+		UPDATE data_exports
+		SET status = CASE WHEN attempts < $1 THEN $2 ELSE $3 END,
+			error = $4, started_at = NULL,
+			completed_at = CASE WHEN attempts < $1 THEN NULL ELSE CURRENT_TIMESTAMP END
+		WHERE id = $5 AND status = $6 AND attempts = $7`,
+		dataExportMaxAttempts(), models.EXPORT_STATUS_PENDING, models.EXPORT_STATUS_FAILED,
+		message, job.ID, models.EXPORT_STATUS_PROCESSING, job.Attempts)
+	return err
+}
+
+// releaseStaleDataExports trata os exports parados: os que estão em processamento há mais de
+// EXPORT_JOB_TIMEOUT voltam para a fila (ou falham, esgotadas as tentativas) e os que esperam
+// na fila há mais de EXPORT_QUEUE_TIMEOUT falham, para que o usuário possa pedir outro.
+func releaseStaleDataExports() error {
+	now := time.Now()
+	_, err := database.DB.Exec(`
+		UPDATE data_exports
+		SET status = CASE WHEN attempts < $1 THEN $2 ELSE $3 END,
+			error = CASE WHEN attempts < $1 THEN error ELSE $4 END,
+			started_at = NULL,
+			completed_at = CASE WHEN attempts < $1 THEN NULL ELSE CURRENT_TIMESTAMP END
+		WHERE status = $5 AND started_at < $6`,
+		dataExportMaxAttempts(), models.EXPORT_STATUS_PENDING, models.EXPORT_STATUS_FAILED,
+		dataExportTimeoutError, models.EXPORT_STATUS_PROCESSING, now.Add(-dataExportJobTimeout()))
+	if err != nil {
+		return err
+	}
+
+	_, err = database.DB.Exec(`
+		UPDATE data_exports SET status = $1, error = $2, completed_at = CURRENT_TIMESTAMP
+		WHERE status = $3 AND created_at < $4`,
+		models.EXPORT_STATUS_FAILED, dataExportTimeoutError,
+		models.EXPORT_STATUS_PENDING, now.Add(-dataExportQueueTimeout()))
+	return err
+}
+
+// PurgeExpiredDataExports remove os exports vencidos e seus arquivos no storage
+func PurgeExpiredDataExports() error {
+	rows, err := database.DB.Query(`
+		DELETE FROM data_exports WHERE expires_at < CURRENT_TIMESTAMP
+		RETURNING user_id, storage_key`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	files := map[int][]string{}
+	for rows.Next() {
+		var userID int
+		var key sql.NullString
+		if err := rows.Scan(&userID, &key); err != nil {
+			return err
+		}
+		if key.Valid {
+			files[userID] = append(files[userID], key.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for userID, keys := range files {
+		DeleteStoredFiles(userID, keys)
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/fixtures"
+	"smartpicks-backend/internal/models"
+)
+
+var ErrAliasInUse = errors.New("alias já usado por outro time")
+
+// FixtureImportOptions controla a importação. Sem Apply nada é gravado (dry-run).
+type FixtureImportOptions struct {
+	// CompetitionID é a competição das linhas que não informam uma
+	CompetitionID *int
+	Apply         bool
+}
+
+// ImportFixtures confere as partidas lidas do arquivo com o banco e monta o relatório do que
+// seria criado, atualizado ou ignorado. A chave natural de uma partida é mandante + visitante +
+// dia (UTC) do início. Com Apply as linhas válidas são gravadas em uma transação.
+func ImportFixtures(rows []fixtures.Row, opts FixtureImportOptions) (*fixtures.Report, error) {
+	teamIDs, teamNames, err := loadTeamLookup()
+	if err != nil {
+		return nil, err
+	}
+	competitionIDs, err := loadCompetitionLookup()
+	if err != nil {
+		return nil, err
+	}
+	if opts.CompetitionID != nil {
+		found := false
+		for _, id := range competitionIDs {
+			found = found || id == *opts.CompetitionID
+		}
+		if !found {
+			return nil, ErrCompetitionNotFound
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	report := fixtures.NewReport(!opts.Apply)
+	seen := map[string]int{}
+	for _, row := range rows {
+		result := fixtures.RowResult{
+			Line:        row.Line,
+			Competition: row.Competition,
+			HomeTeam:    row.HomeTeam,
+			AwayTeam:    row.AwayTeam,
+		}
+		if !row.Kickoff.IsZero() {
+			kickoff := row.Kickoff
+			result.Kickoff = &kickoff
+		}
+		errs := append([]string{}, row.Errors...)
+
+		homeID, homeOK := teamIDs[fixtures.NormalizeName(row.HomeTeam)]
+		if row.HomeTeam != "" && !homeOK {
+			errs = append(errs, fmt.Sprintf("time desconhecido: %s (cadastre o time ou um alias)", row.HomeTeam))
+		}
+		awayID, awayOK := teamIDs[fixtures.NormalizeName(row.AwayTeam)]
+		if row.AwayTeam != "" && !awayOK {
+			errs = append(errs, fmt.Sprintf("time desconhecido: %s (cadastre o time ou um alias)", row.AwayTeam))
+		}
+		if homeOK && awayOK && homeID == awayID {
+			errs = append(errs, "mandante e visitante são o mesmo time")
+		}
+
+		competitionID := opts.CompetitionID
+		if row.Competition != "" {
+			if id, ok := competitionIDs[fixtures.NormalizeName(row.Competition)]; ok {
+				competitionID = &id
+			} else {
+				errs = append(errs, fmt.Sprintf("competição desconhecida: %s", row.Competition))
+			}
+		}
+		if row.Status != "" && !models.IsValidMatchStatus(row.Status) {
+			errs = append(errs, fmt.Sprintf("status inválido: %s", row.Status))
+		}
+
+		if len(errs) == 0 {
+			key := fmt.Sprintf("%d:%d:%s", homeID, awayID, row.Kickoff.Format("2006-01-02"))
+			if line, dup := seen[key]; dup {
+				errs = append(errs, fmt.Sprintf("partida repetida no arquivo (linha %d)", line))
+			}
+			seen[key] = row.Line
+		}
+		if len(errs) > 0 {
+			result.Action = fixtures.ACTION_ERROR
+			result.Errors = errs
+			report.Add(result)
+			continue
+		}
+
+		existing, err := findMatchByNaturalKey(tx, homeID, awayID, row.Kickoff)
+		if err == sql.ErrNoRows && opts.Apply {
+			status := row.Status
+			if status == "" {
+				status = models.MATCH_STATUS_SCHEDULED
+			}
+			// Outra importação pode gravar a mesma partida ao mesmo tempo: o índice único da chave
+			// natural decide, e a linha que perde segue como atualização da partida já gravada
+			var id int
+			err = tx.QueryRow(`
+				INSERT INTO matches (competition_id, home_team_id, away_team_id, team_a, team_b, match_date, location, status)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+				ON CONFLICT (home_team_id, away_team_id, ((match_date AT TIME ZONE 'UTC')::date)) DO NOTHING
+				RETURNING id`,
+				competitionID, homeID, awayID, teamNames[homeID], teamNames[awayID], row.Kickoff, row.Venue, status,
+			).Scan(&id)
+			if err == nil {
+				result.Action = fixtures.ACTION_CREATE
+				result.MatchID = &id
+				report.Add(result)
+				continue
+			}
+			if err != sql.ErrNoRows {
+				return nil, fmt.Errorf("linha %d: %v", row.Line, err)
+			}
+			existing, err = findMatchByNaturalKey(tx, homeID, awayID, row.Kickoff)
+		}
+		if err == sql.ErrNoRows {
+			result.Action = fixtures.ACTION_CREATE
+			report.Add(result)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		result.MatchID = &existing.ID
+		updated := existing
+		if !existing.MatchDate.Equal(row.Kickoff) {
+			updated.MatchDate = row.Kickoff
+			result.Changes = append(result.Changes, fmt.Sprintf("match_date: %s → %s",
+				existing.MatchDate.UTC().Format(time.RFC3339), row.Kickoff.Format(time.RFC3339)))
+		}
+		if row.Venue != "" && row.Venue != existing.Location {
+			updated.Location = row.Venue
+			result.Changes = append(result.Changes, fmt.Sprintf("location: %q → %q", existing.Location, row.Venue))
+		}
+		if row.Status != "" && row.Status != existing.Status {
+			updated.Status = row.Status
+			result.Changes = append(result.Changes, fmt.Sprintf("status: %s → %s", existing.Status, row.Status))
+		}
+		if competitionID != nil && (existing.CompetitionID == nil || *existing.CompetitionID != *competitionID) {
+			updated.CompetitionID = competitionID
+			result.Changes = append(result.Changes, fmt.Sprintf("competition_id: %s → %d",
+				intOrNone(existing.CompetitionID), *competitionID))
+		}
+
+		if len(result.Changes) == 0 {
+			result.Action = fixtures.ACTION_SKIP
+			report.Add(result)
+			continue
+		}
+		result.Action = fixtures.ACTION_UPDATE
+		if opts.Apply {
+			if _, err := tx.Exec(`
+				UPDATE matches SET competition_id = $1, match_date = $2, location = $3, status = $4
+				WHERE id = $5`,
+				updated.CompetitionID, updated.MatchDate, updated.Location, updated.Status, existing.ID); err != nil {
+				return nil, fmt.Errorf("linha %d: %v", row.Line, err)
+			}
+		}
+		report.Add(result)
+	}
+
+	if opts.Apply {
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// findMatchByNaturalKey busca a partida dos dois times no mesmo dia (UTC). A chave natural é
+// única (idx_matches_natural_key_unique), então há no máximo uma.
+func findMatchByNaturalKey(tx *sql.Tx, homeID, awayID int, kickoff time.Time) (models.Match, error) {
+	var m models.Match
+	err := tx.QueryRow(`
+		SELECT id, competition_id, match_date, location, status FROM matches
+		WHERE home_team_id = $1 AND away_team_id = $2 AND (match_date AT TIME ZONE 'UTC')::date = $3::date
+		FOR UPDATE`, homeID, awayID, kickoff.UTC().Format("2006-01-02"),
+	).Scan(&m.ID, &m.CompetitionID, &m.MatchDate, &m.Location, &m.Status)
+	return m, err
+}
+
+// loadTeamLookup mapeia os nomes normalizados dos times e dos seus aliases para o ID do time
+func loadTeamLookup() (map[string]int, map[int]string, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, name, FALSE FROM teams
+		UNION ALL
+		SELECT team_id, alias, TRUE FROM team_aliases`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	ids := map[string]int{}
+	names := map[int]string{}
+	for rows.Next() {
+		var id int
+		var name string
+		var alias bool
+		if err := rows.Scan(&id, &name, &alias); err != nil {
+			return nil, nil, err
+		}
+		ids[fixtures.NormalizeName(name)] = id
+		if !alias {
+			names[id] = name
+		}
+	}
+	return ids, names, rows.Err()
+}
+
+// loadCompetitionLookup mapeia nome e código (ex: "E0") normalizados para o ID da competição
+func loadCompetitionLookup() (map[string]int, error) {
+	rows, err := database.DB.Query("SELECT id, name, COALESCE(code, '') FROM competitions")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := map[string]int{}
+	for rows.Next() {
+		var id int
+		var name, code string
+		if err := rows.Scan(&id, &name, &code); err != nil {
+			return nil, err
+		}
+		ids[fixtures.NormalizeName(name)] = id
+		if code != "" {
+			ids[fixtures.NormalizeName(code)] = id
+		}
+	}
+	return ids, rows.Err()
+}
+
+// AddTeamAlias cadastra outro nome pelo qual o time aparece nos arquivos de partidas
+func AddTeamAlias(teamID int, alias string) error {
+	_, err := database.DB.Exec("INSERT INTO team_aliases (team_id, alias) VALUES ($1, $2)",
+		teamID, fixtures.NormalizeName(alias))
+	switch {
+	case IsForeignKeyViolation(err):
+		return ErrTeamNotFound
+	case IsUniqueViolation(err):
+		return ErrAliasInUse
+	}
+	return err
+}
+
+// DeleteTeamAlias remove um alias do time. Retorna sql.ErrNoRows se ele não existir.
+func DeleteTeamAlias(teamID int, alias string) error {
+	result, err := database.DB.Exec("DELETE FROM team_aliases WHERE team_id = $1 AND alias = $2",
+		teamID, fixtures.NormalizeName(alias))
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func intOrNone(value *int) string {
+	if value == nil {
+		return "nenhuma"
+	}
+	return fmt.Sprint(*value)
+}
@@ -0,0 +1,352 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrTeamNotFound        = errors.New("time não encontrado")
+	ErrCompetitionNotFound = errors.New("competição não encontrada")
+	// ErrMatchExists indica que os dois times já têm uma partida no mesmo dia (UTC)
+	ErrMatchExists = errors.New("já existe uma partida destes times neste dia")
+	// ErrInUse indica que o registro é referenciado (ex: partida com palpites, time com partidas)
+	ErrInUse = errors.New("registro em uso")
+)
+
+// matchSelect busca as partidas com os nomes da competição e dos times. team_a/team_b guardam
+// os nomes de quando a partida foi gravada e valem para partidas antigas, sem time cadastrado.
+const matchSelect = `
+	SELECT m.id, m.competition_id, c.name, m.home_team_id, m.away_team_id,
+		COALESCE(th.name, m.team_a), COALESCE(ta.name, m.team_b), m.match_date, m.location, m.status,
+		m.home_score, m.away_score, m.settled_at
+	FROM matches m
+	LEFT JOIN competitions c ON c.id = m.competition_id
+	LEFT JOIN teams th ON th.id = m.home_team_id
+	LEFT JOIN teams ta ON ta.id = m.away_team_id`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMatch(row rowScanner) (models.Match, error) {
+	var m models.Match
+	err := row.Scan(&m.ID, &m.CompetitionID, &m.Competition, &m.HomeTeamID, &m.AwayTeamID,
+		&m.TeamA, &m.TeamB, &m.MatchDate, &m.Location, &m.Status,
+		&m.HomeScore, &m.AwayScore, &m.SettledAt)
+	m.MatchDate = m.MatchDate.UTC()
+	return m, err
+}
+
+// MatchFilter são os filtros de GET /matches. Campos vazios não filtram.
+type MatchFilter struct {
+	From          *time.Time
+	To            *time.Time
+	TeamID        int
+	Team          string
+	CompetitionID int
+	Statuses      []string
+	Limit         int
+	Offset        int
+}
+
+// ListMatches retorna as partidas do filtro em ordem de início e o total sem paginação
+func ListMatches(f MatchFilter) ([]models.Match, int, error) {
+	var where []string
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if f.From != nil {
+		where = append(where, "m.match_date >= "+arg(*f.From))
+	}
+	if f.To != nil {
+		where = append(where, "m.match_date < "+arg(*f.To))
+	}
+	if f.TeamID > 0 {
+		p := arg(f.TeamID)
+		where = append(where, fmt.Sprintf("(m.home_team_id = %s OR m.away_team_id = %s)", p, p))
+	}
+	if f.Team != "" {
+		p := arg("%" + f.Team + "%")
+		where = append(where, fmt.Sprintf("(COALESCE(th.name, m.team_a) ILIKE %s OR COALESCE(ta.name, m.team_b) ILIKE %s)", p, p))
+	}
+	if f.CompetitionID > 0 {
+		where = append(where, "m.competition_id = "+arg(f.CompetitionID))
+	}
+	if len(f.Statuses) > 0 {
+		where = append(where, "m.status = ANY("+arg(pq.Array(f.Statuses))+")")
+	}
+
+	query := matchSelect
+	if len(where) > 0 {
+		query += "\n\tWHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM ("+query+") filtered", args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query += "\n\tORDER BY m.match_date ASC, m.id ASC LIMIT " + arg(f.Limit) + " OFFSET " + arg(f.Offset)
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	matches := []models.Match{}
+	for rows.Next() {
+		m, err := scanMatch(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		matches = append(matches, m)
+	}
+	return matches, total, rows.Err()
+}
+
+// GetMatch busca uma partida. Retorna ErrMatchNotFound se ela não existir.
+func GetMatch(id int) (models.Match, error) {
+	m, err := scanMatch(database.DB.QueryRow(matchSelect+" WHERE m.id = $1", id))
+	if err == sql.ErrNoRows {
+		return m, ErrMatchNotFound
+	}
+	return m, err
+}
+
+// LoadMatches busca as partidas pelos IDs. IDs inexistentes ficam fora do mapa.
+func LoadMatches(ids []int) (map[int]models.Match, error) {
+	matches := map[int]models.Match{}
+	if len(ids) == 0 {
+		return matches, nil
+	}
+
+	rows, err := database.DB.Query(matchSelect+" WHERE m.id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		m, err := scanMatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		matches[m.ID] = m
+	}
+	return matches, rows.Err()
+}
+
+// MatchInput são os dados gravados ao criar ou alterar uma partida
+type MatchInput struct {
+	CompetitionID *int      `json:"competition_id"`
+	HomeTeamID    int       `json:"home_team_id"`
+	AwayTeamID    int       `json:"away_team_id"`
+	MatchDate     time.Time `json:"match_date"`
+	Location      string    `json:"location"`
+	Status        string    `json:"status"`
+}
+
+// resolveMatchInput confere a competição e os times e retorna os nomes dos times
+func resolveMatchInput(in MatchInput) (string, string, error) {
+	if in.CompetitionID != nil {
+		var exists bool
+		if err := database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM competitions WHERE id = $1)", *in.CompetitionID).
+			Scan(&exists); err != nil {
+			return "", "", err
+		}
+		if !exists {
+			return "", "", ErrCompetitionNotFound
+		}
+	}
+
+	var home, away string
+	if err := database.DB.QueryRow("SELECT name FROM teams WHERE id = $1", in.HomeTeamID).Scan(&home); err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrTeamNotFound
+		}
+		return "", "", err
+	}
+	if err := database.DB.QueryRow("SELECT name FROM teams WHERE id = $1", in.AwayTeamID).Scan(&away); err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrTeamNotFound
+		}
+		return "", "", err
+	}
+	return home, away, nil
+}
+
+// CreateMatch grava uma nova partida
+func CreateMatch(in MatchInput) (models.Match, error) {
+	home, away, err := resolveMatchInput(in)
+	if err != nil {
+		return models.Match{}, err
+	}
+
+	var id int
+	err = database.DB.QueryRow(`
+		INSERT INTO matches (competition_id, home_team_id, away_team_id, team_a, team_b, match_date, location, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id`,
+		in.CompetitionID, in.HomeTeamID, in.AwayTeamID, home, away, in.MatchDate.UTC(), in.Location, in.Status,
+	).Scan(&id)
+	if IsUniqueViolation(err) {
+		return models.Match{}, ErrMatchExists
+	}
+	if err != nil {
+		return models.Match{}, err
+	}
+	return GetMatch(id)
+}
+
+// UpdateMatch altera os dados de uma partida (o placar é gravado pela apuração)
+func UpdateMatch(id int, in MatchInput) (models.Match, error) {
+	home, away, err := resolveMatchInput(in)
+	if err != nil {
+		return models.Match{}, err
+	}
+
+	result, err := database.DB.Exec(`
+		UPDATE matches SET competition_id = $1, home_team_id = $2, away_team_id = $3, team_a = $4, team_b = $5,
+			match_date = $6, location = $7, status = $8
+		WHERE id = $9`,
+		in.CompetitionID, in.HomeTeamID, in.AwayTeamID, home, away, in.MatchDate.UTC(), in.Location, in.Status, id)
+	if IsUniqueViolation(err) {
+		return models.Match{}, ErrMatchExists
+	}
+	if err != nil {
+		return models.Match{}, err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return models.Match{}, ErrMatchNotFound
+	}
+	return GetMatch(id)
+}
+
+// DeleteMatch remove uma partida. Partidas com palpites não podem ser removidas (ErrInUse).
+func DeleteMatch(id int) error {
+	return deleteByID("matches", id, ErrMatchNotFound)
+}
+
+// ListCompetitions retorna as competições em ordem alfabética
+func ListCompetitions() ([]models.Competition, error) {
+	rows, err := database.DB.Query("SELECT id, name, code, country, created_at FROM competitions ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	competitions := []models.Competition{}
+	for rows.Next() {
+		var c models.Competition
+		if err := rows.Scan(&c.ID, &c.Name, &c.Code, &c.Country, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		competitions = append(competitions, c)
+	}
+	return competitions, rows.Err()
+}
+
+// SaveCompetition cria (ID zero) ou altera uma competição
+func SaveCompetition(c *models.Competition) error {
+	if c.ID == 0 {
+		return database.DB.QueryRow(`
+			INSERT INTO competitions (name, code, country) VALUES ($1, $2, $3)
+			RETURNING id, created_at`, c.Name, c.Code, c.Country).Scan(&c.ID, &c.CreatedAt)
+	}
+	err := database.DB.QueryRow(`
+		UPDATE competitions SET name = $1, code = $2, country = $3 WHERE id = $4
+		RETURNING created_at`, c.Name, c.Code, c.Country, c.ID).Scan(&c.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrCompetitionNotFound
+	}
+	return err
+}
+
+// DeleteCompetition remove uma competição; as partidas dela ficam sem competição
+func DeleteCompetition(id int) error {
+	return deleteByID("competitions", id, ErrCompetitionNotFound)
+}
+
+// ListTeams retorna os times em ordem alfabética com os aliases, opcionalmente filtrados pelo nome
+func ListTeams(search string) ([]models.Team, error) {
+	rows, err := database.DB.Query(`
+		SELECT t.id, t.name, t.short_name, t.country,
+			ARRAY(SELECT alias FROM team_aliases WHERE team_id = t.id ORDER BY alias), t.created_at
+		FROM teams t
+		WHERE $1 = '' OR t.name ILIKE '%' || $1 || '%'
+		ORDER BY t.name`, search)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	teams := []models.Team{}
+	for rows.Next() {
+		var t models.Team
+		if err := rows.Scan(&t.ID, &t.Name, &t.ShortName, &t.Country, pq.Array(&t.Aliases), &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		teams = append(teams, t)
+	}
+	return teams, rows.Err()
+}
+
+// SaveTeam cria (ID zero) ou altera um time
+func SaveTeam(t *models.Team) error {
+	if t.ID == 0 {
+		return database.DB.QueryRow(`
+			INSERT INTO teams (name, short_name, country) VALUES ($1, $2, $3)
+			RETURNING id, created_at`, t.Name, t.ShortName, t.Country).Scan(&t.ID, &t.CreatedAt)
+	}
+	err := database.DB.QueryRow(`
+		UPDATE teams SET name = $1, short_name = $2, country = $3 WHERE id = $4
+		RETURNING created_at`, t.Name, t.ShortName, t.Country, t.ID).Scan(&t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrTeamNotFound
+	}
+	return err
+}
+
+// DeleteTeam remove um time. Times com partidas não podem ser removidos (ErrInUse).
+func DeleteTeam(id int) error {
+	return deleteByID("teams", id, ErrTeamNotFound)
+}
+
+// deleteByID apaga o registro da tabela, convertendo violações de chave estrangeira em ErrInUse
+func deleteByID(table string, id int, notFound error) error {
+	result, err := database.DB.Exec("DELETE FROM "+table+" WHERE id = $1", id)
+	if IsForeignKeyViolation(err) {
+		return ErrInUse
+	}
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// IsForeignKeyViolation indica se o erro do Postgres é uma violação de chave estrangeira
+func IsForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+// IsUniqueViolation indica se o erro do Postgres é uma violação de unicidade
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"smartpicks-backend/internal/config"
+	"smartpicks-backend/internal/database"
+
+	"github.com/lib/pq"
+)
+
+// hammingDistanceSQL calcula a distância de Hamming entre img_phash e o hash $1 (bits diferentes)
+const hammingDistanceSQL = `length(replace(((img_phash # $1)::bit(64))::text, '0', ''))`
+
+// DuplicateMaxDistance é a distância de Hamming máxima (em 64 bits) para duas imagens serem
+// consideradas a mesma (PALPITE_DUPLICATE_MAX_DISTANCE)
+func DuplicateMaxDistance() int {
+	return config.Int("PALPITE_DUPLICATE_MAX_DISTANCE", 6)
+}
+
+// DuplicateMatch é o palpite original de uma imagem repetida
+type DuplicateMatch struct {
+	OriginalID int
+	Distance   int
+}
+
+// Número de faixas de 16 bits em que o hash é dividido (colunas img_phash_b0..b3)
+const phashBandCount = 4
+
+// FindDuplicateImage procura um palpite com imagem parecida (distância de Hamming até maxDistance).
+// Se o palpite encontrado já é uma cópia, o original apontado por ele é retornado, para que
+// todas as cópias fiquem no mesmo grupo. Retorna nil quando não há imagem parecida.
+//
+// A busca usa as faixas indexadas do hash: se dois hashes estão a até maxDistance bits, alguma
+// das 4 faixas difere em no máximo maxDistance/4 bits. Só os palpites com uma faixa entre esses
+// vizinhos têm a distância completa calculada.
+func FindDuplicateImage(hash int64, maxDistance int) (*DuplicateMatch, error) {
+	bands := phashBands(hash)
+	radius := maxDistance / phashBandCount
+	var neighbors [phashBandCount]pq.Int64Array
+	for i, band := range bands {
+		neighbors[i] = bandNeighbors(band, radius)
+	}
+
+	var match DuplicateMatch
+	err := database.DB.QueryRow(`
+		SELECT COALESCE(duplicate_of, id), distance FROM (
+			SELECT id, duplicate_of, created_at, `+hammingDistanceSQL+` AS distance
+			FROM palpites
+			WHERE img_phash IS NOT NULL
+			  AND (img_phash_b0 = ANY($3) OR img_phash_b1 = ANY($4)
+			    OR img_phash_b2 = ANY($5) OR img_phash_b3 = ANY($6))
+		) candidates
+		WHERE distance <= $2
+		ORDER BY distance, created_at
+		LIMIT 1`, hash, maxDistance, neighbors[0], neighbors[1], neighbors[2], neighbors[3]).
+		Scan(&match.OriginalID, &match.Distance)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &match, nil
+}
+
+// phashBands divide o hash nas faixas de 16 bits gravadas em img_phash_b0..b3
+func phashBands(hash int64) [phashBandCount]int64 {
+	var bands [phashBandCount]int64
+	for i := range bands {
+		bands[i] = int64(uint64(hash) >> (48 - 16*i) & 0xFFFF)
+	}
+	return bands
+}
+
+// bandNeighbors retorna os valores de 16 bits a até radius bits de distância da faixa
+func bandNeighbors(band int64, radius int) pq.Int64Array {
+	neighbors := pq.Int64Array{band}
+	var flip func(value int64, from, left int)
+	flip = func(value int64, from, left int) {
+		for bit := from; bit < 16 && left > 0; bit++ {
+			next := value ^ (1 << bit)
+			neighbors = append(neighbors, next)
+			flip(next, bit+1, left-1)
+		}
+	}
+	flip(band, 0, radius)
+	return neighbors
+}
+
+// DuplicateEntry é um palpite de um grupo de imagens repetidas
+type DuplicateEntry struct {
+	PalpiteID  int       `json:"palpite_id"`
+	UserID     int       `json:"user_id"`
+	AutorNome  string    `json:"autor_nome"`
+	ImgURL     string    `json:"img_url"`
+	Distance   *int      `json:"distance,omitempty"`
+	SameAuthor bool      `json:"same_author"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DuplicateCluster agrupa o palpite original e as cópias da mesma imagem
+type DuplicateCluster struct {
+	Original   DuplicateEntry   `json:"original"`
+	Duplicates []DuplicateEntry `json:"duplicates"`
+}
+
+// ListDuplicateClusters lista os grupos de imagens repetidas, do mais recente para o mais antigo
+func ListDuplicateClusters(limit, offset int) ([]DuplicateCluster, error) {
+	rows, err := database.DB.Query(`
+		WITH clusters AS (
+			SELECT duplicate_of AS original_id, MAX(created_at) AS last_copy
+			FROM palpites
+			WHERE duplicate_of IS NOT NULL
+			GROUP BY duplicate_of
+			ORDER BY last_copy DESC
+			LIMIT $1 OFFSET $2
+		)
+		SELECT c.original_id, p.id, p.user_id, COALESCE(u.nome, ''), COALESCE(p.img_url, ''),
+			p.duplicate_distance, p.created_at
+		FROM clusters c
+		JOIN palpites p ON p.id = c.original_id OR p.duplicate_of = c.original_id
+		LEFT JOIN users u ON u.id = p.user_id
+		ORDER BY c.last_copy DESC, c.original_id, p.created_at`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clusters := []DuplicateCluster{}
+	index := map[int]int{}
+	for rows.Next() {
+		var originalID int
+		var entry DuplicateEntry
+		if err := rows.Scan(&originalID, &entry.PalpiteID, &entry.UserID, &entry.AutorNome, &entry.ImgURL,
+			&entry.Distance, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		i, ok := index[originalID]
+		if !ok {
+			i = len(clusters)
+			index[originalID] = i
+			clusters = append(clusters, DuplicateCluster{Duplicates: []DuplicateEntry{}})
+		}
+		if entry.PalpiteID == originalID {
+			entry.Distance = nil
+			clusters[i].Original = entry
+		} else {
+			clusters[i].Duplicates = append(clusters[i].Duplicates, entry)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range clusters {
+		for j := range clusters[i].Duplicates {
+			clusters[i].Duplicates[j].SameAuthor = clusters[i].Duplicates[j].UserID == clusters[i].Original.UserID
+		}
+	}
+	return clusters, nil
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"math/bits"
+	"testing"
+)
+
+func TestPhashBands(t *testing.T) {
+	tests := []struct {
+		name string
+		hash int64
+		want [phashBandCount]int64
+	}{
+		{"zero", 0, [phashBandCount]int64{0, 0, 0, 0}},
+		{"faixas distintas", 0x0123456789ABCDEF, [phashBandCount]int64{0x0123, 0x4567, 0x89AB, 0xCDEF}},
+		{"bit de sinal na primeira faixa", -1, [phashBandCount]int64{0xFFFF, 0xFFFF, 0xFFFF, 0xFFFF}},
+		{"só o bit mais alto", -1 << 63, [phashBandCount]int64{0x8000, 0, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := phashBands(tt.hash); got != tt.want {
+				t.Errorf("phashBands(%#x) = %#x, esperado %#x", tt.hash, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBandNeighbors(t *testing.T) {
+	tests := []struct {
+		name   string
+		band   int64
+		radius int
+		want   int
+	}{
+		{"raio 0 é só a própria faixa", 0x1234, 0, 1},
+		{"raio 1", 0x1234, 1, 17},
+		{"raio 2", 0xFFFF, 2, 137},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			neighbors := bandNeighbors(tt.band, tt.radius)
+			if len(neighbors) != tt.want {
+				t.Fatalf("bandNeighbors(%#x, %d) tem %d valores, esperado %d", tt.band, tt.radius, len(neighbors), tt.want)
+			}
+			seen := make(map[int64]bool)
+			for _, n := range neighbors {
+				if seen[n] {
+					t.Errorf("valor %#x repetido", n)
+				}
+				seen[n] = true
+				if n < 0 || n > 0xFFFF {
+					t.Errorf("valor %#x fora de 16 bits", n)
+				}
+				if d := bits.OnesCount64(uint64(n ^ tt.band)); d > tt.radius {
+					t.Errorf("valor %#x está a %d bits, raio %d", n, d, tt.radius)
+				}
+			}
+		})
+	}
+}
+
+// Com a distância máxima padrão (6), o raio por faixa é 6/4 = 1: qualquer forma de espalhar
+// 6 bits diferentes pelas 4 faixas deixa alguma faixa com no máximo 1 bit diferente
+func TestBandNeighborsPigeonhole(t *testing.T) {
+	const maxDistance = 6
+	radius := maxDistance / phashBandCount
+	base := int64(0x0F0F33335555AAAA)
+
+	tests := []struct {
+		name string
+		flip uint64
+	}{
+		{"tudo em uma faixa", 0x003F000000000000},
+		{"3 + 3", 0x0007000700000000},
+		{"2 + 2 + 2", 0x0003000300030000},
+		{"2 + 2 + 1 + 1", 0x0003000300010001},
+		{"3 + 1 + 1 + 1", 0x8001800100010001},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := base ^ int64(tt.flip)
+			if d := bits.OnesCount64(uint64(base ^ other)); d != maxDistance {
+				t.Fatalf("caso mal montado: distância %d", d)
+			}
+
+			bands, otherBands := phashBands(base), phashBands(other)
+			found := false
+			for i := range bands {
+				for _, n := range bandNeighbors(bands[i], radius) {
+					if n == otherBands[i] {
+						found = true
+					}
+				}
+			}
+			if !found {
+				t.Errorf("hash a %d bits não é candidato com raio %d por faixa", maxDistance, radius)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"smartpicks-backend/internal/config"
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrPalpiteNotFound   = errors.New("palpite não encontrado")
+	ErrSelectionsSettled = errors.New("o palpite já tem seleções apuradas")
+)
+
+// MatchStartedError indica uma seleção em partida que já começou e não aceita mais palpites
+type MatchStartedError struct {
+	MatchID int
+	Kickoff time.Time
+}
+
+func (e *MatchStartedError) Error() string {
+	return fmt.Sprintf("a partida %d já começou (%s UTC) e não aceita mais palpites",
+		e.MatchID, e.Kickoff.UTC().Format("02/01/2006 15:04"))
+}
+
+// KickoffGrace é a tolerância depois do início em que a partida ainda aceita palpites
+// (PALPITE_KICKOFF_GRACE, padrão 0)
+func KickoffGrace() time.Duration {
+	return config.Duration("PALPITE_KICKOFF_GRACE", 0)
+}
+
+// LateWindow é o tempo antes do início a partir do qual as seleções são marcadas como
+// tardias (PALPITE_LATE_WINDOW, padrão 15m)
+func LateWindow() time.Duration {
+	return config.Duration("PALPITE_LATE_WINDOW", 15*time.Minute)
+}
+
+// checkSelectionsOpen trava as partidas das seleções (FOR SHARE, para o início não mudar até o
+// commit) e confere no relógio do servidor se ainda aceitam palpites. Marca as seleções tardias.
+func checkSelectionsOpen(tx *sql.Tx, selections []models.PalpiteSelection, now time.Time) error {
+	rows, err := tx.Query(matchSelect+" WHERE m.id = ANY($1) FOR SHARE OF m", pq.Array(selectionMatchIDs(selections)))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	matches := map[int]models.Match{}
+	for rows.Next() {
+		m, err := scanMatch(rows)
+		if err != nil {
+			return err
+		}
+		matches[m.ID] = m
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	grace, lateWindow := KickoffGrace(), LateWindow()
+	for i := range selections {
+		s := &selections[i]
+		m, ok := matches[s.MatchID]
+		if !ok {
+			return ErrMatchNotFound
+		}
+		if m.ClosedForPicks(now, grace) {
+			return &MatchStartedError{MatchID: m.ID, Kickoff: m.MatchDate}
+		}
+		untilKickoff := m.MatchDate.Sub(now)
+		minutes := int(untilKickoff.Minutes())
+		s.MinutesBeforeKickoff = &minutes
+		s.Late = untilKickoff < lateWindow
+		s.Match = &m
+	}
+	return nil
+}
+
+// InsertSelections grava as seleções do palpite na transação informada. Seleções em partidas
+// que já começaram (no instante now) são recusadas com *MatchStartedError.
+func InsertSelections(tx *sql.Tx, palpiteID int, selections []models.PalpiteSelection, now time.Time) error {
+	if len(selections) == 0 {
+		return nil
+	}
+	if err := checkSelectionsOpen(tx, selections, now); err != nil {
+		return err
+	}
+
+	for i := range selections {
+		s := &selections[i]
+		s.PalpiteID = palpiteID
+		err := tx.QueryRow(`
+			INSERT INTO palpite_selections (palpite_id, match_id, market, selection, line, odds, stake,
+				late, minutes_before_kickoff, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			RETURNING id, status, created_at`,
+			palpiteID, s.MatchID, s.Market, s.Selection, s.Line, s.Odds, s.Stake,
+			s.Late, s.MinutesBeforeKickoff, now,
+		).Scan(&s.ID, &s.Status, &s.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return invalidateConsensusCache(tx, selectionMatchIDs(selections))
+}
+
+func selectionMatchIDs(selections []models.PalpiteSelection) []int {
+	ids := make([]int, len(selections))
+	for i := range selections {
+		ids[i] = selections[i].MatchID
+	}
+	return ids
+}
+
+// LoadSelections busca as seleções dos palpites, com os dados da partida, agrupadas por palpite
+func LoadSelections(palpiteIDs []int) (map[int][]models.PalpiteSelection, error) {
+	selections := map[int][]models.PalpiteSelection{}
+	if len(palpiteIDs) == 0 {
+		return selections, nil
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, palpite_id, match_id, market, selection, line, odds, stake, status, profit, settled_at,
+			late, minutes_before_kickoff, created_at
+		FROM palpite_selections
+		WHERE palpite_id = ANY($1)
+		ORDER BY palpite_id, id`, pq.Array(palpiteIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matchIDs []int
+	for rows.Next() {
+		var s models.PalpiteSelection
+		if err := rows.Scan(&s.ID, &s.PalpiteID, &s.MatchID, &s.Market, &s.Selection, &s.Line, &s.Odds, &s.Stake,
+			&s.Status, &s.Profit, &s.SettledAt, &s.Late, &s.MinutesBeforeKickoff, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		selections[s.PalpiteID] = append(selections[s.PalpiteID], s)
+		matchIDs = append(matchIDs, s.MatchID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	matches, err := LoadMatches(matchIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, list := range selections {
+		for i := range list {
+			if m, ok := matches[list[i].MatchID]; ok {
+				list[i].Match = &m
+			}
+		}
+	}
+	return selections, nil
+}
+
+// ReplaceSelections troca as seleções de um palpite do usuário. Só é permitido enquanto nenhuma
+// seleção foi apurada e nenhuma das partidas, antigas ou novas, começou; assim uma seleção não
+// pode ser retirada depois que a partida está em andamento.
+func ReplaceSelections(palpiteID, userID int, selections []models.PalpiteSelection, now time.Time) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var ownerID int
+	err = tx.QueryRow("SELECT user_id FROM palpites WHERE id = $1 FOR UPDATE", palpiteID).Scan(&ownerID)
+	if err == sql.ErrNoRows || (err == nil && ownerID != userID) {
+		return ErrPalpiteNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query("SELECT match_id, status FROM palpite_selections WHERE palpite_id = $1", palpiteID)
+	if err != nil {
+		return err
+	}
+	var current []models.PalpiteSelection
+	for rows.Next() {
+		var s models.PalpiteSelection
+		if err := rows.Scan(&s.MatchID, &s.Status); err != nil {
+			rows.Close()
+			return err
+		}
+		current = append(current, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, s := range current {
+		if s.Status != models.PICK_STATUS_PENDING {
+			return ErrSelectionsSettled
+		}
+	}
+	if len(current) > 0 {
+		if err := checkSelectionsOpen(tx, current, now); err != nil {
+			return err
+		}
+		if err := invalidateConsensusCache(tx, selectionMatchIDs(current)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM palpite_selections WHERE palpite_id = $1", palpiteID); err != nil {
+		return err
+	}
+	if err := InsertSelections(tx, palpiteID, selections, now); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE palpites SET status = $1, profit = NULL, updated_at = $2 WHERE id = $3",
+		models.PICK_STATUS_PENDING, now, palpiteID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
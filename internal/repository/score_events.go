@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/settlement"
+)
+
+// Origem dos eventos do feed de placares na tabela webhook_events
+const scoresWebhookSource = "scores"
+
+// Resultado gravado para cada evento recebido
+const (
+	SCORE_EVENT_APPLIED = "applied"
+	SCORE_EVENT_STALE   = "stale"
+)
+
+// ScoreEvent é uma atualização de placar/status enviada pelo feed. Os placares são opcionais
+// fora de finished (ex: partida adiada).
+type ScoreEvent struct {
+	EventID    string    `json:"event_id"`
+	MatchID    int       `json:"match_id"`
+	Status     string    `json:"status"`
+	HomeScore  *int      `json:"home_score"`
+	AwayScore  *int      `json:"away_score"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// ScoreEventResult diz o que foi feito com o evento. Settlement é a apuração disparada por ele
+// (nil quando a partida não precisou ser apurada).
+type ScoreEventResult struct {
+	Duplicate  bool
+	Stale      bool
+	Settlement *SettlementAudit
+}
+
+// ApplyScoreEvent grava o evento do feed, atualiza o placar e o status da partida e, quando ela
+// termina (ou é cancelada), apura os palpites, tudo na mesma transação: se a apuração falhar o
+// evento não fica registrado e o feed pode reenviá-lo. Eventos repetidos (mesmo event_id) são
+// ignorados, e eventos mais antigos que o último aplicado são registrados sem alterar a partida,
+// pois o feed pode entregar fora de ordem.
+func ApplyScoreEvent(ev ScoreEvent, payload []byte) (*ScoreEventResult, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO webhook_events (source, event_id, match_id, payload, result)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (source, event_id) DO NOTHING`,
+		scoresWebhookSource, ev.EventID, ev.MatchID, string(payload), SCORE_EVENT_APPLIED)
+	if IsForeignKeyViolation(err) {
+		return nil, ErrMatchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return &ScoreEventResult{Duplicate: true}, nil
+	}
+
+	var status string
+	var homeScore, awayScore *int
+	var scoreUpdatedAt, settledAt *time.Time
+	err = tx.QueryRow(`
+		SELECT status, home_score, away_score, score_updated_at, settled_at
+		FROM matches WHERE id = $1 FOR UPDATE`, ev.MatchID,
+	).Scan(&status, &homeScore, &awayScore, &scoreUpdatedAt, &settledAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrMatchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if scoreUpdatedAt != nil && ev.OccurredAt.Before(*scoreUpdatedAt) {
+		if _, err := tx.Exec("UPDATE webhook_events SET result = $1 WHERE source = $2 AND event_id = $3",
+			SCORE_EVENT_STALE, scoresWebhookSource, ev.EventID); err != nil {
+			return nil, err
+		}
+		return &ScoreEventResult{Stale: true}, tx.Commit()
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE matches SET status = $1, home_score = COALESCE($2, home_score), away_score = COALESCE($3, away_score),
+			score_updated_at = $4
+		WHERE id = $5`, ev.Status, ev.HomeScore, ev.AwayScore, ev.OccurredAt, ev.MatchID); err != nil {
+		return nil, err
+	}
+
+	var settle *SettleRequest
+	reason := fmt.Sprintf("feed de placares (evento %s)", ev.EventID)
+	switch ev.Status {
+	case models.MATCH_STATUS_FINISHED:
+		score := settlement.Score{Home: *ev.HomeScore, Away: *ev.AwayScore}
+		// Depois de apurada, só uma correção do placar final reapura a partida
+		corrected := settledAt != nil && (homeScore == nil || awayScore == nil ||
+			*homeScore != score.Home || *awayScore != score.Away || status != models.MATCH_STATUS_FINISHED)
+		if settledAt == nil || corrected {
+			settle = &SettleRequest{MatchID: ev.MatchID, Score: &score, Reason: reason, Resettle: corrected}
+		}
+	case models.MATCH_STATUS_CANCELLED:
+		if settledAt == nil || status != models.MATCH_STATUS_CANCELLED {
+			settle = &SettleRequest{MatchID: ev.MatchID, Reason: reason, Resettle: settledAt != nil}
+		}
+	}
+
+	res := &ScoreEventResult{}
+	if settle != nil {
+		if res.Settlement, err = SettleMatchTx(tx, *settle); err != nil {
+			return nil, err
+		}
+	}
+	return res, tx.Commit()
+}
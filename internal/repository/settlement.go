@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/settlement"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrMatchNotFound   = errors.New("partida não encontrada")
+	ErrAlreadySettled  = errors.New("partida já apurada")
+	ErrMatchNotStarted = errors.New("partida ainda não começou")
+)
+
+// SettleRequest descreve uma apuração. Sem Score (Void) todas as seleções da partida são
+// devolvidas, como em jogos cancelados. SettledBy é nil nas apurações automáticas.
+type SettleRequest struct {
+	MatchID   int
+	Score     *settlement.Score
+	SettledBy *int
+	Reason    string
+	// Resettle permite apurar de novo uma partida já apurada (ex: placar corrigido) e apurar
+	// com placar uma partida cuja data ainda não chegou
+	Resettle bool
+}
+
+// SelectionChange registra a mudança de resultado de uma seleção em uma apuração
+type SelectionChange struct {
+	SelectionID int      `json:"selection_id"`
+	PalpiteID   int      `json:"palpite_id"`
+	OldStatus   string   `json:"old_status"`
+	NewStatus   string   `json:"new_status"`
+	OldProfit   *float64 `json:"old_profit,omitempty"`
+	NewProfit   float64  `json:"new_profit"`
+}
+
+// SettlementAudit é uma entrada da trilha de auditoria das apurações
+type SettlementAudit struct {
+	ID         int               `json:"id"`
+	MatchID    int               `json:"match_id"`
+	HomeScore  *int              `json:"home_score,omitempty"`
+	AwayScore  *int              `json:"away_score,omitempty"`
+	Void       bool              `json:"void"`
+	Resettle   bool              `json:"resettle"`
+	SettledBy  *int              `json:"settled_by,omitempty"`
+	Reason     string            `json:"reason,omitempty"`
+	Selections int               `json:"selections"`
+	Palpites   int               `json:"palpites"`
+	Changes    []SelectionChange `json:"changes"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// SettleMatch grava o placar e o status da partida (finished, ou cancelled sem placar), apura
+// todas as seleções ligadas a ela, atualiza o resultado e o lucro dos palpites afetados e
+// registra a auditoria, tudo em uma transação
+func SettleMatch(req SettleRequest) (*SettlementAudit, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	audit, err := SettleMatchTx(tx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return audit, nil
+}
+
+// SettleMatchTx é o SettleMatch dentro da transação informada (ex: junto com o evento do feed
+// de placares que disparou a apuração). Quem chama faz o commit.
+func SettleMatchTx(tx *sql.Tx, req SettleRequest) (*SettlementAudit, error) {
+	if req.Score != nil {
+		if err := req.Score.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	var settledAt *time.Time
+	var matchDate time.Time
+	err := tx.QueryRow("SELECT settled_at, match_date FROM matches WHERE id = $1 FOR UPDATE", req.MatchID).
+		Scan(&settledAt, &matchDate)
+	if err == sql.ErrNoRows {
+		return nil, ErrMatchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if settledAt != nil && !req.Resettle {
+		return nil, ErrAlreadySettled
+	}
+	// Um placar antes do início é quase sempre a partida errada; o void (jogo cancelado) pode
+	if req.Score != nil && !req.Resettle && matchDate.After(time.Now()) {
+		return nil, ErrMatchNotStarted
+	}
+
+	audit := SettlementAudit{
+		MatchID:   req.MatchID,
+		Void:      req.Score == nil,
+		Resettle:  settledAt != nil,
+		SettledBy: req.SettledBy,
+		Reason:    req.Reason,
+		Changes:   []SelectionChange{},
+	}
+	if req.Score != nil {
+		audit.HomeScore, audit.AwayScore = &req.Score.Home, &req.Score.Away
+	}
+
+	status := models.MATCH_STATUS_FINISHED
+	if audit.Void {
+		status = models.MATCH_STATUS_CANCELLED
+	}
+	if _, err := tx.Exec(`
+		UPDATE matches SET home_score = $1, away_score = $2, status = $3, settled_at = CURRENT_TIMESTAMP
+		WHERE id = $4`, audit.HomeScore, audit.AwayScore, status, req.MatchID); err != nil {
+		return nil, err
+	}
+
+	selections, err := loadMatchSelections(tx, req.MatchID)
+	if err != nil {
+		return nil, err
+	}
+
+	palpiteIDs := []int{}
+	seen := map[int]bool{}
+	for _, s := range selections {
+		status := models.PICK_STATUS_VOID
+		if req.Score != nil {
+			if status, err = settlement.SettleSelection(s, *req.Score); err != nil {
+				return nil, err
+			}
+		}
+		profit := settlement.Profit(status, s.Odds, s.Stake)
+
+		if status != s.Status || s.Profit == nil || *s.Profit != profit {
+			audit.Changes = append(audit.Changes, SelectionChange{
+				SelectionID: s.ID, PalpiteID: s.PalpiteID,
+				OldStatus: s.Status, NewStatus: status,
+				OldProfit: s.Profit, NewProfit: profit,
+			})
+		}
+		if _, err := tx.Exec(`
+			UPDATE palpite_selections SET status = $1, profit = $2, settled_at = CURRENT_TIMESTAMP
+			WHERE id = $3`, status, profit, s.ID); err != nil {
+			return nil, err
+		}
+
+		if !seen[s.PalpiteID] {
+			seen[s.PalpiteID] = true
+			palpiteIDs = append(palpiteIDs, s.PalpiteID)
+		}
+	}
+	audit.Selections = len(selections)
+	audit.Palpites = len(palpiteIDs)
+
+	if err := updatePalpiteResults(tx, palpiteIDs); err != nil {
+		return nil, err
+	}
+	if err := invalidateStatsCache(tx, palpiteIDs); err != nil {
+		return nil, err
+	}
+	if err := invalidateConsensusCache(tx, []int{req.MatchID}); err != nil {
+		return nil, err
+	}
+
+	changes, err := json.Marshal(audit.Changes)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.QueryRow(`
+		INSERT INTO settlement_audit (match_id, home_score, away_score, void, resettle, settled_by, reason,
+			selections, palpites, changes)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10)
+		RETURNING id, created_at`,
+		audit.MatchID, audit.HomeScore, audit.AwayScore, audit.Void, audit.Resettle, audit.SettledBy, audit.Reason,
+		audit.Selections, audit.Palpites, changes,
+	).Scan(&audit.ID, &audit.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &audit, nil
+}
+
+// loadMatchSelections busca (e bloqueia) as seleções ligadas à partida
+func loadMatchSelections(tx *sql.Tx, matchID int) ([]models.PalpiteSelection, error) {
+	rows, err := tx.Query(`
+		SELECT id, palpite_id, match_id, market, selection, line, odds, stake, status, profit
+		FROM palpite_selections WHERE match_id = $1
+		ORDER BY id
+		FOR UPDATE`, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var selections []models.PalpiteSelection
+	for rows.Next() {
+		var s models.PalpiteSelection
+		if err := rows.Scan(&s.ID, &s.PalpiteID, &s.MatchID, &s.Market, &s.Selection, &s.Line, &s.Odds, &s.Stake,
+			&s.Status, &s.Profit); err != nil {
+			return nil, err
+		}
+		selections = append(selections, s)
+	}
+	return selections, rows.Err()
+}
+
+// updatePalpiteResults recalcula o resultado e o lucro dos palpites a partir de todas as suas
+// seleções (um palpite pode ter seleções em outras partidas ainda não apuradas)
+func updatePalpiteResults(tx *sql.Tx, palpiteIDs []int) error {
+	if len(palpiteIDs) == 0 {
+		return nil
+	}
+
+	rows, err := tx.Query(`
+		SELECT palpite_id, status, profit FROM palpite_selections
+		WHERE palpite_id = ANY($1)
+		ORDER BY palpite_id, id`, pq.Array(palpiteIDs))
+	if err != nil {
+		return err
+	}
+	byPalpite := map[int][]models.PalpiteSelection{}
+	for rows.Next() {
+		var s models.PalpiteSelection
+		if err := rows.Scan(&s.PalpiteID, &s.Status, &s.Profit); err != nil {
+			rows.Close()
+			return err
+		}
+		byPalpite[s.PalpiteID] = append(byPalpite[s.PalpiteID], s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range palpiteIDs {
+		status, profit := settlement.Combine(byPalpite[id])
+		if status == models.PICK_STATUS_PENDING {
+			_, err = tx.Exec(`UPDATE palpites SET status = $1, profit = NULL, settled_at = NULL WHERE id = $2`, status, id)
+		} else {
+			_, err = tx.Exec(`UPDATE palpites SET status = $1, profit = $2, settled_at = CURRENT_TIMESTAMP WHERE id = $3`,
+				status, profit, id)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListSettlementAudit retorna a trilha de auditoria das apurações da partida, da mais recente
+// para a mais antiga
+func ListSettlementAudit(matchID int) ([]SettlementAudit, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, match_id, home_score, away_score, void, resettle, settled_by, COALESCE(reason, ''),
+			selections, palpites, changes, created_at
+		FROM settlement_audit WHERE match_id = $1
+		ORDER BY created_at DESC, id DESC`, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	audits := []SettlementAudit{}
+	for rows.Next() {
+		var a SettlementAudit
+		var changes []byte
+		if err := rows.Scan(&a.ID, &a.MatchID, &a.HomeScore, &a.AwayScore, &a.Void, &a.Resettle, &a.SettledBy,
+			&a.Reason, &a.Selections, &a.Palpites, &changes, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(changes, &a.Changes); err != nil {
+			return nil, err
+		}
+		audits = append(audits, a)
+	}
+	return audits, rows.Err()
+}
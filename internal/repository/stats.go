@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"smartpicks-backend/internal/config"
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/stats"
+
+	"github.com/lib/pq"
+)
+
+// StatsBankroll é a banca, em unidades, usada no cálculo do ROI (STATS_BANKROLL_UNITS)
+func StatsBankroll() float64 {
+	return float64(config.Int("STATS_BANKROLL_UNITS", 100))
+}
+
+// statsCacheTTL é a validade máxima das estatísticas em cache (STATS_CACHE_TTL). A apuração de
+// uma partida já invalida o cache dos autores afetados; o TTL é só uma garantia extra.
+func statsCacheTTL() time.Duration {
+	return config.Duration("STATS_CACHE_TTL", 10*time.Minute)
+}
+
+// StatsRange filtra as estatísticas pela data das partidas. From e To são inclusivos; nil não filtra.
+type StatsRange struct {
+	From *time.Time
+	To   *time.Time
+}
+
+func (r StatsRange) key() string {
+	key := "all"
+	if r.From != nil {
+		key = r.From.Format("2006-01-02")
+	}
+	key += ":"
+	if r.To != nil {
+		key += r.To.Format("2006-01-02")
+	}
+	return key
+}
+
+// GetTipsterStats retorna as estatísticas do usuário no período, usando o cache quando válido.
+// O cache guarda a versão de user_stats_versions lida antes do cálculo; como a apuração incrementa
+// a versão na mesma transação em que muda as seleções, um cálculo que concorreu com ela fica com
+// uma versão antiga e nunca é servido.
+func GetTipsterStats(userID int, r StatsRange) (*stats.TipsterStats, time.Time, error) {
+	var version int64
+	err := database.DB.QueryRow(`
+		SELECT COALESCE((SELECT version FROM user_stats_versions WHERE user_id = $1), 0)`, userID).Scan(&version)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var data []byte
+	var computedAt time.Time
+	err = database.DB.QueryRow(`
+		SELECT stats, computed_at FROM user_stats_cache
+		WHERE user_id = $1 AND range_key = $2 AND version = $3 AND computed_at > $4`,
+		userID, r.key(), version, time.Now().Add(-statsCacheTTL())).Scan(&data, &computedAt)
+	if err == nil {
+		var cached stats.TipsterStats
+		if err := json.Unmarshal(data, &cached); err == nil {
+			return &cached, computedAt, nil
+		}
+	} else if err != sql.ErrNoRows {
+		return nil, time.Time{}, err
+	}
+
+	picks, err := loadSettledPicks(userID, r)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	result := stats.Compute(picks, StatsBankroll())
+	computedAt = time.Now()
+
+	if data, err = json.Marshal(result); err == nil {
+		_, err = database.DB.Exec(`
+			INSERT INTO user_stats_cache (user_id, range_key, stats, computed_at, version)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (user_id, range_key) DO UPDATE
+			SET stats = EXCLUDED.stats, computed_at = EXCLUDED.computed_at, version = EXCLUDED.version`,
+			userID, r.key(), data, computedAt, version)
+	}
+	if err != nil {
+		log.Printf("Erro ao gravar estatísticas do usuário %d em cache: %v", userID, err)
+	}
+	return &result, computedAt, nil
+}
+
+// loadSettledPicks busca as seleções apuradas do usuário em ordem cronológica
+func loadSettledPicks(userID int, r StatsRange) ([]stats.Pick, error) {
+	var to *time.Time
+	if r.To != nil {
+		end := r.To.AddDate(0, 0, 1)
+		to = &end
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT s.market, COALESCE(c.name, ''), s.status, s.odds, s.stake, COALESCE(s.profit, 0)
+		FROM palpite_selections s
+		JOIN palpites p ON p.id = s.palpite_id
+		JOIN matches m ON m.id = s.match_id
+		LEFT JOIN competitions c ON c.id = m.competition_id
+		WHERE p.user_id = $1 AND s.status <> $2
+			AND ($3::timestamptz IS NULL OR m.match_date >= $3)
+			AND ($4::timestamptz IS NULL OR m.match_date < $4)
+		ORDER BY m.match_date, s.id`, userID, models.PICK_STATUS_PENDING, r.From, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var picks []stats.Pick
+	for rows.Next() {
+		var p stats.Pick
+		if err := rows.Scan(&p.Market, &p.Competition, &p.Status, &p.Odds, &p.Stake, &p.Profit); err != nil {
+			return nil, err
+		}
+		if p.Competition == "" {
+			p.Competition = "Sem competição"
+		}
+		picks = append(picks, p)
+	}
+	return picks, rows.Err()
+}
+
+// invalidateStatsCache descarta as estatísticas em cache dos autores dos palpites, incrementando
+// a versão deles. Os autores são travados em ordem para que apurações concorrentes não se bloqueiem.
+func invalidateStatsCache(tx *sql.Tx, palpiteIDs []int) error {
+	if len(palpiteIDs) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(`
+		INSERT INTO user_stats_versions (user_id, version)
+		SELECT DISTINCT user_id, 1 FROM palpites WHERE id = ANY($1)
+		ORDER BY user_id
+		ON CONFLICT (user_id) DO UPDATE SET version = user_stats_versions.version + 1`, pq.Array(palpiteIDs))
+	return err
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"smartpicks-backend/internal/services"
+)
+
+var fileStorage services.Storage
+
+// SetStorage define onde ficam os arquivos enviados pelos usuários (configurado no startup)
+func SetStorage(s services.Storage) {
+	fileStorage = s
+}
+
+// DeleteStoredFiles apaga do storage os arquivos do usuário. Falhas são apenas registradas no log,
+// pois as referências aos arquivos já foram removidas do banco. Só são apagadas chaves das pastas
+// do próprio usuário (avatars/<id>/, palpites/<id>/ e exports/<id>/).
+func DeleteStoredFiles(userID int, files []string) {
+	deleteOwnedFiles(userID, files, userStoragePrefixes(userID))
+}
+
+// DeleteAvatarFiles apaga do storage arquivos de avatar do usuário: só chaves em avatars/<id>/
+func DeleteAvatarFiles(userID int, files []string) {
+	deleteOwnedFiles(userID, files, []string{avatarStoragePrefix(userID)})
+}
+
+// avatarStoragePrefix é a pasta do storage com os avatares do usuário
+func avatarStoragePrefix(userID int) string {
+	return fmt.Sprintf("avatars/%d/", userID)
+}
+
+// userStoragePrefixes são as pastas do storage com arquivos gerados para o usuário
+func userStoragePrefixes(userID int) []string {
+	return []string{
+		avatarStoragePrefix(userID),
+		fmt.Sprintf("palpites/%d/", userID),
+		fmt.Sprintf("exports/%d/", userID),
+	}
+}
+
+// deleteOwnedFiles apaga os arquivos cujas chaves estão em uma das pastas informadas. URLs de
+// outros domínios e chaves fora dessas pastas (de outro usuário, por exemplo) são ignoradas.
+func deleteOwnedFiles(userID int, files []string, prefixes []string) {
+	if len(files) == 0 {
+		return
+	}
+	if fileStorage == nil {
+		log.Printf("Arquivos do usuário %d não foram removidos: storage não configurado", userID)
+		return
+	}
+
+	for _, file := range files {
+		// Avatares antigos podem estar salvos em base64 no próprio banco
+		if strings.HasPrefix(file, "data:") {
+			continue
+		}
+		key, ok := fileStorage.KeyFromURL(file)
+		if !ok {
+			continue
+		}
+		if !ownsStorageKey(key, prefixes) {
+			log.Printf("Arquivo %s não foi removido: não pertence ao usuário %d", key, userID)
+			continue
+		}
+		if err := fileStorage.Delete(key); err != nil {
+			log.Printf("Erro ao remover arquivo %s do usuário %d: %v", key, userID, err)
+		}
+	}
+}
+
+// ownsStorageKey indica se a chave está em uma das pastas. Chaves com "..", barras repetidas
+// etc. são recusadas para que não escapem da pasta depois de normalizadas.
+func ownsStorageKey(key string, prefixes []string) bool {
+	if path.Clean(key) != key {
+		return false
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
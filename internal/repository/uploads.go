@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"time"
+
+	"smartpicks-backend/internal/config"
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/models"
+)
+
+// uploadClaimTimeout é o prazo para um upload direto concluído ser usado em um palpite
+// (UPLOAD_CLAIM_TIMEOUT). Depois disso o original é apagado.
+func uploadClaimTimeout() time.Duration {
+	return config.Duration("UPLOAD_CLAIM_TIMEOUT", 24*time.Hour)
+}
+
+// PurgeStaleUploads remove os uploads diretos abandonados e seus arquivos no storage: os que
+// ficaram pendentes além da validade da URL pré-assinada, os concluídos que não foram usados
+// em um palpite dentro de UPLOAD_CLAIM_TIMEOUT e os já usados cujo original não pôde ser apagado
+// na criação do palpite. Os arquivos são os originais enviados pelo cliente, ainda com os
+// metadados (EXIF, GPS). Retorna quantos uploads foram removidos.
+func PurgeStaleUploads() (int, error) {
+	rows, err := database.DB.Query(`
+		DELETE FROM uploads
+		WHERE (status = $1 AND expires_at < CURRENT_TIMESTAMP)
+		   OR (status = $2 AND palpite_id IS NULL AND completed_at < $3)
+		   OR palpite_id IS NOT NULL
+		RETURNING user_id, storage_key`,
+		models.UPLOAD_STATUS_PENDING, models.UPLOAD_STATUS_COMPLETED, time.Now().Add(-uploadClaimTimeout()))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	purged := 0
+	files := map[int][]string{}
+	for rows.Next() {
+		var userID int
+		var key string
+		if err := rows.Scan(&userID, &key); err != nil {
+			return 0, err
+		}
+		files[userID] = append(files[userID], key)
+		purged++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for userID, keys := range files {
+		DeleteStoredFiles(userID, keys)
+	}
+	return purged, nil
+}
@@ -0,0 +1,66 @@
+// Package repository concentra o acesso a dados que exige tratamento além de uma query simples
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"smartpicks-backend/internal/database"
+	"smartpicks-backend/internal/services"
+)
+
+var ErrCipherNotConfigured = errors.New("criptografia de dados não configurada")
+
+var fieldCipher *services.FieldCipher
+
+// SetFieldCipher define o cipher usado para os campos sensíveis (configurado no startup)
+func SetFieldCipher(c *services.FieldCipher) {
+	fieldCipher = c
+}
+
+// EncryptCPF cifra o CPF (já normalizado) e calcula o hash usado nas buscas e na unicidade
+func EncryptCPF(cpf string) (encrypted, hash string, err error) {
+	if fieldCipher == nil {
+		return "", "", ErrCipherNotConfigured
+	}
+	encrypted, err = fieldCipher.Encrypt(cpf)
+	if err != nil {
+		return "", "", err
+	}
+	return encrypted, fieldCipher.Hash(cpf), nil
+}
+
+// DecryptCPF decifra o valor da coluna users.cpf_encrypted
+func DecryptCPF(encrypted string) (string, error) {
+	if fieldCipher == nil {
+		return "", ErrCipherNotConfigured
+	}
+	return fieldCipher.Decrypt(encrypted)
+}
+
+// CPFExists indica se o CPF (já normalizado) pertence a algum usuário. Também considera
+// cadastros antigos que ainda não foram cifrados pelo comando reencrypt-cpf.
+func CPFExists(cpf string) (bool, error) {
+	if fieldCipher == nil {
+		return false, ErrCipherNotConfigured
+	}
+	var exists bool
+	err := database.DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM users WHERE cpf_hash = $1 OR cpf = $2)`,
+		fieldCipher.Hash(cpf), cpf).Scan(&exists)
+	return exists, err
+}
+
+// UserCPF busca e decifra o CPF de um usuário. Retorna "" se o usuário não tiver CPF.
+func UserCPF(userID int) (string, error) {
+	var plain, encrypted sql.NullString
+	err := database.DB.QueryRow("SELECT cpf, cpf_encrypted FROM users WHERE id = $1", userID).
+		Scan(&plain, &encrypted)
+	if err != nil {
+		return "", err
+	}
+	if encrypted.Valid && encrypted.String != "" {
+		return DecryptCPF(encrypted.String)
+	}
+	return plain.String, nil
+}
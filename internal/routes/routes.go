@@ -1,10 +1,14 @@
 package routes
 
 import (
+	"log"
 	"net/http"
 
 	"smartpicks-backend/internal/database"
 	"smartpicks-backend/internal/handlers"
+	"smartpicks-backend/internal/models"
+	"smartpicks-backend/internal/repository"
+	"smartpicks-backend/internal/services"
 
 	"github.com/gorilla/mux"
 )
@@ -20,8 +24,8 @@ func enableCORS(next http.Handler) http.Handler {
 		if allowed[origin] {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 		}
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Origin, Accept, X-User-ID")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Origin, Accept")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -35,24 +39,83 @@ func enableCORS(next http.Handler) http.Handler {
 func RegisterRoutes(r *mux.Router) {
 	database.Connect()
 
+	mailer, err := services.NewMailerFromEnv()
+	if err != nil {
+		log.Fatal("Erro ao configurar envio de emails:", err)
+	}
+	handlers.SetMailer(mailer)
+
+	loginGuard, err := services.NewLoginGuardFromEnv(database.DB)
+	if err != nil {
+		log.Fatal("Erro ao configurar controle de tentativas de login:", err)
+	}
+	handlers.SetLoginGuard(loginGuard)
+
+	fieldCipher, err := services.NewFieldCipherFromEnv()
+	if err != nil {
+		log.Fatal("Erro ao configurar criptografia de dados:", err)
+	}
+	repository.SetFieldCipher(fieldCipher)
+
+	storage, err := services.NewStorageFromEnv()
+	if err != nil {
+		log.Fatal("Erro ao configurar armazenamento de arquivos:", err)
+	}
+	handlers.SetStorage(storage)
+	repository.SetStorage(storage)
+
+	scoresSigner, err := services.NewWebhookSignerFromEnv()
+	if err != nil {
+		log.Fatal("Erro ao configurar webhook de placares:", err)
+	}
+	handlers.SetScoresWebhookSigner(scoresSigner)
+
 	r.Use(enableCORS)
 
 	api := r.PathPrefix("/api").Subrouter()
+	api.Use(handlers.AuthMiddleware)
 
 	api.HandleFunc("/login", handlers.Login).Methods("POST", "OPTIONS")
 	api.HandleFunc("/register", handlers.Register).Methods("POST", "OPTIONS")
+	api.HandleFunc("/auth/refresh", handlers.RefreshToken).Methods("POST", "OPTIONS")
+	api.HandleFunc("/auth/logout", handlers.Logout).Methods("POST", "OPTIONS")
+	api.HandleFunc("/auth/forgot-password", handlers.ForgotPassword).Methods("POST", "OPTIONS")
+	api.HandleFunc("/auth/reset-password", handlers.ResetPassword).Methods("POST", "OPTIONS")
+	api.HandleFunc("/auth/verify-email", handlers.VerifyEmail).Methods("GET", "OPTIONS")
+	api.HandleFunc("/auth/confirm-email-change", handlers.ConfirmEmailChange).Methods("GET", "OPTIONS")
+	api.HandleFunc("/webhooks/scores", handlers.ScoresWebhook).Methods("POST", "OPTIONS")
+	api.HandleFunc("/auth/2fa/setup", handlers.SetupTwoFactor).Methods("POST", "OPTIONS")
+	api.HandleFunc("/auth/2fa/confirm", handlers.ConfirmTwoFactor).Methods("POST", "OPTIONS")
+	api.HandleFunc("/auth/2fa/verify", handlers.VerifyTwoFactor).Methods("POST", "OPTIONS")
+	api.HandleFunc("/auth/2fa/disable", handlers.DisableTwoFactor).Methods("POST", "OPTIONS")
+	api.HandleFunc("/auth/2fa/recovery-codes", handlers.RegenerateRecoveryCodes).Methods("POST", "OPTIONS")
 	api.HandleFunc("/users", handlers.GetAllUsers).Methods("GET", "OPTIONS")
 	api.HandleFunc("/users/permissions", handlers.CheckUserPermissions).Methods("GET", "OPTIONS")
 	api.HandleFunc("/users/profile", handlers.GetUsersByProfile).Methods("GET", "OPTIONS")
 	api.HandleFunc("/users/avatar", handlers.UpdateAvatar).Methods("POST", "PUT", "OPTIONS")
 	api.HandleFunc("/users/avatar", handlers.DeleteAvatar).Methods("DELETE", "OPTIONS")
+	api.HandleFunc("/users/me", handlers.UpdateMyProfile).Methods("PATCH", "OPTIONS")
+	api.HandleFunc("/users/me", handlers.DeleteMyAccount).Methods("DELETE", "OPTIONS")
+	api.HandleFunc("/users/me/password", handlers.ChangeMyPassword).Methods("POST", "OPTIONS")
+	api.HandleFunc("/users/me/deletion/cancel", handlers.CancelMyAccountDeletion).Methods("POST", "OPTIONS")
+	api.HandleFunc("/users/me/export", handlers.ExportMyData).Methods("GET", "OPTIONS")
+	api.HandleFunc("/users/me/exports/{id}", handlers.GetMyDataExport).Methods("GET", "OPTIONS")
+	api.HandleFunc("/exports/download", handlers.DownloadDataExport).Methods("GET", "OPTIONS")
+	api.HandleFunc("/users/me/sessions", handlers.GetMySessions).Methods("GET", "OPTIONS")
+	api.HandleFunc("/users/me/sessions/{id}", handlers.RevokeMySession).Methods("DELETE", "OPTIONS")
 	api.HandleFunc("/users/{id}/palpites", handlers.GetPalpitesByUserID).Methods("GET", "OPTIONS")
+	api.HandleFunc("/users/{id}/stats", handlers.GetUserStats).Methods("GET", "OPTIONS")
 	api.HandleFunc("/users/{id}", handlers.GetUserByID).Methods("GET", "OPTIONS")
 	api.HandleFunc("/matches", handlers.GetAllMatches).Methods("GET", "OPTIONS")
+	api.HandleFunc("/matches/{id:[0-9]+}", handlers.GetMatchByID).Methods("GET", "OPTIONS")
+	api.HandleFunc("/matches/{id:[0-9]+}/consensus", handlers.GetMatchConsensus).Methods("GET", "OPTIONS")
+	api.HandleFunc("/competitions", handlers.GetCompetitions).Methods("GET", "OPTIONS")
+	api.HandleFunc("/teams", handlers.GetTeams).Methods("GET", "OPTIONS")
 
 	api.HandleFunc("/palpites/stats", handlers.GetAllPalpitesWithStats).Methods("GET", "OPTIONS")
 	api.HandleFunc("/palpites/{id}/stats", handlers.GetPalpiteStats).Methods("GET", "OPTIONS")
 	api.HandleFunc("/palpites/{id}/react", handlers.TogglePalpiteReaction).Methods("POST", "OPTIONS")
+	api.HandleFunc("/palpites/{id}/selections", handlers.UpdatePalpiteSelections).Methods("PUT", "OPTIONS")
 	api.HandleFunc("/palpites/{id}/comentarios", handlers.GetComentariosByPalpite).Methods("GET", "OPTIONS")
 	api.HandleFunc("/palpites/{id}", handlers.GetPalpiteByID).Methods("GET", "OPTIONS")
 	api.HandleFunc("/palpites", handlers.GetPalpites).Methods("GET", "OPTIONS")
@@ -63,7 +126,53 @@ func RegisterRoutes(r *mux.Router) {
 	api.HandleFunc("/comentarios/{id}", handlers.DeleteComentario).Methods("DELETE", "OPTIONS")
 	api.HandleFunc("/comentarios", handlers.CreateComentario).Methods("POST", "OPTIONS")
 
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Handle("/users/{id}/unlock",
+		handlers.RequirePermission(models.PERMISSION_USERS_UNLOCK)(http.HandlerFunc(handlers.UnlockUserLogin))).
+		Methods("POST", "OPTIONS")
+	admin.Handle("/users/{id}/perfil",
+		handlers.RequirePermission(models.PERMISSION_USERS_MANAGE_ROLES)(http.HandlerFunc(handlers.UpdateUserPerfil))).
+		Methods("PUT", "OPTIONS")
+	admin.Handle("/accounts/purge",
+		handlers.RequirePermission(models.PERMISSION_USERS_DELETE)(http.HandlerFunc(handlers.PurgeDeletedAccounts))).
+		Methods("POST", "OPTIONS")
+	admin.Handle("/uploads/purge",
+		handlers.RequirePermission(models.PERMISSION_USERS_DELETE)(http.HandlerFunc(handlers.PurgeUploads))).
+		Methods("POST", "OPTIONS")
+	admin.Handle("/exports/process",
+		handlers.RequirePermission(models.PERMISSION_USERS_READ_PRIVATE)(http.HandlerFunc(handlers.RunDataExports))).
+		Methods("POST", "OPTIONS")
+	manageMatches := handlers.RequirePermission(models.PERMISSION_MATCHES_MANAGE)
+	admin.Handle("/matches", manageMatches(http.HandlerFunc(handlers.CreateMatch))).Methods("POST", "OPTIONS")
+	admin.Handle("/matches/{id}", manageMatches(http.HandlerFunc(handlers.UpdateMatch))).Methods("PUT", "OPTIONS")
+	admin.Handle("/matches/{id}", manageMatches(http.HandlerFunc(handlers.DeleteMatch))).Methods("DELETE", "OPTIONS")
+	admin.Handle("/competitions", manageMatches(http.HandlerFunc(handlers.SaveCompetition))).Methods("POST", "OPTIONS")
+	admin.Handle("/competitions/{id}", manageMatches(http.HandlerFunc(handlers.SaveCompetition))).Methods("PUT", "OPTIONS")
+	admin.Handle("/competitions/{id}", manageMatches(http.HandlerFunc(handlers.DeleteCompetition))).Methods("DELETE", "OPTIONS")
+	admin.Handle("/teams", manageMatches(http.HandlerFunc(handlers.SaveTeam))).Methods("POST", "OPTIONS")
+	admin.Handle("/teams/{id}", manageMatches(http.HandlerFunc(handlers.SaveTeam))).Methods("PUT", "OPTIONS")
+	admin.Handle("/teams/{id}", manageMatches(http.HandlerFunc(handlers.DeleteTeam))).Methods("DELETE", "OPTIONS")
+	admin.Handle("/teams/{id}/aliases", manageMatches(http.HandlerFunc(handlers.AddTeamAlias))).Methods("POST", "OPTIONS")
+	admin.Handle("/teams/{id}/aliases/{alias}", manageMatches(http.HandlerFunc(handlers.DeleteTeamAlias))).Methods("DELETE", "OPTIONS")
+	admin.Handle("/fixtures/import", manageMatches(http.HandlerFunc(handlers.ImportFixtures))).Methods("POST", "OPTIONS")
+	admin.Handle("/matches/{id}/settle",
+		handlers.RequirePermission(models.PERMISSION_PALPITES_SETTLE)(http.HandlerFunc(handlers.SettleMatch))).
+		Methods("POST", "OPTIONS")
+	admin.Handle("/matches/{id}/settlements",
+		handlers.RequirePermission(models.PERMISSION_PALPITES_SETTLE)(http.HandlerFunc(handlers.GetMatchSettlements))).
+		Methods("GET", "OPTIONS")
+	admin.Handle("/palpites/duplicates",
+		handlers.RequirePermission(models.PERMISSION_PALPITES_MODERATE)(http.HandlerFunc(handlers.GetDuplicatePalpites))).
+		Methods("GET", "OPTIONS")
+
 	api.HandleFunc("/upload", handlers.UploadImageHandler).Methods("POST", "OPTIONS")
+	api.HandleFunc("/uploads/presign", handlers.PresignUpload).Methods("POST", "OPTIONS")
+	api.HandleFunc("/uploads/{key:.+}/complete", handlers.CompleteUpload).Methods("POST", "OPTIONS")
+
+	// Com STORAGE_DRIVER=local os arquivos enviados são servidos pela própria API
+	if local, ok := storage.(*services.LocalStorage); ok {
+		r.PathPrefix("/uploads/").Handler(http.StripPrefix("/uploads/", local.Handler())).Methods("GET", "HEAD")
+	}
 
 	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"smartpicks-backend/internal/imaging"
+)
+
+// AvatarSizes são os lados (em pixels) das versões geradas de cada avatar
+var AvatarSizes = []int{64, 128, 512}
+
+// AvatarDefaultSize é o tamanho salvo em users.avatar e exibido nas listagens
+const AvatarDefaultSize = 128
+
+// MaxAvatarBytes é o tamanho máximo aceito para o arquivo original do avatar
+const MaxAvatarBytes = 5 << 20
+
+const avatarJPEGQuality = 85
+
+// ErrInvalidAvatar indica que o arquivo enviado não é uma imagem aceita (erro do cliente)
+var ErrInvalidAvatar = errors.New("avatar inválido")
+
+// UploadAvatar valida a imagem enviada, gera as versões quadradas em AvatarSizes (JPEG) e as
+// grava no storage. Retorna a URL de cada versão indexada pelo tamanho.
+func UploadAvatar(storage Storage, userID int, data []byte) (map[string]string, error) {
+	if len(data) > MaxAvatarBytes {
+		return nil, fmt.Errorf("%w: arquivo muito grande. Máximo 5MB", ErrInvalidAvatar)
+	}
+
+	img, _, err := imaging.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAvatar, err)
+	}
+
+	// O timestamp no nome evita que CDNs e navegadores sirvam o avatar anterior do cache
+	timestamp := time.Now().UnixNano()
+	variants := make(map[string]string, len(AvatarSizes))
+	var keys []string
+	for _, size := range AvatarSizes {
+		encoded, err := imaging.EncodeJPEG(imaging.SquareThumbnail(img, size), avatarJPEGQuality)
+		if err != nil {
+			deleteKeys(storage, keys)
+			return nil, fmt.Errorf("erro ao gerar avatar de %dpx: %v", size, err)
+		}
+
+		key := fmt.Sprintf("avatars/%d/%d_%d.jpg", userID, timestamp, size)
+		if err := storage.Put(key, encoded, "image/jpeg"); err != nil {
+			deleteKeys(storage, keys)
+			return nil, err
+		}
+		keys = append(keys, key)
+		variants[strconv.Itoa(size)] = storage.URL(key)
+	}
+	return variants, nil
+}
+
+// deleteKeys remove as versões já gravadas quando o processamento de uma imagem falha no meio
+func deleteKeys(storage Storage, keys []string) {
+	for _, key := range keys {
+		storage.Delete(key)
+	}
+}
@@ -3,20 +3,30 @@ package services
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"os"
+	"strings"
+	"time"
+
+	"smartpicks-backend/internal/config"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
+// S3Service implementa Storage sobre o Amazon S3 ou um serviço compatível (MinIO) quando
+// AWS_S3_ENDPOINT está definido
 type S3Service struct {
 	Client     *s3.Client
 	BucketName string
 	Region     string
+	// PublicURL é a base das URLs dos arquivos (ex: https://cdn.exemplo.com). Vazia, usa o
+	// endereço do bucket.
+	PublicURL string
 }
 
 func NewS3Service() (*S3Service, error) {
@@ -27,43 +37,177 @@ func NewS3Service() (*S3Service, error) {
 		return nil, fmt.Errorf("AWS_REGION ou AWS_BUCKET_NAME não definidos")
 	}
 
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
+	cfg, err := awsconfig.LoadDefaultConfig(context.TODO(), awsconfig.WithRegion(region))
 	if err != nil {
 		return nil, err
 	}
 
+	endpoint := strings.TrimSuffix(os.Getenv("AWS_S3_ENDPOINT"), "/")
+	pathStyle := config.Bool("AWS_S3_FORCE_PATH_STYLE", endpoint != "")
 	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
 		o.Region = region
-		// Endpoint regional explícito
-		o.EndpointResolver = s3.EndpointResolverFromURL(fmt.Sprintf("https://s3.%s.amazonaws.com", region))
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		} else {
+			// Endpoint regional explícito
+			o.EndpointResolver = s3.EndpointResolverFromURL(fmt.Sprintf("https://s3.%s.amazonaws.com", region))
+		}
+		o.UsePathStyle = pathStyle
 	})
 
+	publicURL := os.Getenv("AWS_S3_PUBLIC_URL")
+	if publicURL == "" && endpoint != "" {
+		if pathStyle {
+			publicURL = endpoint + "/" + bucket
+		} else {
+			publicURL = strings.Replace(endpoint, "://", "://"+bucket+".", 1)
+		}
+	}
+
 	return &S3Service{
 		Client:     client,
 		BucketName: bucket,
 		Region:     region,
+		PublicURL:  strings.TrimSuffix(publicURL, "/"),
 	}, nil
 }
 
-func (s *S3Service) UploadFile(file multipart.File, fileName string, contentType string) (string, error) {
-	buf := new(bytes.Buffer)
-	if _, err := io.Copy(buf, file); err != nil {
-		return "", err
-	}
-
+// Put envia o conteúdo para o bucket
+func (s *S3Service) Put(key string, data []byte, contentType string) error {
 	input := &s3.PutObjectInput{
 		Bucket:      aws.String(s.BucketName),
-		Key:         aws.String(fileName),
-		Body:        bytes.NewReader(buf.Bytes()),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
 		ContentType: aws.String(contentType),
 	}
 
-	_, err := s.Client.PutObject(context.TODO(), input)
+	if _, err := s.Client.PutObject(context.TODO(), input); err != nil {
+		return fmt.Errorf("erro ao enviar para S3: %v", err)
+	}
+	return nil
+}
+
+// Get baixa um objeto do bucket e retorna o conteúdo e o Content-Type
+func (s *S3Service) Get(key string) ([]byte, string, error) {
+	output, err := s.Client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(s.BucketName),
+		Key:    aws.String(key),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, "", ErrFileNotFound
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("erro ao baixar do S3: %v", err)
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(output.Body)
 	if err != nil {
-		return "", fmt.Errorf("erro ao enviar para S3: %v", err)
+		return nil, "", err
 	}
+	return data, aws.ToString(output.ContentType), nil
+}
+
+// Delete remove um objeto do bucket
+func (s *S3Service) Delete(key string) error {
+	_, err := s.Client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+		Bucket: aws.String(s.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao remover do S3: %v", err)
+	}
+	return nil
+}
+
+// Exists verifica se o objeto existe no bucket
+func (s *S3Service) Exists(key string) (bool, error) {
+	_, err := s.Client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String(s.BucketName),
+		Key:    aws.String(key),
+	})
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("erro ao consultar o S3: %v", err)
+	}
+	return true, nil
+}
+
+// PresignPut gera uma URL pré-assinada para o cliente enviar o objeto direto ao bucket. O
+// Content-Type e o tamanho entram na assinatura, então o S3 recusa envios diferentes.
+func (s *S3Service) PresignPut(key, contentType string, size int64, ttl time.Duration) (string, error) {
+	request, err := s3.NewPresignClient(s.Client).PresignPutObject(context.TODO(), &s3.PutObjectInput{
+		Bucket:        aws.String(s.BucketName),
+		Key:           aws.String(key),
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(size),
+	}, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", fmt.Errorf("erro ao gerar URL de upload: %v", err)
+	}
+	return request.URL, nil
+}
+
+// Stat consulta os metadados do objeto com HeadObject
+func (s *S3Service) Stat(key string) (FileInfo, error) {
+	output, err := s.Client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String(s.BucketName),
+		Key:    aws.String(key),
+	})
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return FileInfo{}, ErrFileNotFound
+	}
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("erro ao consultar o S3: %v", err)
+	}
+	return FileInfo{Size: aws.ToInt64(output.ContentLength), ContentType: aws.ToString(output.ContentType)}, nil
+}
+
+// ReadPrefix baixa só os primeiros n bytes do objeto (Range)
+func (s *S3Service) ReadPrefix(key string, n int64) ([]byte, error) {
+	output, err := s.Client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(s.BucketName),
+		Key:    aws.String(key),
+		Range:  aws.String(fmt.Sprintf("bytes=0-%d", n-1)),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao baixar do S3: %v", err)
+	}
+	defer output.Body.Close()
+	return io.ReadAll(io.LimitReader(output.Body, n))
+}
+
+// URL retorna a URL pública do objeto
+func (s *S3Service) URL(key string) string {
+	return s.baseURL() + "/" + key
+}
+
+// KeyFromURL extrai a chave do objeto de uma URL do bucket (ou de uma chave salva sem a URL).
+// Retorna false para URLs de outros domínios.
+func (s *S3Service) KeyFromURL(url string) (string, bool) {
+	if key, ok := keyFromBaseURL(s.baseURL(), url); ok {
+		return key, true
+	}
+	// Arquivos enviados antes de PublicURL ser configurada continuam com a URL do bucket
+	return keyFromBaseURL(s.bucketURL(), url)
+}
+
+func (s *S3Service) baseURL() string {
+	if s.PublicURL != "" {
+		return s.PublicURL
+	}
+	return s.bucketURL()
+}
 
-	// 🔹 URL correta (baseada na região)
-	url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.BucketName, s.Region, fileName)
-	return url, nil
+func (s *S3Service) bucketURL() string {
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com", s.BucketName, s.Region)
 }
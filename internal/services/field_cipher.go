@@ -0,0 +1,201 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"smartpicks-backend/internal/config"
+)
+
+var ErrInvalidCiphertext = errors.New("valor cifrado inválido")
+
+// FieldCipher cifra campos sensíveis (CPF) com envelope encryption: cada valor recebe uma
+// chave de dados (DEK) aleatória, que é cifrada com a chave mestra (KEK) atual do keyring.
+// O valor cifrado guarda o ID da KEK, então chaves antigas continuam decifrando até a rotação.
+type FieldCipher struct {
+	keys         map[string][]byte
+	currentKeyID string
+	hashKey      []byte
+}
+
+// NewFieldCipher monta o cipher com o keyring (ID -> chave de 32 bytes), o ID da chave
+// usada para cifrar novos valores e a chave do HMAC de busca
+func NewFieldCipher(keys map[string][]byte, currentKeyID string, hashKey []byte) (*FieldCipher, error) {
+	if _, ok := keys[currentKeyID]; !ok {
+		return nil, fmt.Errorf("chave de criptografia %q não encontrada no keyring", currentKeyID)
+	}
+	for id, key := range keys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("chave de criptografia %q deve ter 32 bytes", id)
+		}
+	}
+	if len(hashKey) < 16 {
+		return nil, errors.New("chave do HMAC deve ter pelo menos 16 bytes")
+	}
+	return &FieldCipher{keys: keys, currentKeyID: currentKeyID, hashKey: hashKey}, nil
+}
+
+// NewFieldCipherFromEnv lê o keyring de DATA_ENCRYPTION_KEYS ("id:chave_base64,id:chave_base64"),
+// a chave atual de DATA_ENCRYPTION_KEY_ID (padrão: a primeira da lista) e a chave do HMAC de CPF_HASH_KEY
+func NewFieldCipherFromEnv() (*FieldCipher, error) {
+	raw := config.String("DATA_ENCRYPTION_KEYS", "")
+	if raw == "" {
+		return nil, errors.New("DATA_ENCRYPTION_KEYS não definida")
+	}
+
+	keys := make(map[string][]byte)
+	var firstID string
+	for _, entry := range strings.Split(raw, ",") {
+		id, encoded, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || id == "" {
+			return nil, fmt.Errorf("entrada inválida em DATA_ENCRYPTION_KEYS: %q (use id:chave_base64)", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("chave %q de DATA_ENCRYPTION_KEYS não está em base64: %v", id, err)
+		}
+		if firstID == "" {
+			firstID = id
+		}
+		keys[id] = key
+	}
+
+	hashKey := config.String("CPF_HASH_KEY", "")
+	if hashKey == "" {
+		return nil, errors.New("CPF_HASH_KEY não definida")
+	}
+
+	return NewFieldCipher(keys, config.String("DATA_ENCRYPTION_KEY_ID", firstID), []byte(hashKey))
+}
+
+// CurrentKeyID retorna o ID da chave usada para cifrar novos valores
+func (c *FieldCipher) CurrentKeyID() string {
+	return c.currentKeyID
+}
+
+// Encrypt cifra o valor no formato "<id da chave>:<DEK cifrada>:<valor cifrado>" (base64 URL-safe)
+func (c *FieldCipher) Encrypt(plaintext string) (string, error) {
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+
+	sealed, err := seal(dek, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+	return c.envelope(dek, sealed)
+}
+
+// Decrypt decifra um valor gerado por Encrypt com qualquer chave do keyring
+func (c *FieldCipher) Decrypt(ciphertext string) (string, error) {
+	dek, sealed, err := c.unwrap(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dek, sealed, nil)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation indica se o valor foi cifrado com uma chave diferente da atual
+func (c *FieldCipher) NeedsRotation(ciphertext string) bool {
+	keyID, _, _ := strings.Cut(ciphertext, ":")
+	return keyID != c.currentKeyID
+}
+
+// Rewrap cifra novamente a DEK com a chave atual, sem alterar o valor cifrado
+func (c *FieldCipher) Rewrap(ciphertext string) (string, error) {
+	dek, sealed, err := c.unwrap(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return c.envelope(dek, sealed)
+}
+
+// Hash calcula o HMAC-SHA256 (hex) do valor. É determinístico, então serve para buscas
+// e para a restrição de unicidade sem expor o valor em texto puro.
+func (c *FieldCipher) Hash(value string) string {
+	mac := hmac.New(sha256.New, c.hashKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// envelope cifra a DEK com a chave atual e monta o valor final
+func (c *FieldCipher) envelope(dek, sealed []byte) (string, error) {
+	wrapped, err := seal(c.keys[c.currentKeyID], dek, []byte(c.currentKeyID))
+	if err != nil {
+		return "", err
+	}
+	return c.currentKeyID + ":" + base64.RawURLEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// unwrap separa o valor cifrado e decifra a DEK com a chave indicada nele
+func (c *FieldCipher) unwrap(ciphertext string) ([]byte, []byte, error) {
+	parts := strings.Split(ciphertext, ":")
+	if len(parts) != 3 {
+		return nil, nil, ErrInvalidCiphertext
+	}
+	kek, ok := c.keys[parts[0]]
+	if !ok {
+		return nil, nil, fmt.Errorf("chave de criptografia %q não está no keyring", parts[0])
+	}
+	wrapped, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, ErrInvalidCiphertext
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, ErrInvalidCiphertext
+	}
+	dek, err := open(kek, wrapped, []byte(parts[0]))
+	if err != nil {
+		return nil, nil, ErrInvalidCiphertext
+	}
+	return dek, sealed, nil
+}
+
+// seal cifra com AES-256-GCM e prefixa o nonce
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decifra um valor gerado por seal
+func open(key, sealed, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+	nonce, data := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, data, additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
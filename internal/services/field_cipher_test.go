@@ -0,0 +1,114 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte { return bytes.Repeat([]byte{b}, 32) }
+
+func newTestCipher(t *testing.T, keys map[string][]byte, current string) *FieldCipher {
+	t.Helper()
+	c, err := NewFieldCipher(keys, current, []byte("chave-do-hmac-de-teste"))
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	return c
+}
+
+func TestFieldCipherKeyRotation(t *testing.T) {
+	const cpf = "52998224725"
+
+	// Antes da rotação só existe a chave k1; depois k2 passa a ser a atual e k1 fica no keyring
+	before := newTestCipher(t, map[string][]byte{"k1": testKey(1)}, "k1")
+	after := newTestCipher(t, map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, "k2")
+	onlyNew := newTestCipher(t, map[string][]byte{"k2": testKey(2)}, "k2")
+
+	old, err := before.Encrypt(cpf)
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	rewrapped, err := after.Rewrap(old)
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	fresh, err := after.Encrypt(cpf)
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	// Trocar o ID da chave no envelope não pode fazer a DEK ser aceita (o ID é dado autenticado)
+	swappedID := "k2" + strings.TrimPrefix(old, "k1")
+	parts := strings.Split(old, ":")
+	tamperedValue := parts[0] + ":" + parts[1] + ":" + strings.Repeat("A", len(parts[2]))
+
+	tests := []struct {
+		name         string
+		cipher       *FieldCipher
+		ciphertext   string
+		want         string
+		wantErr      bool
+		wantRotation bool
+	}{
+		{"valor antigo com o keyring novo", after, old, cpf, false, true},
+		{"valor antigo com o keyring antigo", before, old, cpf, false, false},
+		{"valor recifrado com o keyring novo", after, rewrapped, cpf, false, false},
+		{"valor recifrado sem a chave antiga", onlyNew, rewrapped, cpf, false, false},
+		{"valor novo com o keyring novo", after, fresh, cpf, false, false},
+		{"valor antigo sem a chave antiga", onlyNew, old, "", true, true},
+		{"valor novo com o keyring antigo", before, fresh, "", true, true},
+		{"ID da chave trocado", after, swappedID, "", true, false},
+		{"valor cifrado adulterado", after, tamperedValue, "", true, true},
+		{"formato inválido", after, "k2:abc", "", true, false},
+		{"vazio", after, "", "", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cipher.Decrypt(tt.ciphertext)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decrypt() erro = %v, esperado erro: %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Decrypt() = %q, esperado %q", got, tt.want)
+			}
+			if rotation := tt.cipher.NeedsRotation(tt.ciphertext); rotation != tt.wantRotation {
+				t.Errorf("NeedsRotation() = %v, esperado %v", rotation, tt.wantRotation)
+			}
+		})
+	}
+}
+
+func TestFieldCipherHash(t *testing.T) {
+	a := newTestCipher(t, map[string][]byte{"k1": testKey(1)}, "k1")
+	rotated := newTestCipher(t, map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, "k2")
+
+	// O hash depende só da chave do HMAC: a rotação da chave de criptografia não o altera
+	if a.Hash("52998224725") != rotated.Hash("52998224725") {
+		t.Error("Hash() mudou com a rotação da chave de criptografia")
+	}
+	if a.Hash("52998224725") == a.Hash("11144477735") {
+		t.Error("Hash() igual para valores diferentes")
+	}
+}
+
+func TestNewFieldCipher(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    map[string][]byte
+		current string
+		hashKey []byte
+	}{
+		{"chave atual fora do keyring", map[string][]byte{"k1": testKey(1)}, "k2", []byte("chave-do-hmac-de-teste")},
+		{"chave curta", map[string][]byte{"k1": testKey(1)[:16]}, "k1", []byte("chave-do-hmac-de-teste")},
+		{"chave do HMAC curta", map[string][]byte{"k1": testKey(1)}, "k1", []byte("curta")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFieldCipher(tt.keys, tt.current, tt.hashKey); err == nil {
+				t.Error("NewFieldCipher() sem erro, esperado erro")
+			}
+		})
+	}
+}
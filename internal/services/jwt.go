@@ -0,0 +1,168 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"smartpicks-backend/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const jwtIssuer = "smartpicks-backend"
+
+// Audiences dos tokens emitidos. Um token de desafio nunca é aceito como access token.
+const (
+	AudienceAccess         = "access"
+	AudienceTwoFactorLogin = "2fa_login"
+	AudienceTwoFactorSetup = "2fa_setup"
+	AudienceDataExport     = "data_export"
+)
+
+var ErrInvalidToken = errors.New("token inválido ou expirado")
+
+// AccessClaims são as informações carregadas no access token
+type AccessClaims struct {
+	UserID    int    `json:"uid"`
+	Perfil    string `json:"perfil"`
+	SessionID int    `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// AccessTokenTTL retorna a validade configurada para os access tokens (JWT_ACCESS_TTL)
+func AccessTokenTTL() time.Duration {
+	return config.Duration("JWT_ACCESS_TTL", 15*time.Minute)
+}
+
+// RefreshTokenTTL retorna a validade máxima de uma sessão (JWT_REFRESH_TTL)
+func RefreshTokenTTL() time.Duration {
+	return config.Duration("JWT_REFRESH_TTL", 30*24*time.Hour)
+}
+
+func jwtSecret() ([]byte, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("JWT_SECRET não definido")
+	}
+	return []byte(secret), nil
+}
+
+// GenerateAccessToken gera um access token assinado (HS256) para o usuário na sessão informada
+func GenerateAccessToken(userID int, perfil string, sessionID int) (string, time.Time, error) {
+	secret, err := jwtSecret()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(AccessTokenTTL())
+	claims := AccessClaims{
+		UserID:    userID,
+		Perfil:    perfil,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    jwtIssuer,
+			Subject:   strconv.Itoa(userID),
+			Audience:  jwt.ClaimStrings{AudienceAccess},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// ParseAccessToken valida a assinatura e a expiração do token e retorna suas claims
+func ParseAccessToken(tokenString string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	if err := parseToken(tokenString, AudienceAccess, claims); err != nil || claims.UserID <= 0 {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// GenerateChallengeToken gera um token de curta duração que identifica o usuário numa etapa
+// intermediária do login (ex: aguardando o código 2FA). audience define a etapa.
+func GenerateChallengeToken(userID int, audience string, ttl time.Duration) (string, error) {
+	secret, err := jwtSecret()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := AccessClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    jwtIssuer,
+			Subject:   strconv.Itoa(userID),
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// ParseChallengeToken valida um token de desafio da audience informada e retorna o ID do usuário
+func ParseChallengeToken(tokenString, audience string) (int, error) {
+	claims := &AccessClaims{}
+	if err := parseToken(tokenString, audience, claims); err != nil || claims.UserID <= 0 {
+		return 0, ErrInvalidToken
+	}
+	return claims.UserID, nil
+}
+
+// GenerateDownloadToken gera um link assinado para baixar o recurso informado (ex: um export
+// de dados) que deixa de valer em expiresAt
+func GenerateDownloadToken(resourceID int, audience string, expiresAt time.Time) (string, error) {
+	secret, err := jwtSecret()
+	if err != nil {
+		return "", err
+	}
+
+	claims := jwt.RegisteredClaims{
+		Issuer:    jwtIssuer,
+		Subject:   strconv.Itoa(resourceID),
+		Audience:  jwt.ClaimStrings{audience},
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// ParseDownloadToken valida um token gerado por GenerateDownloadToken e retorna o ID do recurso
+func ParseDownloadToken(tokenString, audience string) (int, error) {
+	claims := &jwt.RegisteredClaims{}
+	if err := parseToken(tokenString, audience, claims); err != nil {
+		return 0, ErrInvalidToken
+	}
+	id, err := strconv.Atoi(claims.Subject)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidToken
+	}
+	return id, nil
+}
+
+func parseToken(tokenString, audience string, claims jwt.Claims) error {
+	secret, err := jwtSecret()
+	if err != nil {
+		return err
+	}
+
+	_, err = jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(jwtIssuer),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+	return err
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"smartpicks-backend/internal/config"
+)
+
+// LoginAttempt é o estado do contador de falhas de uma chave (conta ou IP)
+type LoginAttempt struct {
+	Failures    int
+	LockedUntil time.Time
+}
+
+// LoginAttemptStore guarda os contadores de falhas de login. A implementação em memória
+// serve para um único servidor; a do Postgres é compartilhada entre instâncias (Vercel).
+type LoginAttemptStore interface {
+	Get(key string) (LoginAttempt, error)
+	// RecordFailure incrementa o contador; se a última falha (ou o fim do último bloqueio)
+	// for mais antiga que window, o contador recomeça em 1
+	RecordFailure(key string, window time.Duration) (LoginAttempt, error)
+	Lock(key string, until time.Time) error
+	Reset(key string) error
+}
+
+// LoginGuard aplica backoff exponencial e bloqueio temporário sobre um LoginAttemptStore
+type LoginGuard struct {
+	Store            LoginAttemptStore
+	AccountThreshold int
+	IPThreshold      int
+	BaseLockout      time.Duration
+	MaxLockout       time.Duration
+	Window           time.Duration
+}
+
+// NewLoginGuardFromEnv monta o LoginGuard com o store escolhido em LOGIN_ATTEMPTS_STORE
+// ("postgres" ou "memory", padrão "postgres") e os limites configurados
+func NewLoginGuardFromEnv(db *sql.DB) (*LoginGuard, error) {
+	var store LoginAttemptStore
+	switch driver := config.String("LOGIN_ATTEMPTS_STORE", "postgres"); driver {
+	case "postgres":
+		store = &PostgresLoginAttemptStore{DB: db}
+	case "memory":
+		store = NewMemoryLoginAttemptStore()
+	default:
+		return nil, fmt.Errorf("LOGIN_ATTEMPTS_STORE inválido: %s", driver)
+	}
+
+	return &LoginGuard{
+		Store:            store,
+		AccountThreshold: config.Int("LOGIN_MAX_ATTEMPTS_ACCOUNT", 5),
+		IPThreshold:      config.Int("LOGIN_MAX_ATTEMPTS_IP", 20),
+		BaseLockout:      config.Duration("LOGIN_LOCKOUT_BASE", time.Minute),
+		MaxLockout:       config.Duration("LOGIN_LOCKOUT_MAX", time.Hour),
+		Window:           config.Duration("LOGIN_ATTEMPTS_WINDOW", 15*time.Minute),
+	}, nil
+}
+
+// AccountLoginKey é a chave do contador de uma conta (pelo email)
+func AccountLoginKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// IPLoginKey é a chave do contador de um endereço IP
+func IPLoginKey(ip string) string {
+	return "ip:" + ip
+}
+
+// TwoFactorLoginKey é a chave do contador de códigos 2FA inválidos de um usuário
+func TwoFactorLoginKey(userID int) string {
+	return fmt.Sprintf("2fa:%d", userID)
+}
+
+// Check retorna quanto tempo falta para liberar a chave mais restrita (0 se nenhuma está bloqueada)
+func (g *LoginGuard) Check(keys ...string) (time.Duration, error) {
+	var retryAfter time.Duration
+	now := time.Now()
+	for _, key := range keys {
+		attempt, err := g.Store.Get(key)
+		if err != nil {
+			return 0, err
+		}
+		if remaining := attempt.LockedUntil.Sub(now); remaining > retryAfter {
+			retryAfter = remaining
+		}
+	}
+	return retryAfter, nil
+}
+
+// RegisterFailure contabiliza uma falha na chave e a bloqueia quando o limite é atingido.
+// A cada falha além do limite o bloqueio dobra (BaseLockout, 2x, 4x...) até MaxLockout.
+// Retorna a duração do bloqueio aplicado (0 se a chave não foi bloqueada).
+func (g *LoginGuard) RegisterFailure(key string) (time.Duration, error) {
+	threshold := g.AccountThreshold
+	if strings.HasPrefix(key, "ip:") {
+		threshold = g.IPThreshold
+	}
+
+	attempt, err := g.Store.RecordFailure(key, g.Window)
+	if err != nil {
+		return 0, err
+	}
+	if attempt.Failures < threshold {
+		return 0, nil
+	}
+
+	lockout := g.BaseLockout
+	for i := threshold; i < attempt.Failures && lockout < g.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > g.MaxLockout {
+		lockout = g.MaxLockout
+	}
+
+	if err := g.Store.Lock(key, time.Now().Add(lockout)); err != nil {
+		return 0, err
+	}
+	return lockout, nil
+}
+
+// Reset zera o contador e remove o bloqueio da chave
+func (g *LoginGuard) Reset(key string) error {
+	return g.Store.Reset(key)
+}
+
+// MemoryLoginAttemptStore mantém os contadores em memória (um único processo)
+type MemoryLoginAttemptStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryLoginAttempt
+}
+
+type memoryLoginAttempt struct {
+	LoginAttempt
+	lastFailure time.Time
+}
+
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{entries: make(map[string]*memoryLoginAttempt)}
+}
+
+func (s *MemoryLoginAttemptStore) Get(key string) (LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.entries[key]; ok {
+		return entry.LoginAttempt, nil
+	}
+	return LoginAttempt{}, nil
+}
+
+func (s *MemoryLoginAttemptStore) RecordFailure(key string, window time.Duration) (LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryLoginAttempt{}
+		s.entries[key] = entry
+	}
+	lastActivity := entry.lastFailure
+	if entry.LockedUntil.After(lastActivity) {
+		lastActivity = entry.LockedUntil
+	}
+	if now.Sub(lastActivity) > window {
+		entry.Failures = 0
+	}
+	entry.Failures++
+	entry.lastFailure = now
+	return entry.LoginAttempt, nil
+}
+
+func (s *MemoryLoginAttemptStore) Lock(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.entries[key]; ok {
+		entry.LockedUntil = until
+	}
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// PostgresLoginAttemptStore guarda os contadores na tabela login_attempts
+type PostgresLoginAttemptStore struct {
+	DB *sql.DB
+}
+
+func (s *PostgresLoginAttemptStore) Get(key string) (LoginAttempt, error) {
+	var attempt LoginAttempt
+	var lockedUntil sql.NullTime
+	err := s.DB.QueryRow(`
+		SELECT failures, locked_until FROM login_attempts WHERE key = $1`, key).
+		Scan(&attempt.Failures, &lockedUntil)
+	if err == sql.ErrNoRows {
+		return LoginAttempt{}, nil
+	}
+	attempt.LockedUntil = lockedUntil.Time
+	return attempt, err
+}
+
+func (s *PostgresLoginAttemptStore) RecordFailure(key string, window time.Duration) (LoginAttempt, error) {
+	var attempt LoginAttempt
+	var lockedUntil sql.NullTime
+	err := s.DB.QueryRow(`
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES ($1, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN GREATEST(login_attempts.last_failure_at, login_attempts.locked_until)
+					< CURRENT_TIMESTAMP - make_interval(secs => $2) THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = CURRENT_TIMESTAMP
+		RETURNING failures, locked_until`, key, window.Seconds()).
+		Scan(&attempt.Failures, &lockedUntil)
+	attempt.LockedUntil = lockedUntil.Time
+	return attempt, err
+}
+
+func (s *PostgresLoginAttemptStore) Lock(key string, until time.Time) error {
+	_, err := s.DB.Exec("UPDATE login_attempts SET locked_until = $2 WHERE key = $1", key, until)
+	return err
+}
+
+func (s *PostgresLoginAttemptStore) Reset(key string) error {
+	_, err := s.DB.Exec("DELETE FROM login_attempts WHERE key = $1", key)
+	return err
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"smartpicks-backend/internal/config"
+)
+
+// Email é uma mensagem de texto simples a ser enviada
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer abstrai o envio de emails (SMTP em produção, log/arquivo em desenvolvimento e testes)
+type Mailer interface {
+	Send(msg Email) error
+}
+
+// NewMailerFromEnv escolhe a implementação pelo MAIL_DRIVER ("smtp" ou "log", padrão "log")
+func NewMailerFromEnv() (Mailer, error) {
+	switch driver := config.String("MAIL_DRIVER", "log"); driver {
+	case "smtp":
+		return NewSMTPMailer()
+	case "log":
+		return &LogMailer{Dir: os.Getenv("MAIL_LOG_DIR")}, nil
+	default:
+		return nil, fmt.Errorf("MAIL_DRIVER inválido: %s", driver)
+	}
+}
+
+// SMTPMailer envia emails por um servidor SMTP com autenticação PLAIN
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func NewSMTPMailer() (*SMTPMailer, error) {
+	m := &SMTPMailer{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     config.String("SMTP_PORT", "587"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("MAIL_FROM"),
+	}
+	if m.Host == "" || m.From == "" {
+		return nil, fmt.Errorf("SMTP_HOST ou MAIL_FROM não definidos")
+	}
+	return m, nil
+}
+
+func (m *SMTPMailer) Send(msg Email) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	addr := m.Host + ":" + m.Port
+	if err := smtp.SendMail(addr, auth, m.From, []string{msg.To}, buildMessage(m.From, msg)); err != nil {
+		return fmt.Errorf("erro ao enviar email via SMTP: %v", err)
+	}
+	return nil
+}
+
+// LogMailer escreve os emails no log e, se Dir estiver definido, grava cada mensagem
+// em um arquivo .eml nesse diretório. No log os tokens dos links são omitidos, pois o log
+// costuma ser lido por mais gente e guardado por mais tempo; a mensagem completa só vai para Dir.
+type LogMailer struct {
+	Dir string
+}
+
+// tokenParamPattern encontra o valor de parâmetros token= nos links dos emails
+var tokenParamPattern = regexp.MustCompile(`([?&]token=)[^&\s]+`)
+
+// RedactTokens substitui os tokens dos links do texto por [omitido]
+func RedactTokens(text string) string {
+	return tokenParamPattern.ReplaceAllString(text, "${1}[omitido]")
+}
+
+func (m *LogMailer) Send(msg Email) error {
+	log.Printf("📧 Email para %s: %s\n%s", msg.To, msg.Subject, RedactTokens(msg.Body))
+	if m.Dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d_%s.eml", time.Now().UnixNano(), sanitizeFileName(msg.To))
+	return os.WriteFile(filepath.Join(m.Dir, name), buildMessage("no-reply@smartpicks.local", msg), 0o644)
+}
+
+func buildMessage(from string, msg Email) []byte {
+	// Remove quebras de linha dos cabeçalhos para evitar header injection
+	header := strings.NewReplacer("\r", "", "\n", "")
+
+	var b strings.Builder
+	b.WriteString("From: " + header.Replace(from) + "\r\n")
+	b.WriteString("To: " + header.Replace(msg.To) + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("UTF-8", header.Replace(msg.Subject)) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+	return []byte(b.String())
+}
+
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, s)
+}
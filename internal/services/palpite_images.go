@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"smartpicks-backend/internal/imaging"
+)
+
+// PalpiteImageSizes são as versões geradas para a imagem de cada palpite (largura máxima em pixels).
+// "full" substitui o original, que nunca é guardado.
+var PalpiteImageSizes = []struct {
+	Name     string
+	MaxWidth int
+}{
+	{"thumb", 320},
+	{"medium", 800},
+	{"full", 1600},
+}
+
+const palpiteJPEGQuality = 82
+
+// ErrInvalidImage indica que o arquivo enviado não é uma imagem aceita (erro do cliente)
+var ErrInvalidImage = errors.New("imagem inválida")
+
+// ProcessedImage é o resultado do processamento da imagem de um palpite
+type ProcessedImage struct {
+	// URL aponta para a versão "full"
+	URL      string
+	Variants map[string]string
+	Width    int
+	Height   int
+	Blurhash string
+	// PHash é o hash perceptual (dHash) usado para encontrar imagens repetidas
+	PHash uint64
+}
+
+// ProcessPalpiteImage decodifica a imagem (aplicando a rotação do EXIF), gera as versões de
+// PalpiteImageSizes em JPEG, sem metadados (EXIF, GPS), e as grava no storage
+func ProcessPalpiteImage(storage Storage, userID int, data []byte) (*ProcessedImage, error) {
+	img, _, err := imaging.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	result := &ProcessedImage{
+		Variants: make(map[string]string, len(PalpiteImageSizes)),
+		PHash:    imaging.DHash(img),
+	}
+	timestamp := time.Now().UnixNano()
+	var keys []string
+	for _, size := range PalpiteImageSizes {
+		resized := imaging.Fit(img, size.MaxWidth)
+		encoded, err := imaging.EncodeJPEG(resized, palpiteJPEGQuality)
+		if err != nil {
+			deleteKeys(storage, keys)
+			return nil, fmt.Errorf("erro ao gerar imagem %s: %v", size.Name, err)
+		}
+
+		key := fmt.Sprintf("palpites/%d/%d_%s.jpg", userID, timestamp, size.Name)
+		if err := storage.Put(key, encoded, "image/jpeg"); err != nil {
+			deleteKeys(storage, keys)
+			return nil, err
+		}
+		keys = append(keys, key)
+		result.Variants[size.Name] = storage.URL(key)
+
+		if size.Name == "full" {
+			result.URL = storage.URL(key)
+			result.Width = resized.Bounds().Dx()
+			result.Height = resized.Bounds().Dy()
+			result.Blurhash = imaging.Blurhash(resized)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"smartpicks-backend/internal/config"
+)
+
+// ErrFileNotFound é retornado por Get quando o arquivo não existe no storage
+var ErrFileNotFound = errors.New("arquivo não encontrado")
+
+// Storage abstrai onde os arquivos enviados (imagens dos palpites, avatares) são guardados:
+// S3 (ou compatível, como o MinIO), disco local ou memória (testes)
+type Storage interface {
+	// Put grava o conteúdo na chave informada, substituindo o anterior
+	Put(key string, data []byte, contentType string) error
+	// Get retorna o conteúdo e o Content-Type do arquivo
+	Get(key string) ([]byte, string, error)
+	Delete(key string) error
+	// URL retorna o endereço público do arquivo
+	URL(key string) string
+	Exists(key string) (bool, error)
+	// KeyFromURL extrai a chave de uma URL gerada por URL (ou de uma chave salva sem a URL).
+	// Retorna false para URLs de outros domínios.
+	KeyFromURL(url string) (string, bool)
+}
+
+// FileInfo são os metadados de um arquivo no storage
+type FileInfo struct {
+	Size        int64
+	ContentType string
+}
+
+// DirectUploader é implementado pelos storages em que o cliente envia o arquivo direto, por uma
+// URL pré-assinada, sem passar pela API
+type DirectUploader interface {
+	// PresignPut gera a URL de um PUT que só aceita o Content-Type e o tamanho informados
+	PresignPut(key, contentType string, size int64, ttl time.Duration) (string, error)
+	// Stat retorna os metadados do arquivo (ErrFileNotFound se ele não existir)
+	Stat(key string) (FileInfo, error)
+	// ReadPrefix lê apenas os primeiros n bytes do arquivo
+	ReadPrefix(key string, n int64) ([]byte, error)
+}
+
+// NewStorageFromEnv escolhe o backend pelo STORAGE_DRIVER ("s3", "local" ou "memory").
+// Sem STORAGE_DRIVER usa o S3 quando AWS_BUCKET_NAME está definido e o disco local caso contrário.
+func NewStorageFromEnv() (Storage, error) {
+	defaultDriver := "local"
+	if os.Getenv("AWS_BUCKET_NAME") != "" {
+		defaultDriver = "s3"
+	}
+
+	switch driver := config.String("STORAGE_DRIVER", defaultDriver); driver {
+	case "s3":
+		return NewS3Service()
+	case "local":
+		baseURL := config.String("STORAGE_PUBLIC_URL", config.String("API_URL", "http://localhost:8080")+"/uploads")
+		return NewLocalStorage(config.String("STORAGE_LOCAL_DIR", "uploads"), baseURL)
+	case "memory":
+		return NewMemoryStorage(), nil
+	default:
+		return nil, fmt.Errorf("STORAGE_DRIVER inválido: %s", driver)
+	}
+}
+
+// PutAndURL grava o arquivo e retorna sua URL pública
+func PutAndURL(storage Storage, key string, data []byte, contentType string) (string, error) {
+	if err := storage.Put(key, data, contentType); err != nil {
+		return "", err
+	}
+	return storage.URL(key), nil
+}
+
+// cleanKey normaliza a chave e impede que ela aponte para fora da raiz do storage ("../")
+func cleanKey(key string) (string, error) {
+	cleaned := strings.TrimPrefix(path.Clean("/"+key), "/")
+	if cleaned == "" || cleaned == "." {
+		return "", fmt.Errorf("chave de arquivo inválida: %q", key)
+	}
+	return cleaned, nil
+}
+
+// keyFromBaseURL implementa KeyFromURL para backends cujas URLs são baseURL + "/" + chave
+func keyFromBaseURL(baseURL, url string) (string, bool) {
+	if !strings.HasPrefix(url, "http") && !strings.HasPrefix(url, "/") {
+		return url, url != ""
+	}
+	prefix := strings.TrimSuffix(baseURL, "/") + "/"
+	if !strings.HasPrefix(url, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(url, prefix), true
+}
+
+// LocalStorage guarda os arquivos em um diretório do disco, servidos pela própria API em /uploads/
+type LocalStorage struct {
+	Dir     string
+	BaseURL string
+}
+
+func NewLocalStorage(dir, baseURL string) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório de uploads: %v", err)
+	}
+	return &LocalStorage{Dir: dir, BaseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+func (s *LocalStorage) path(key string) (string, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(cleaned)), nil
+}
+
+func (s *LocalStorage) Put(key string, data []byte, contentType string) error {
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return fmt.Errorf("erro ao gravar arquivo: %v", err)
+	}
+	// Grava em um arquivo temporário e renomeia, para nunca servir um arquivo pela metade
+	tmp := filePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("erro ao gravar arquivo: %v", err)
+	}
+	if err := os.Rename(tmp, filePath); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("erro ao gravar arquivo: %v", err)
+	}
+	return nil
+}
+
+func (s *LocalStorage) Get(key string) ([]byte, string, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", ErrFileNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return data, http.DetectContentType(data), nil
+}
+
+func (s *LocalStorage) Delete(key string) error {
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("erro ao remover arquivo: %v", err)
+	}
+	return nil
+}
+
+func (s *LocalStorage) URL(key string) string {
+	cleaned, _ := cleanKey(key)
+	return s.BaseURL + "/" + cleaned
+}
+
+func (s *LocalStorage) Exists(key string) (bool, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !info.IsDir(), nil
+}
+
+func (s *LocalStorage) KeyFromURL(url string) (string, bool) {
+	return keyFromBaseURL(s.BaseURL, url)
+}
+
+// Handler serve os arquivos do diretório (sem listar o conteúdo das pastas). Deve ser montado
+// com http.StripPrefix("/uploads/", ...).
+func (s *LocalStorage) Handler() http.Handler {
+	fileServer := http.FileServer(http.Dir(s.Dir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "" || strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		fileServer.ServeHTTP(w, r)
+	})
+}
+
+// MemoryStorage guarda os arquivos em memória. Usado em testes e desenvolvimento.
+type MemoryStorage struct {
+	mu    sync.RWMutex
+	files map[string]memoryFile
+}
+
+type memoryFile struct {
+	data        []byte
+	contentType string
+}
+
+const memoryStorageBaseURL = "memory://"
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{files: make(map[string]memoryFile)}
+}
+
+func (s *MemoryStorage) Put(key string, data []byte, contentType string) error {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[cleaned] = memoryFile{data: append([]byte(nil), data...), contentType: contentType}
+	return nil
+}
+
+func (s *MemoryStorage) Get(key string) ([]byte, string, error) {
+	cleaned, _ := cleanKey(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	file, ok := s.files[cleaned]
+	if !ok {
+		return nil, "", ErrFileNotFound
+	}
+	return append([]byte(nil), file.data...), file.contentType, nil
+}
+
+func (s *MemoryStorage) Delete(key string) error {
+	cleaned, _ := cleanKey(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.files, cleaned)
+	return nil
+}
+
+func (s *MemoryStorage) URL(key string) string {
+	cleaned, _ := cleanKey(key)
+	return memoryStorageBaseURL + cleaned
+}
+
+func (s *MemoryStorage) Exists(key string) (bool, error) {
+	cleaned, _ := cleanKey(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.files[cleaned]
+	return ok, nil
+}
+
+func (s *MemoryStorage) KeyFromURL(url string) (string, bool) {
+	if strings.HasPrefix(url, memoryStorageBaseURL) {
+		return strings.TrimPrefix(url, memoryStorageBaseURL), true
+	}
+	if strings.Contains(url, "://") {
+		return "", false
+	}
+	return url, url != ""
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret é o segredo dos vetores de teste da RFC 6238 ("12345678901234567890") em base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	tests := []struct {
+		name string
+		unix int64
+		want string
+	}{
+		// Últimos 6 dígitos dos códigos SHA1 de 8 dígitos da RFC 6238
+		{"t=59", 59, "287082"},
+		{"t=1111111109", 1111111109, "081804"},
+		{"t=1111111111", 1111111111, "050471"},
+		{"t=1234567890", 1234567890, "005924"},
+		{"t=2000000000", 2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TOTPCode(rfcSecret, TOTPStep(time.Unix(tt.unix, 0)))
+			if err != nil {
+				t.Fatalf("erro inesperado: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("TOTPCode() = %s, esperado %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := TOTPStep(now)
+	code := func(step int64) string {
+		c, err := TOTPCode(rfcSecret, step)
+		if err != nil {
+			t.Fatalf("erro inesperado: %v", err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"intervalo atual", code(current), 0, current, true},
+		{"intervalo anterior dentro da tolerância", code(current - 1), 0, current - 1, true},
+		{"intervalo seguinte dentro da tolerância", code(current + 1), 0, current + 1, true},
+		{"dois intervalos antes", code(current - 2), 0, 0, false},
+		{"dois intervalos depois", code(current + 2), 0, 0, false},
+		{"com espaços", code(current)[:3] + " " + code(current)[3:], 0, current, true},
+		{"reuso do código já aceito", code(current), current, 0, false},
+		{"código anterior ao último aceito", code(current - 1), current - 1, 0, false},
+		{"código novo depois de um anterior aceito", code(current), current - 1, current, true},
+		{"tamanho errado", code(current)[:5], 0, 0, false},
+		{"código errado", "000000", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfcSecret, tt.code, now, tt.lastStep)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("ValidateTOTP() = (%d, %v), esperado (%d, %v)", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"abcde-fghij", "abcdefghij"},
+		{" ABCDE-FGHIJ ", "abcdefghij"},
+		{"abcde fghij", "abcdefghij"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if got := NormalizeRecoveryCode(tt.code); got != tt.want {
+				t.Errorf("NormalizeRecoveryCode(%q) = %s, esperado %s", tt.code, got, tt.want)
+			}
+		})
+	}
+}
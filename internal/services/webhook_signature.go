@@ -0,0 +1,98 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"smartpicks-backend/internal/config"
+)
+
+// Header com a assinatura dos webhooks de placares: "t=<unix>,v1=<hmac-sha256 hex>"
+const WebhookSignatureHeader = "X-Scores-Signature"
+
+var (
+	ErrMissingSignature = errors.New("assinatura ausente")
+	ErrInvalidSignature = errors.New("assinatura inválida")
+	ErrExpiredSignature = errors.New("assinatura fora da janela de tempo")
+)
+
+// WebhookSigner assina e confere os webhooks do feed de placares. A assinatura cobre o
+// timestamp e o corpo ("<t>.<corpo>"), então uma requisição capturada não pode ser
+// reenviada depois da tolerância.
+type WebhookSigner struct {
+	secret    []byte
+	tolerance time.Duration
+}
+
+// NewWebhookSigner monta o assinador com o segredo compartilhado e a tolerância de relógio
+func NewWebhookSigner(secret string, tolerance time.Duration) (*WebhookSigner, error) {
+	if len(secret) < 16 {
+		return nil, errors.New("o segredo do webhook deve ter pelo menos 16 caracteres")
+	}
+	return &WebhookSigner{secret: []byte(secret), tolerance: tolerance}, nil
+}
+
+// NewWebhookSignerFromEnv lê SCORES_WEBHOOK_SECRET e SCORES_WEBHOOK_TOLERANCE (padrão 5m).
+// Sem segredo retorna nil e o webhook fica desativado.
+func NewWebhookSignerFromEnv() (*WebhookSigner, error) {
+	secret := config.String("SCORES_WEBHOOK_SECRET", "")
+	if secret == "" {
+		return nil, nil
+	}
+	return NewWebhookSigner(secret, config.Duration("SCORES_WEBHOOK_TOLERANCE", 5*time.Minute))
+}
+
+// Sign retorna o valor do header de assinatura do corpo no instante informado
+func (s *WebhookSigner) Sign(body []byte, at time.Time) string {
+	t := strconv.FormatInt(at.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", t, s.mac(t, body))
+}
+
+// Verify confere o header de assinatura do corpo recebido
+func (s *WebhookSigner) Verify(header string, body []byte, now time.Time) error {
+	if header == "" {
+		return ErrMissingSignature
+	}
+
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if math.Abs(now.Sub(time.Unix(unix, 0)).Seconds()) > s.tolerance.Seconds() {
+		return ErrExpiredSignature
+	}
+
+	expected := s.mac(timestamp, body)
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func (s *WebhookSigner) mac(timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookSignerVerify(t *testing.T) {
+	signer, err := NewWebhookSigner("segredo-compartilhado-de-teste", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	other, err := NewWebhookSigner("outro-segredo-compartilhado", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+
+	body := []byte(`{"event_id":"evt_1","match_id":10,"home_score":2,"away_score":1}`)
+	now := time.Unix(1760000000, 0)
+	signed := signer.Sign(body, now)
+	mac := signed[strings.Index(signed, "v1=")+3:]
+
+	tests := []struct {
+		name   string
+		header string
+		body   []byte
+		now    time.Time
+		want   error
+	}{
+		{"assinatura válida", signed, body, now, nil},
+		{"dentro da tolerância, relógio adiantado", signed, body, now.Add(5 * time.Minute), nil},
+		{"dentro da tolerância, relógio atrasado", signed, body, now.Add(-5 * time.Minute), nil},
+		{"fora da tolerância (reenvio tardio)", signed, body, now.Add(5*time.Minute + time.Second), ErrExpiredSignature},
+		{"fora da tolerância (timestamp no futuro)", signed, body, now.Add(-6 * time.Minute), ErrExpiredSignature},
+		{"corpo alterado", signed, []byte(`{"event_id":"evt_1","match_id":10,"home_score":0,"away_score":1}`), now, ErrInvalidSignature},
+		{"timestamp trocado sem refazer o HMAC", "t=1760000060,v1=" + mac, body, now, ErrInvalidSignature},
+		{"outro segredo", other.Sign(body, now), body, now, ErrInvalidSignature},
+		{"uma das assinaturas confere (troca de segredo)", other.Sign(body, now) + ",v1=" + mac, body, now, nil},
+		{"sem v1", "t=1760000000", body, now, ErrInvalidSignature},
+		{"timestamp inválido", "t=abc,v1=" + mac, body, now, ErrInvalidSignature},
+		{"header vazio", "", body, now, ErrMissingSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := signer.Verify(tt.header, tt.body, tt.now); got != tt.want {
+				t.Errorf("Verify() = %v, esperado %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWebhookSignerShortSecret(t *testing.T) {
+	if _, err := NewWebhookSigner("curto", time.Minute); err == nil {
+		t.Error("NewWebhookSigner() sem erro, esperado erro para segredo curto")
+	}
+}
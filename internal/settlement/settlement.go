@@ -0,0 +1,205 @@
+// Package settlement apura as seleções dos palpites a partir do placar final da partida.
+// A lógica é pura (sem banco); quem grava o resultado é o repository.
+package settlement
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"smartpicks-backend/internal/models"
+)
+
+// Score é o placar final (tempo regulamentar) de uma partida
+type Score struct {
+	Home int `json:"home_score"`
+	Away int `json:"away_score"`
+}
+
+var ErrInvalidScore = errors.New("placar inválido")
+
+func (s Score) Validate() error {
+	if s.Home < 0 || s.Away < 0 {
+		return ErrInvalidScore
+	}
+	return nil
+}
+
+// SettleSelection decide o resultado de uma seleção com o placar final
+func SettleSelection(s models.PalpiteSelection, score Score) (string, error) {
+	if err := score.Validate(); err != nil {
+		return "", err
+	}
+
+	switch s.Market {
+	case models.MARKET_1X2:
+		var won bool
+		switch s.Selection {
+		case models.SELECTION_HOME:
+			won = score.Home > score.Away
+		case models.SELECTION_DRAW:
+			won = score.Home == score.Away
+		case models.SELECTION_AWAY:
+			won = score.Home < score.Away
+		default:
+			return "", invalidSelection(s)
+		}
+		return wonOrLost(won), nil
+
+	case models.MARKET_BTTS:
+		both := score.Home > 0 && score.Away > 0
+		switch s.Selection {
+		case models.SELECTION_YES:
+			return wonOrLost(both), nil
+		case models.SELECTION_NO:
+			return wonOrLost(!both), nil
+		}
+		return "", invalidSelection(s)
+
+	case models.MARKET_CORRECT_SCORE:
+		home, away, ok := parseScore(s.Selection)
+		if !ok {
+			return "", invalidSelection(s)
+		}
+		return wonOrLost(home == score.Home && away == score.Away), nil
+
+	case models.MARKET_OVER_UNDER:
+		if s.Line == nil {
+			return "", fmt.Errorf("seleção %d sem linha", s.ID)
+		}
+		total := float64(score.Home + score.Away)
+		switch s.Selection {
+		case models.SELECTION_OVER:
+			return asianLine(*s.Line, func(line float64) float64 { return total - line }), nil
+		case models.SELECTION_UNDER:
+			return asianLine(*s.Line, func(line float64) float64 { return line - total }), nil
+		}
+		return "", invalidSelection(s)
+
+	case models.MARKET_HANDICAP:
+		if s.Line == nil {
+			return "", fmt.Errorf("seleção %d sem linha", s.ID)
+		}
+		var margin float64
+		switch s.Selection {
+		case models.SELECTION_HOME:
+			margin = float64(score.Home - score.Away)
+		case models.SELECTION_AWAY:
+			margin = float64(score.Away - score.Home)
+		default:
+			return "", invalidSelection(s)
+		}
+		return asianLine(*s.Line, func(line float64) float64 { return margin + line }), nil
+	}
+
+	return "", fmt.Errorf("mercado desconhecido: %s", s.Market)
+}
+
+// asianLine apura linhas asiáticas. result recebe a linha e retorna a margem da aposta
+// (positiva ganha, zero devolve, negativa perde). Linhas de quarto (ex: -0.75) dividem a stake
+// em duas apostas nas linhas vizinhas (-0.5 e -1).
+func asianLine(line float64, result func(line float64) float64) string {
+	if math.Mod(math.Abs(line)*2, 1) == 0 {
+		return wholeOrHalfLine(result(line))
+	}
+
+	first := wholeOrHalfLine(result(line - 0.25))
+	second := wholeOrHalfLine(result(line + 0.25))
+	switch {
+	case first == second:
+		return first
+	case first == models.PICK_STATUS_WON || second == models.PICK_STATUS_WON:
+		return models.PICK_STATUS_HALF_WON
+	default:
+		return models.PICK_STATUS_HALF_LOST
+	}
+}
+
+func wholeOrHalfLine(margin float64) string {
+	switch {
+	case margin > 0:
+		return models.PICK_STATUS_WON
+	case margin < 0:
+		return models.PICK_STATUS_LOST
+	default:
+		return models.PICK_STATUS_VOID
+	}
+}
+
+// Profit é o lucro em unidades de uma seleção apurada (negativo quando perde)
+func Profit(status string, odds, stake float64) float64 {
+	var profit float64
+	switch status {
+	case models.PICK_STATUS_WON:
+		profit = stake * (odds - 1)
+	case models.PICK_STATUS_HALF_WON:
+		profit = stake * (odds - 1) / 2
+	case models.PICK_STATUS_HALF_LOST:
+		profit = -stake / 2
+	case models.PICK_STATUS_LOST:
+		profit = -stake
+	}
+	return round(profit)
+}
+
+// Combine resume as seleções de um palpite: pending enquanto alguma não foi apurada; quando todas
+// têm o mesmo resultado, ele é o do palpite; senão vale o sinal do lucro somado (won, lost ou void).
+func Combine(selections []models.PalpiteSelection) (string, float64) {
+	if len(selections) == 0 {
+		return models.PICK_STATUS_PENDING, 0
+	}
+
+	var profit float64
+	same := true
+	for _, s := range selections {
+		if s.Status == "" || s.Status == models.PICK_STATUS_PENDING {
+			return models.PICK_STATUS_PENDING, 0
+		}
+		if s.Status != selections[0].Status {
+			same = false
+		}
+		if s.Profit != nil {
+			profit += *s.Profit
+		}
+	}
+	profit = round(profit)
+
+	switch {
+	case same:
+		return selections[0].Status, profit
+	case profit > 0:
+		return models.PICK_STATUS_WON, profit
+	case profit < 0:
+		return models.PICK_STATUS_LOST, profit
+	default:
+		return models.PICK_STATUS_VOID, profit
+	}
+}
+
+func wonOrLost(won bool) string {
+	if won {
+		return models.PICK_STATUS_WON
+	}
+	return models.PICK_STATUS_LOST
+}
+
+func parseScore(value string) (int, int, bool) {
+	parts := strings.Split(value, "-")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	home, err1 := strconv.Atoi(parts[0])
+	away, err2 := strconv.Atoi(parts[1])
+	return home, away, err1 == nil && err2 == nil
+}
+
+func invalidSelection(s models.PalpiteSelection) error {
+	return fmt.Errorf("seleção inválida para o mercado %s: %s", s.Market, s.Selection)
+}
+
+// round arredonda para 3 casas, a precisão gravada no banco
+func round(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
@@ -0,0 +1,160 @@
+package settlement
+
+import (
+	"testing"
+
+	"smartpicks-backend/internal/models"
+)
+
+func line(v float64) *float64 { return &v }
+
+func TestSettleSelection(t *testing.T) {
+	tests := []struct {
+		name      string
+		market    string
+		selection string
+		line      *float64
+		score     Score
+		want      string
+	}{
+		// 1X2
+		{"1x2 casa vence", models.MARKET_1X2, models.SELECTION_HOME, nil, Score{2, 1}, models.PICK_STATUS_WON},
+		{"1x2 casa perde", models.MARKET_1X2, models.SELECTION_HOME, nil, Score{0, 1}, models.PICK_STATUS_LOST},
+		{"1x2 casa empata", models.MARKET_1X2, models.SELECTION_HOME, nil, Score{1, 1}, models.PICK_STATUS_LOST},
+		{"1x2 empate", models.MARKET_1X2, models.SELECTION_DRAW, nil, Score{0, 0}, models.PICK_STATUS_WON},
+		{"1x2 empate perde", models.MARKET_1X2, models.SELECTION_DRAW, nil, Score{3, 2}, models.PICK_STATUS_LOST},
+		{"1x2 fora vence", models.MARKET_1X2, models.SELECTION_AWAY, nil, Score{1, 4}, models.PICK_STATUS_WON},
+		{"1x2 fora perde", models.MARKET_1X2, models.SELECTION_AWAY, nil, Score{2, 2}, models.PICK_STATUS_LOST},
+
+		// Over/under
+		{"over 2.5 ganha", models.MARKET_OVER_UNDER, models.SELECTION_OVER, line(2.5), Score{2, 1}, models.PICK_STATUS_WON},
+		{"over 2.5 perde", models.MARKET_OVER_UNDER, models.SELECTION_OVER, line(2.5), Score{1, 1}, models.PICK_STATUS_LOST},
+		{"under 2.5 ganha", models.MARKET_OVER_UNDER, models.SELECTION_UNDER, line(2.5), Score{1, 0}, models.PICK_STATUS_WON},
+		{"under 2.5 perde", models.MARKET_OVER_UNDER, models.SELECTION_UNDER, line(2.5), Score{3, 0}, models.PICK_STATUS_LOST},
+		{"over 2 devolve", models.MARKET_OVER_UNDER, models.SELECTION_OVER, line(2), Score{1, 1}, models.PICK_STATUS_VOID},
+		{"under 3 devolve", models.MARKET_OVER_UNDER, models.SELECTION_UNDER, line(3), Score{2, 1}, models.PICK_STATUS_VOID},
+		{"over 2.25 com 2 gols perde metade", models.MARKET_OVER_UNDER, models.SELECTION_OVER, line(2.25), Score{2, 0}, models.PICK_STATUS_HALF_LOST},
+		{"over 2.75 com 3 gols ganha metade", models.MARKET_OVER_UNDER, models.SELECTION_OVER, line(2.75), Score{2, 1}, models.PICK_STATUS_HALF_WON},
+		{"under 2.25 com 2 gols ganha metade", models.MARKET_OVER_UNDER, models.SELECTION_UNDER, line(2.25), Score{1, 1}, models.PICK_STATUS_HALF_WON},
+		{"under 2.75 com 3 gols perde metade", models.MARKET_OVER_UNDER, models.SELECTION_UNDER, line(2.75), Score{3, 0}, models.PICK_STATUS_HALF_LOST},
+		{"over 2.25 com 4 gols ganha", models.MARKET_OVER_UNDER, models.SELECTION_OVER, line(2.25), Score{2, 2}, models.PICK_STATUS_WON},
+
+		// Ambos marcam
+		{"btts sim ganha", models.MARKET_BTTS, models.SELECTION_YES, nil, Score{1, 1}, models.PICK_STATUS_WON},
+		{"btts sim perde", models.MARKET_BTTS, models.SELECTION_YES, nil, Score{3, 0}, models.PICK_STATUS_LOST},
+		{"btts não ganha", models.MARKET_BTTS, models.SELECTION_NO, nil, Score{0, 0}, models.PICK_STATUS_WON},
+		{"btts não perde", models.MARKET_BTTS, models.SELECTION_NO, nil, Score{2, 1}, models.PICK_STATUS_LOST},
+
+		// Handicap asiático
+		{"handicap casa -0.5 ganha", models.MARKET_HANDICAP, models.SELECTION_HOME, line(-0.5), Score{1, 0}, models.PICK_STATUS_WON},
+		{"handicap casa -0.5 perde no empate", models.MARKET_HANDICAP, models.SELECTION_HOME, line(-0.5), Score{1, 1}, models.PICK_STATUS_LOST},
+		{"handicap casa 0 devolve no empate", models.MARKET_HANDICAP, models.SELECTION_HOME, line(0), Score{2, 2}, models.PICK_STATUS_VOID},
+		{"handicap casa -1 devolve com 1 gol", models.MARKET_HANDICAP, models.SELECTION_HOME, line(-1), Score{2, 1}, models.PICK_STATUS_VOID},
+		{"handicap casa -1 ganha com 2 gols", models.MARKET_HANDICAP, models.SELECTION_HOME, line(-1), Score{3, 1}, models.PICK_STATUS_WON},
+		{"handicap casa -0.25 perde metade no empate", models.MARKET_HANDICAP, models.SELECTION_HOME, line(-0.25), Score{0, 0}, models.PICK_STATUS_HALF_LOST},
+		{"handicap casa -0.75 ganha metade com 1 gol", models.MARKET_HANDICAP, models.SELECTION_HOME, line(-0.75), Score{1, 0}, models.PICK_STATUS_HALF_WON},
+		{"handicap casa -0.75 ganha com 2 gols", models.MARKET_HANDICAP, models.SELECTION_HOME, line(-0.75), Score{2, 0}, models.PICK_STATUS_WON},
+		{"handicap casa -1.25 perde metade com 1 gol", models.MARKET_HANDICAP, models.SELECTION_HOME, line(-1.25), Score{2, 1}, models.PICK_STATUS_HALF_LOST},
+		{"handicap fora +0.25 ganha metade no empate", models.MARKET_HANDICAP, models.SELECTION_AWAY, line(0.25), Score{1, 1}, models.PICK_STATUS_HALF_WON},
+		{"handicap fora +0.75 perde metade com 1 gol", models.MARKET_HANDICAP, models.SELECTION_AWAY, line(0.75), Score{1, 0}, models.PICK_STATUS_HALF_LOST},
+		{"handicap fora +1.5 ganha perdendo por 1", models.MARKET_HANDICAP, models.SELECTION_AWAY, line(1.5), Score{2, 1}, models.PICK_STATUS_WON},
+		{"handicap fora +1.5 perde perdendo por 2", models.MARKET_HANDICAP, models.SELECTION_AWAY, line(1.5), Score{2, 0}, models.PICK_STATUS_LOST},
+
+		// Placar exato
+		{"placar exato ganha", models.MARKET_CORRECT_SCORE, "2-1", nil, Score{2, 1}, models.PICK_STATUS_WON},
+		{"placar exato invertido perde", models.MARKET_CORRECT_SCORE, "2-1", nil, Score{1, 2}, models.PICK_STATUS_LOST},
+		{"placar exato 0-0 ganha", models.MARKET_CORRECT_SCORE, "0-0", nil, Score{0, 0}, models.PICK_STATUS_WON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := models.PalpiteSelection{Market: tt.market, Selection: tt.selection, Line: tt.line}
+			got, err := SettleSelection(s, tt.score)
+			if err != nil {
+				t.Fatalf("erro inesperado: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("SettleSelection() = %s, esperado %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSettleSelectionErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		sel   models.PalpiteSelection
+		score Score
+	}{
+		{"placar negativo", models.PalpiteSelection{Market: models.MARKET_1X2, Selection: models.SELECTION_HOME}, Score{-1, 0}},
+		{"mercado desconhecido", models.PalpiteSelection{Market: "escanteios", Selection: models.SELECTION_OVER}, Score{1, 0}},
+		{"seleção inválida no 1x2", models.PalpiteSelection{Market: models.MARKET_1X2, Selection: models.SELECTION_YES}, Score{1, 0}},
+		{"over sem linha", models.PalpiteSelection{Market: models.MARKET_OVER_UNDER, Selection: models.SELECTION_OVER}, Score{1, 0}},
+		{"handicap sem linha", models.PalpiteSelection{Market: models.MARKET_HANDICAP, Selection: models.SELECTION_HOME}, Score{1, 0}},
+		{"placar exato mal formado", models.PalpiteSelection{Market: models.MARKET_CORRECT_SCORE, Selection: "dois a um"}, Score{2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := SettleSelection(tt.sel, tt.score); err == nil {
+				t.Error("esperava erro")
+			}
+		})
+	}
+}
+
+func TestProfit(t *testing.T) {
+	tests := []struct {
+		status string
+		odds   float64
+		stake  float64
+		want   float64
+	}{
+		{models.PICK_STATUS_WON, 1.9, 2, 1.8},
+		{models.PICK_STATUS_HALF_WON, 1.9, 2, 0.9},
+		{models.PICK_STATUS_VOID, 1.9, 2, 0},
+		{models.PICK_STATUS_HALF_LOST, 1.9, 2, -1},
+		{models.PICK_STATUS_LOST, 1.9, 2, -2},
+		{models.PICK_STATUS_PENDING, 1.9, 2, 0},
+		{models.PICK_STATUS_WON, 2.333, 1, 1.333},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			if got := Profit(tt.status, tt.odds, tt.stake); got != tt.want {
+				t.Errorf("Profit() = %v, esperado %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCombine(t *testing.T) {
+	settled := func(status string, profit float64) models.PalpiteSelection {
+		return models.PalpiteSelection{Status: status, Profit: &profit}
+	}
+
+	tests := []struct {
+		name       string
+		selections []models.PalpiteSelection
+		wantStatus string
+		wantProfit float64
+	}{
+		{"sem seleções", nil, models.PICK_STATUS_PENDING, 0},
+		{"uma pendente", []models.PalpiteSelection{settled(models.PICK_STATUS_WON, 1), {Status: models.PICK_STATUS_PENDING}}, models.PICK_STATUS_PENDING, 0},
+		{"todas ganhas", []models.PalpiteSelection{settled(models.PICK_STATUS_WON, 1), settled(models.PICK_STATUS_WON, 0.5)}, models.PICK_STATUS_WON, 1.5},
+		{"única meio ganha", []models.PalpiteSelection{settled(models.PICK_STATUS_HALF_WON, 0.45)}, models.PICK_STATUS_HALF_WON, 0.45},
+		{"misto com lucro", []models.PalpiteSelection{settled(models.PICK_STATUS_WON, 1.5), settled(models.PICK_STATUS_LOST, -1)}, models.PICK_STATUS_WON, 0.5},
+		{"misto com prejuízo", []models.PalpiteSelection{settled(models.PICK_STATUS_HALF_WON, 0.4), settled(models.PICK_STATUS_LOST, -1)}, models.PICK_STATUS_LOST, -0.6},
+		{"misto zerado", []models.PalpiteSelection{settled(models.PICK_STATUS_WON, 1), settled(models.PICK_STATUS_LOST, -1)}, models.PICK_STATUS_VOID, 0},
+		{"todas devolvidas", []models.PalpiteSelection{settled(models.PICK_STATUS_VOID, 0), settled(models.PICK_STATUS_VOID, 0)}, models.PICK_STATUS_VOID, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, profit := Combine(tt.selections)
+			if status != tt.wantStatus || profit != tt.wantProfit {
+				t.Errorf("Combine() = (%s, %v), esperado (%s, %v)", status, profit, tt.wantStatus, tt.wantProfit)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package stats
+
+import (
+	"sort"
+	"time"
+)
+
+// Limites do peso de um tipster no consenso ponderado por ROI
+const (
+	MIN_CONSENSUS_WEIGHT = 0.1
+	MAX_CONSENSUS_WEIGHT = 3
+)
+
+// Backing é uma seleção de um tipster na partida, com o histórico dele. TipsterROI é o ROI (%)
+// de todas as seleções já apuradas do tipster e TipsterPicks quantas são.
+type Backing struct {
+	UserID       int
+	UserName     string
+	Market       string
+	Selection    string
+	Line         *float64
+	Odds         float64
+	TipsterROI   float64
+	TipsterPicks int
+}
+
+// BackingTipster é um dos tipsters que apoiam uma opção
+type BackingTipster struct {
+	UserID   int     `json:"user_id"`
+	UserName string  `json:"user_name"`
+	Odds     float64 `json:"odds"`
+	ROI      float64 `json:"roi"`
+	Picks    int     `json:"picks"`
+}
+
+// ConsensusOption é uma seleção (com a linha, quando há) de um mercado. Count é o número de
+// tipsters, Percentage a fatia deles no mercado e WeightedPercentage a mesma fatia com cada
+// tipster pesando pelo ROI.
+type ConsensusOption struct {
+	Selection          string           `json:"selection"`
+	Line               *float64         `json:"line,omitempty"`
+	Count              int              `json:"count"`
+	Percentage         float64          `json:"percentage"`
+	AverageOdds        float64          `json:"average_odds"`
+	WeightedPercentage float64          `json:"weighted_percentage"`
+	TopTipsters        []BackingTipster `json:"top_tipsters"`
+
+	weight float64
+}
+
+// ConsensusMarket agrupa as opções de um mercado, da mais apoiada para a menos
+type ConsensusMarket struct {
+	Market   string            `json:"market"`
+	Tipsters int               `json:"tipsters"`
+	Options  []ConsensusOption `json:"options"`
+}
+
+// Consensus é a opinião da comunidade sobre uma partida
+type Consensus struct {
+	MatchID    int               `json:"match_id"`
+	Tipsters   int               `json:"tipsters"`
+	Markets    []ConsensusMarket `json:"markets"`
+	ComputedAt time.Time         `json:"computed_at"`
+}
+
+// ComputeConsensus agrega as seleções da partida por mercado e seleção. Cada tipster conta uma
+// vez por opção (backings deve vir do mais antigo para o mais novo; vale a odd mais recente). O
+// peso de um tipster é 1 + ROI/100, limitado entre MIN_CONSENSUS_WEIGHT e MAX_CONSENSUS_WEIGHT;
+// com menos de minPicks seleções apuradas o histórico é curto demais e o peso fica 1.
+func ComputeConsensus(matchID int, backings []Backing, minPicks, topTipsters int) Consensus {
+	type optionKey struct {
+		market, selection string
+		line              float64
+		hasLine           bool
+	}
+
+	latest := map[optionKey]map[int]Backing{}
+	var order []optionKey
+	tipsters := map[int]bool{}
+	for _, b := range backings {
+		key := optionKey{market: b.Market, selection: b.Selection}
+		if b.Line != nil {
+			key.line, key.hasLine = *b.Line, true
+		}
+		if latest[key] == nil {
+			latest[key] = map[int]Backing{}
+			order = append(order, key)
+		}
+		latest[key][b.UserID] = b
+		tipsters[b.UserID] = true
+	}
+
+	markets := map[string]*ConsensusMarket{}
+	marketTipsters := map[string]map[int]bool{}
+	var marketOrder []string
+	for _, key := range order {
+		option := ConsensusOption{Selection: key.selection, TopTipsters: []BackingTipster{}}
+		if key.hasLine {
+			line := key.line
+			option.Line = &line
+		}
+
+		var oddsSum float64
+		for _, b := range latest[key] {
+			option.Count++
+			oddsSum += b.Odds
+			option.weight += consensusWeight(b, minPicks)
+			option.TopTipsters = append(option.TopTipsters, BackingTipster{
+				UserID: b.UserID, UserName: b.UserName, Odds: b.Odds, ROI: b.TipsterROI, Picks: b.TipsterPicks,
+			})
+			if marketTipsters[key.market] == nil {
+				marketTipsters[key.market] = map[int]bool{}
+			}
+			marketTipsters[key.market][b.UserID] = true
+		}
+		option.AverageOdds = round(oddsSum / float64(option.Count))
+
+		// Os melhores tipsters primeiro; quem tem histórico curto fica depois de quem tem o mínimo
+		sort.Slice(option.TopTipsters, func(i, j int) bool {
+			a, b := option.TopTipsters[i], option.TopTipsters[j]
+			if (a.Picks >= minPicks) != (b.Picks >= minPicks) {
+				return a.Picks >= minPicks
+			}
+			if a.ROI != b.ROI {
+				return a.ROI > b.ROI
+			}
+			return a.UserID < b.UserID
+		})
+		if len(option.TopTipsters) > topTipsters {
+			option.TopTipsters = option.TopTipsters[:topTipsters]
+		}
+
+		if markets[key.market] == nil {
+			markets[key.market] = &ConsensusMarket{Market: key.market}
+			marketOrder = append(marketOrder, key.market)
+		}
+		markets[key.market].Options = append(markets[key.market].Options, option)
+	}
+
+	result := Consensus{MatchID: matchID, Tipsters: len(tipsters), Markets: []ConsensusMarket{}}
+	for _, name := range marketOrder {
+		market := markets[name]
+		market.Tipsters = len(marketTipsters[name])
+
+		// Um tipster pode apoiar mais de uma opção do mercado (ex: linhas diferentes), então as
+		// fatias são sobre o total de apoios e somam 100%
+		var count int
+		var weight float64
+		for _, o := range market.Options {
+			count += o.Count
+			weight += o.weight
+		}
+		for i := range market.Options {
+			o := &market.Options[i]
+			o.Percentage = round(float64(o.Count) / float64(count) * 100)
+			if weight > 0 {
+				o.WeightedPercentage = round(o.weight / weight * 100)
+			}
+		}
+		sort.SliceStable(market.Options, func(i, j int) bool {
+			return market.Options[i].Count > market.Options[j].Count
+		})
+		result.Markets = append(result.Markets, *market)
+	}
+	sort.SliceStable(result.Markets, func(i, j int) bool {
+		return result.Markets[i].Tipsters > result.Markets[j].Tipsters
+	})
+	return result
+}
+
+func consensusWeight(b Backing, minPicks int) float64 {
+	if b.TipsterPicks < minPicks {
+		return 1
+	}
+	return min(max(1+b.TipsterROI/100, MIN_CONSENSUS_WEIGHT), MAX_CONSENSUS_WEIGHT)
+}
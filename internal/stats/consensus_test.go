@@ -0,0 +1,167 @@
+package stats
+
+import (
+	"testing"
+
+	"smartpicks-backend/internal/models"
+)
+
+func TestConsensusWeight(t *testing.T) {
+	const minPicks = 10
+
+	tests := []struct {
+		name  string
+		roi   float64
+		picks int
+		want  float64
+	}{
+		{"ROI positivo", 50, 20, 1.5},
+		{"ROI zero", 0, 20, 1},
+		{"ROI negativo", -40, 20, 0.6},
+		{"limite mínimo", -95, 20, MIN_CONSENSUS_WEIGHT},
+		{"limite máximo", 500, 20, MAX_CONSENSUS_WEIGHT},
+		{"histórico curto ignora o ROI", 200, 9, 1},
+		{"exatamente o mínimo de picks", 200, 10, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := consensusWeight(Backing{TipsterROI: tt.roi, TipsterPicks: tt.picks}, minPicks)
+			if round(got) != tt.want {
+				t.Errorf("consensusWeight() = %.2f, esperado %.2f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeConsensus(t *testing.T) {
+	line := func(v float64) *float64 { return &v }
+	backing := func(userID int, market, selection string, l *float64, odds, roi float64, picks int) Backing {
+		return Backing{UserID: userID, Market: market, Selection: selection, Line: l, Odds: odds, TipsterROI: roi, TipsterPicks: picks}
+	}
+
+	type wantOption struct {
+		selection   string
+		count       int
+		percentage  float64
+		weighted    float64
+		averageOdds float64
+		top         []int
+	}
+	tests := []struct {
+		name     string
+		backings []Backing
+		tipsters int
+		market   string
+		marketN  int
+		options  []wantOption
+	}{
+		{
+			name: "empate na contagem decidido pelo peso do ROI",
+			backings: []Backing{
+				backing(1, models.MARKET_1X2, models.SELECTION_HOME, nil, 2.1, 50, 20),
+				backing(2, models.MARKET_1X2, models.SELECTION_AWAY, nil, 3.4, -95, 20),
+				backing(3, models.MARKET_1X2, models.SELECTION_AWAY, nil, 3.6, 500, 20),
+				backing(4, models.MARKET_1X2, models.SELECTION_HOME, nil, 2.2, 200, 5),
+			},
+			tipsters: 4,
+			market:   models.MARKET_1X2,
+			marketN:  4,
+			options: []wantOption{
+				// pesos: casa 1.5 + 1 (histórico curto) = 2.5; fora 0.1 + 3 = 3.1
+				{models.SELECTION_HOME, 2, 50, 44.64, 2.15, []int{1, 4}},
+				{models.SELECTION_AWAY, 2, 50, 55.36, 3.5, []int{3, 2}},
+			},
+		},
+		{
+			name: "tipster conta uma vez por opção com a odd mais recente",
+			backings: []Backing{
+				backing(1, models.MARKET_BTTS, models.SELECTION_YES, nil, 1.8, 10, 20),
+				backing(1, models.MARKET_BTTS, models.SELECTION_YES, nil, 2.0, 10, 20),
+				backing(2, models.MARKET_BTTS, models.SELECTION_YES, nil, 1.9, 10, 20),
+				backing(3, models.MARKET_BTTS, models.SELECTION_NO, nil, 2.0, 10, 20),
+			},
+			tipsters: 3,
+			market:   models.MARKET_BTTS,
+			marketN:  3,
+			options: []wantOption{
+				{models.SELECTION_YES, 2, 66.67, 66.67, 1.95, []int{1, 2}},
+				{models.SELECTION_NO, 1, 33.33, 33.33, 2, []int{3}},
+			},
+		},
+		{
+			name: "linhas diferentes são opções diferentes",
+			backings: []Backing{
+				backing(1, models.MARKET_OVER_UNDER, models.SELECTION_OVER, line(2.5), 1.9, 0, 20),
+				backing(1, models.MARKET_OVER_UNDER, models.SELECTION_OVER, line(3.5), 2.8, 0, 20),
+				backing(2, models.MARKET_OVER_UNDER, models.SELECTION_OVER, line(2.5), 2.0, 0, 20),
+			},
+			tipsters: 2,
+			market:   models.MARKET_OVER_UNDER,
+			marketN:  2,
+			options: []wantOption{
+				{models.SELECTION_OVER, 2, 66.67, 66.67, 1.95, []int{1, 2}},
+				{models.SELECTION_OVER, 1, 33.33, 33.33, 2.8, []int{1}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ComputeConsensus(7, tt.backings, 10, 5)
+			if got.MatchID != 7 || got.Tipsters != tt.tipsters {
+				t.Fatalf("Consensus = {match %d, tipsters %d}, esperado {7, %d}", got.MatchID, got.Tipsters, tt.tipsters)
+			}
+			if len(got.Markets) != 1 || got.Markets[0].Market != tt.market || got.Markets[0].Tipsters != tt.marketN {
+				t.Fatalf("Markets = %+v, esperado só %s com %d tipsters", got.Markets, tt.market, tt.marketN)
+			}
+
+			options := got.Markets[0].Options
+			if len(options) != len(tt.options) {
+				t.Fatalf("mercado tem %d opções, esperado %d", len(options), len(tt.options))
+			}
+			for i, want := range tt.options {
+				o := options[i]
+				if o.Selection != want.selection || o.Count != want.count || o.Percentage != want.percentage ||
+					o.WeightedPercentage != want.weighted || o.AverageOdds != want.averageOdds {
+					t.Errorf("opção %d = {%s %d %.2f%% %.2f%% odds %.2f}, esperado {%s %d %.2f%% %.2f%% odds %.2f}", i,
+						o.Selection, o.Count, o.Percentage, o.WeightedPercentage, o.AverageOdds,
+						want.selection, want.count, want.percentage, want.weighted, want.averageOdds)
+				}
+				var top []int
+				for _, tipster := range o.TopTipsters {
+					top = append(top, tipster.UserID)
+				}
+				if len(top) != len(want.top) {
+					t.Errorf("opção %d: top tipsters = %v, esperado %v", i, top, want.top)
+					continue
+				}
+				for j := range top {
+					if top[j] != want.top[j] {
+						t.Errorf("opção %d: top tipsters = %v, esperado %v", i, top, want.top)
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestComputeConsensusTopTipstersLimit(t *testing.T) {
+	var backings []Backing
+	for id := 1; id <= 4; id++ {
+		backings = append(backings, Backing{
+			UserID: id, Market: models.MARKET_1X2, Selection: models.SELECTION_DRAW, Odds: 3.2,
+			TipsterROI: float64(id * 10), TipsterPicks: 20,
+		})
+	}
+
+	got := ComputeConsensus(1, backings, 10, 2)
+	top := got.Markets[0].Options[0].TopTipsters
+	if len(top) != 2 || top[0].UserID != 4 || top[1].UserID != 3 {
+		t.Errorf("TopTipsters = %+v, esperado os tipsters 4 e 3", top)
+	}
+	if got.Markets[0].Options[0].Count != 4 {
+		t.Errorf("Count = %d, esperado 4 (o limite vale só para a lista)", got.Markets[0].Options[0].Count)
+	}
+}
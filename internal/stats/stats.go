@@ -0,0 +1,146 @@
+// Package stats calcula o desempenho de um tipster a partir das seleções já apuradas
+package stats
+
+import (
+	"math"
+	"sort"
+
+	"smartpicks-backend/internal/models"
+)
+
+// Pick é uma seleção apurada, com o que é preciso para as estatísticas
+type Pick struct {
+	Market      string
+	Competition string
+	Status      string
+	Odds        float64
+	Stake       float64
+	Profit      float64
+}
+
+// Summary são os números de um conjunto de picks. HitRate, ROI e Yield são percentuais.
+type Summary struct {
+	Picks       int     `json:"picks"`
+	Won         int     `json:"won"`
+	HalfWon     int     `json:"half_won"`
+	Void        int     `json:"void"`
+	HalfLost    int     `json:"half_lost"`
+	Lost        int     `json:"lost"`
+	HitRate     float64 `json:"hit_rate"`
+	Staked      float64 `json:"staked"`
+	Profit      float64 `json:"profit"`
+	ROI         float64 `json:"roi"`
+	Yield       float64 `json:"yield"`
+	AverageOdds float64 `json:"average_odds"`
+}
+
+// Breakdown é o Summary de um mercado ou de uma competição
+type Breakdown struct {
+	Key string `json:"key"`
+	Summary
+}
+
+// TipsterStats é o resultado completo retornado em /users/{id}/stats
+type TipsterStats struct {
+	Summary
+	Bankroll          float64     `json:"bankroll"`
+	LongestWinStreak  int         `json:"longest_win_streak"`
+	LongestLossStreak int         `json:"longest_loss_streak"`
+	CurrentStreak     int         `json:"current_streak"`
+	ByMarket          []Breakdown `json:"by_market"`
+	ByCompetition     []Breakdown `json:"by_competition"`
+}
+
+// Compute calcula as estatísticas. picks deve estar em ordem cronológica (as sequências dependem
+// dela). O ROI é o lucro sobre a banca em unidades; o yield, o lucro sobre o total apostado.
+func Compute(picks []Pick, bankroll float64) TipsterStats {
+	result := TipsterStats{
+		Summary:       summarize(picks, bankroll),
+		Bankroll:      bankroll,
+		ByMarket:      breakdown(picks, bankroll, func(p Pick) string { return p.Market }),
+		ByCompetition: breakdown(picks, bankroll, func(p Pick) string { return p.Competition }),
+	}
+
+	// Devoluções não interrompem nem contam nas sequências
+	var wins, losses int
+	for _, p := range picks {
+		switch p.Status {
+		case models.PICK_STATUS_WON, models.PICK_STATUS_HALF_WON:
+			wins, losses = wins+1, 0
+		case models.PICK_STATUS_LOST, models.PICK_STATUS_HALF_LOST:
+			wins, losses = 0, losses+1
+		default:
+			continue
+		}
+		result.LongestWinStreak = max(result.LongestWinStreak, wins)
+		result.LongestLossStreak = max(result.LongestLossStreak, losses)
+	}
+	// CurrentStreak é positiva para vitórias seguidas e negativa para derrotas
+	result.CurrentStreak = wins - losses
+	return result
+}
+
+func summarize(picks []Pick, bankroll float64) Summary {
+	var s Summary
+	var oddsSum float64
+	for _, p := range picks {
+		s.Picks++
+		switch p.Status {
+		case models.PICK_STATUS_WON:
+			s.Won++
+		case models.PICK_STATUS_HALF_WON:
+			s.HalfWon++
+		case models.PICK_STATUS_VOID:
+			s.Void++
+		case models.PICK_STATUS_HALF_LOST:
+			s.HalfLost++
+		case models.PICK_STATUS_LOST:
+			s.Lost++
+		}
+		s.Staked += p.Stake
+		s.Profit += p.Profit
+		oddsSum += p.Odds
+	}
+	if s.Picks == 0 {
+		return s
+	}
+
+	// Meios resultados contam como acerto ou erro; devoluções ficam fora da taxa de acerto
+	if decided := s.Won + s.HalfWon + s.HalfLost + s.Lost; decided > 0 {
+		s.HitRate = round(float64(s.Won+s.HalfWon) / float64(decided) * 100)
+	}
+	if s.Staked > 0 {
+		s.Yield = round(s.Profit / s.Staked * 100)
+	}
+	if bankroll > 0 {
+		s.ROI = round(s.Profit / bankroll * 100)
+	}
+	s.AverageOdds = round(oddsSum / float64(s.Picks))
+	s.Staked = round(s.Staked)
+	s.Profit = round(s.Profit)
+	return s
+}
+
+// breakdown agrupa os picks pela chave e ordena os grupos pelo número de picks
+func breakdown(picks []Pick, bankroll float64, key func(Pick) string) []Breakdown {
+	groups := map[string][]Pick{}
+	for _, p := range picks {
+		groups[key(p)] = append(groups[key(p)], p)
+	}
+
+	result := make([]Breakdown, 0, len(groups))
+	for k, group := range groups {
+		result = append(result, Breakdown{Key: k, Summary: summarize(group, bankroll)})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Picks != result[j].Picks {
+			return result[i].Picks > result[j].Picks
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,138 @@
+package stats
+
+import (
+	"testing"
+
+	"smartpicks-backend/internal/models"
+)
+
+func pick(status string, odds, profit float64) Pick {
+	return Pick{Market: models.MARKET_1X2, Status: status, Odds: odds, Stake: 1, Profit: profit}
+}
+
+func TestCompute(t *testing.T) {
+	const (
+		won      = models.PICK_STATUS_WON
+		halfWon  = models.PICK_STATUS_HALF_WON
+		void     = models.PICK_STATUS_VOID
+		halfLost = models.PICK_STATUS_HALF_LOST
+		lost     = models.PICK_STATUS_LOST
+	)
+
+	tests := []struct {
+		name        string
+		picks       []Pick
+		bankroll    float64
+		want        Summary
+		longestWin  int
+		longestLoss int
+		current     int
+	}{
+		{
+			name: "sequência mista com devolução no meio",
+			picks: []Pick{
+				pick(won, 2, 1), pick(won, 1.5, 0.5), pick(lost, 2, -1),
+				pick(void, 2, 0), pick(lost, 1.8, -1), pick(halfWon, 1.9, 0.45),
+			},
+			bankroll: 100,
+			want: Summary{
+				Picks: 6, Won: 2, HalfWon: 1, Void: 1, Lost: 2,
+				HitRate: 60, Staked: 6, Profit: -0.05, ROI: -0.05, Yield: -0.83, AverageOdds: 1.87,
+			},
+			longestWin: 2, longestLoss: 2, current: 1,
+		},
+		{
+			name:     "lucro sobre a banca e sobre o apostado",
+			picks:    []Pick{pick(won, 3, 2), pick(won, 2, 1), pick(lost, 2, -1), pick(won, 2.5, 1.5)},
+			bankroll: 10,
+			want: Summary{
+				Picks: 4, Won: 3, Lost: 1,
+				HitRate: 75, Staked: 4, Profit: 3.5, ROI: 35, Yield: 87.5, AverageOdds: 2.38,
+			},
+			longestWin: 2, longestLoss: 1, current: 1,
+		},
+		{
+			name:     "meia derrota conta na sequência de derrotas",
+			picks:    []Pick{pick(won, 2, 1), pick(halfLost, 2, -0.5), pick(void, 2, 0), pick(halfLost, 2, -0.5)},
+			bankroll: 100,
+			want: Summary{
+				Picks: 4, Won: 1, Void: 1, HalfLost: 2,
+				HitRate: 33.33, Staked: 4, Profit: 0, ROI: 0, Yield: 0, AverageOdds: 2,
+			},
+			longestWin: 1, longestLoss: 2, current: -2,
+		},
+		{
+			name:     "sem banca o ROI fica zerado",
+			picks:    []Pick{pick(lost, 2, -1), pick(lost, 2, -1), pick(lost, 2, -1)},
+			bankroll: 0,
+			want: Summary{
+				Picks: 3, Lost: 3,
+				HitRate: 0, Staked: 3, Profit: -3, ROI: 0, Yield: -100, AverageOdds: 2,
+			},
+			longestWin: 0, longestLoss: 3, current: -3,
+		},
+		{
+			name:     "só devoluções",
+			picks:    []Pick{pick(void, 2, 0), pick(void, 1.5, 0)},
+			bankroll: 100,
+			want:     Summary{Picks: 2, Void: 2, Staked: 2, AverageOdds: 1.75},
+		},
+		{
+			name:     "sem picks",
+			picks:    nil,
+			bankroll: 100,
+			want:     Summary{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compute(tt.picks, tt.bankroll)
+			if got.Summary != tt.want {
+				t.Errorf("Summary = %+v, esperado %+v", got.Summary, tt.want)
+			}
+			if got.LongestWinStreak != tt.longestWin || got.LongestLossStreak != tt.longestLoss || got.CurrentStreak != tt.current {
+				t.Errorf("sequências = (%d, %d, %d), esperado (%d, %d, %d)",
+					got.LongestWinStreak, got.LongestLossStreak, got.CurrentStreak, tt.longestWin, tt.longestLoss, tt.current)
+			}
+		})
+	}
+}
+
+func TestComputeBreakdown(t *testing.T) {
+	picks := []Pick{
+		{Market: models.MARKET_BTTS, Competition: "Premier League", Status: models.PICK_STATUS_WON, Odds: 2, Stake: 1, Profit: 1},
+		{Market: models.MARKET_1X2, Competition: "Brasileirão", Status: models.PICK_STATUS_LOST, Odds: 2, Stake: 1, Profit: -1},
+		{Market: models.MARKET_1X2, Competition: "Premier League", Status: models.PICK_STATUS_WON, Odds: 3, Stake: 1, Profit: 2},
+		{Market: models.MARKET_HANDICAP, Competition: "Brasileirão", Status: models.PICK_STATUS_VOID, Odds: 1.9, Stake: 1, Profit: 0},
+	}
+
+	got := Compute(picks, 100)
+
+	// Mais picks primeiro; no empate, ordem alfabética
+	wantMarkets := []struct {
+		key    string
+		picks  int
+		profit float64
+	}{
+		{models.MARKET_1X2, 2, 1},
+		{models.MARKET_BTTS, 1, 1},
+		{models.MARKET_HANDICAP, 1, 0},
+	}
+	if len(got.ByMarket) != len(wantMarkets) {
+		t.Fatalf("ByMarket tem %d grupos, esperado %d", len(got.ByMarket), len(wantMarkets))
+	}
+	for i, want := range wantMarkets {
+		b := got.ByMarket[i]
+		if b.Key != want.key || b.Picks != want.picks || b.Profit != want.profit {
+			t.Errorf("ByMarket[%d] = {%s %d %.2f}, esperado {%s %d %.2f}", i, b.Key, b.Picks, b.Profit, want.key, want.picks, want.profit)
+		}
+	}
+
+	if len(got.ByCompetition) != 2 || got.ByCompetition[0].Key != "Brasileirão" || got.ByCompetition[1].Key != "Premier League" {
+		t.Fatalf("ByCompetition = %+v, esperado Brasileirão e Premier League", got.ByCompetition)
+	}
+	if roi := got.ByCompetition[1].ROI; roi != 3 {
+		t.Errorf("ROI da Premier League = %.2f, esperado 3 (sobre a banca inteira)", roi)
+	}
+}